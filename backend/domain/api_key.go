@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// API key scopes grant machine clients access to a narrow slice of the API.
+const (
+	ScopeCatalogRead = "catalog:read"
+	ScopeReportsRead = "reports:read"
+	ScopeSalesCreate = "sales:create"
+)
+
+// APIKeyScopes lists every scope an API key may be granted.
+var APIKeyScopes = []string{ScopeCatalogRead, ScopeReportsRead, ScopeSalesCreate}
+
+// APIKey is a per-pharmacy credential for integrations. Only the hash of the
+// secret is stored; the plaintext key is shown once at creation.
+type APIKey struct {
+	ID         int64      `db:"id" json:"id"`
+	PharmacyID int64      `db:"pharmacy_id" json:"pharmacy_id"`
+	Name       string     `db:"name" json:"name"`
+	Prefix     string     `db:"prefix" json:"prefix"`
+	SecretHash string     `db:"secret_hash" json:"-"`
+	Scopes     ScopeList  `db:"scopes" json:"scopes"`
+	CreatedBy  *int64     `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	LastUsedAt *time.Time `db:"last_used_at" json:"last_used_at,omitempty"`
+	ExpiresAt  *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+}
+
+// HasScope reports whether the key was granted scope.
+func (k APIKey) HasScope(scope string) bool {
+	return k.Scopes.Contains(scope)
+}
+
+// Active reports whether the key can still authenticate at the given time.
+func (k APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// ScopeList is stored as a comma separated TEXT column.
+type ScopeList []string
+
+// Scan implements sql.Scanner.
+func (s *ScopeList) Scan(src any) error {
+	var raw string
+	switch v := src.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return fmt.Errorf("unsupported scope list type %T", src)
+	}
+	*s = nil
+	for _, part := range strings.Split(raw, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			*s = append(*s, part)
+		}
+	}
+	return nil
+}
+
+// Contains reports whether scope is in the list.
+func (s ScopeList) Contains(scope string) bool {
+	for _, v := range s {
+		if v == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Value implements driver.Valuer.
+func (s ScopeList) Value() (driver.Value, error) {
+	return strings.Join(s, ","), nil
+}
@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+)
+
+// qualifier matches the bracketed notes of catalog generic names, such as
+// "(Ophthalmic)" or "[Elemental source]".
+var qualifier = regexp.MustCompile(`\([^)]*\)|\[[^\]]*\]`)
+
+// Ingredients splits a generic name into its lower-case ingredients, so a
+// combination like "Dextromethorphan + Pseudoephedrine + Triprolidine" is
+// matched on each of them.
+func Ingredients(generic string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(qualifier.ReplaceAllString(generic, " "), "+") {
+		name := strings.ToLower(strings.Join(strings.Fields(part), " "))
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// IngredientKeys returns the names a knowledge base may list an ingredient
+// under: the name and its leading words, so that "warfarin sodium" matches
+// an entry for "warfarin".
+func IngredientKeys(ingredient string) []string {
+	words := strings.Fields(ingredient)
+	keys := make([]string, len(words))
+	for i := range words {
+		keys[i] = strings.Join(words[:i+1], " ")
+	}
+	return keys
+}
+
+// GenericKeys returns every name the ingredients of generic may be listed
+// under.
+func GenericKeys(generic string) []string {
+	var keys []string
+	for _, ingredient := range Ingredients(generic) {
+		keys = append(keys, IngredientKeys(ingredient)...)
+	}
+	return keys
+}
+
+// ScheduleOf returns the most restricted schedule that schedules, keyed by
+// ingredient, lists for the ingredients of generic, or otc when none is
+// listed.
+func ScheduleOf(generic string, schedules map[string]string) string {
+	schedule := ScheduleOTC
+	for _, key := range GenericKeys(generic) {
+		if listed, ok := schedules[key]; ok {
+			schedule = Stricter(schedule, listed)
+		}
+	}
+	return schedule
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGenericKeys(t *testing.T) {
+	got := GenericKeys("Diazepam (Injectable) + Warfarin Sodium")
+	want := []string{"diazepam", "warfarin", "warfarin sodium"}
+	if !slices.Equal(got, want) {
+		t.Errorf("GenericKeys = %q, want %q", got, want)
+	}
+}
+
+func TestScheduleOf(t *testing.T) {
+	schedules := map[string]string{
+		"diazepam":    ScheduleControlled,
+		"amoxicillin": SchedulePrescription,
+		"paracetamol": ScheduleOTC,
+	}
+	tests := []struct {
+		generic string
+		want    string
+	}{
+		{"Paracetamol", ScheduleOTC},
+		{"Amoxicillin Trihydrate", SchedulePrescription},
+		{"Amoxicillin + Diazepam", ScheduleControlled},
+		{"Paracetamol + Caffeine", ScheduleOTC},
+		{"Vitamin C", ScheduleOTC},
+		{"", ScheduleOTC},
+	}
+	for _, tt := range tests {
+		if got := ScheduleOf(tt.generic, schedules); got != tt.want {
+			t.Errorf("ScheduleOf(%q) = %s, want %s", tt.generic, got, tt.want)
+		}
+	}
+}
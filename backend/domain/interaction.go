@@ -0,0 +1,39 @@
+package domain
+
+// Interaction severities, from least to most serious. Severe interactions
+// need a pharmacist's override to be sold.
+const (
+	SeverityMinor    = "minor"
+	SeverityModerate = "moderate"
+	SeveritySevere   = "severe"
+)
+
+// DrugInteraction is an entry of the interaction knowledge base. Ingredients
+// are lower case, with IngredientA sorting before IngredientB.
+type DrugInteraction struct {
+	IngredientA string `db:"ingredient_a" json:"ingredient_a"`
+	IngredientB string `db:"ingredient_b" json:"ingredient_b"`
+	Severity    string `db:"severity" json:"severity"`
+	Description string `db:"description" json:"description"`
+}
+
+// InteractionWarning is a known interaction between two generics of a cart.
+// GenericA contains IngredientA and GenericB contains IngredientB.
+type InteractionWarning struct {
+	DrugInteraction
+	GenericA string `json:"generic_a"`
+	GenericB string `json:"generic_b"`
+}
+
+// SaleInteraction records a severe interaction dispensed in a sale and the
+// owner or pharmacist who overrode it.
+type SaleInteraction struct {
+	ID           int64   `db:"id" json:"id"`
+	SaleID       int64   `db:"sale_id" json:"sale_id"`
+	IngredientA  string  `db:"ingredient_a" json:"ingredient_a"`
+	IngredientB  string  `db:"ingredient_b" json:"ingredient_b"`
+	Severity     string  `db:"severity" json:"severity"`
+	OverriddenBy *int64  `db:"overridden_by" json:"overridden_by,omitempty"`
+	Reason       *string `db:"reason" json:"reason,omitempty"`
+	CreatedAt    string  `db:"created_at" json:"created_at"`
+}
@@ -3,17 +3,85 @@ package domain
 import "time"
 
 type InventoryItem struct {
-	ID           int64      `db:"id" json:"id"`
-	PharmacyID   int64      `db:"pharmacy_id" json:"pharmacy_id"`
-	MedicineID   *int64     `db:"medicine_id" json:"medicine_id"`
-	BrandName    *string    `db:"brand_name" json:"brand_name"`
-	GenericName  *string    `db:"generic_name" json:"generic_name"`
-	Manufacturer *string    `db:"manufacturer" json:"manufacturer"`
-	Type         *string    `db:"type" json:"type"`
-	Quantity     int64      `db:"quantity" json:"quantity"`
-	CostPrice    float64    `db:"cost_price" json:"cost_price"`
-	SalePrice    float64    `db:"sale_price" json:"sale_price"`
-	ExpiryDate   *time.Time `db:"expiry_date" json:"expiry_date,omitempty"`
-	CreatedAt    string     `db:"created_at" json:"created_at"`
-	UpdatedAt    string     `db:"updated_at" json:"updated_at"`
+	ID           int64   `db:"id" json:"id"`
+	PharmacyID   int64   `db:"pharmacy_id" json:"pharmacy_id"`
+	MedicineID   *int64  `db:"medicine_id" json:"medicine_id"`
+	BrandName    *string `db:"brand_name" json:"brand_name"`
+	GenericName  *string `db:"generic_name" json:"generic_name"`
+	Manufacturer *string `db:"manufacturer" json:"manufacturer"`
+	Type         *string `db:"type" json:"type"`
+	Quantity     int64   `db:"quantity" json:"quantity"`
+	// CostPrice and SalePrice are per-unit prices rounded to the paisa; sale
+	// amounts are computed exactly from the pack prices and PackSize.
+	CostPrice     Money      `db:"cost_price" json:"cost_price"`
+	SalePrice     Money      `db:"sale_price" json:"sale_price"`
+	PackSize      int64      `db:"pack_size" json:"pack_size"`
+	PackCostPrice Money      `db:"pack_cost_price" json:"pack_cost_price"`
+	PackSalePrice Money      `db:"pack_sale_price" json:"pack_sale_price"`
+	ExpiryDate    *time.Time `db:"expiry_date" json:"expiry_date,omitempty"`
+	BatchNumber   *string    `db:"batch_number" json:"batch_number,omitempty"`
+	CreatedAt     string     `db:"created_at" json:"created_at"`
+	UpdatedAt     string     `db:"updated_at" json:"updated_at"`
+	// Version increases with every change and is served as the ETag.
+	Version int64 `db:"version" json:"version"`
+}
+
+// InventorySearchResult is an in-stock item as shown at the point of sale.
+type InventorySearchResult struct {
+	InventoryID  int64  `db:"inventory_id" json:"inventory_id"`
+	MedicineID   *int64 `db:"medicine_id" json:"medicine_id"`
+	PackSize     int64  `db:"pack_size" json:"pack_size"`
+	BrandName    string `db:"brand_name" json:"brand_name"`
+	GenericName  string `db:"generic_name" json:"generic_name"`
+	Manufacturer string `db:"manufacturer" json:"manufacturer"`
+	Type         string `db:"type" json:"type"`
+	// Catalog attributes; empty for custom medicines.
+	Slug             string `db:"slug" json:"slug"`
+	DosageForm       string `db:"dosage_form" json:"dosage_form"`
+	Strength         string `db:"strength" json:"strength"`
+	PackageContainer string `db:"package_container" json:"package_container"`
+	PackageSize      string `db:"package_size" json:"package_size"`
+	// Schedule is otc for custom medicines.
+	Schedule    string  `db:"schedule" json:"schedule"`
+	Quantity    int64   `db:"quantity" json:"quantity"`
+	UnitCost    Money   `db:"cost_price" json:"unit_cost"`
+	UnitPrice   Money   `db:"sale_price" json:"unit_price"`
+	TotalCost   Money   `db:"total_cost" json:"total_cost"`
+	ExpiryDate  *string `db:"expiry_date" json:"expiry_date"`
+	BatchNumber *string `db:"batch_number" json:"batch_number,omitempty"`
+}
+
+// ExpiryAlert is an in-stock item nearing its expiry date.
+type ExpiryAlert struct {
+	InventoryID int64  `db:"id" json:"inventory_id"`
+	BrandName   string `db:"brand_name" json:"brand_name"`
+	Quantity    int64  `db:"quantity" json:"quantity"`
+	ExpiryDate  string `db:"expiry_date" json:"expiry_date"`
+}
+
+// Substitute is another brand with the same generic name, strength and dosage
+// form as a requested medicine, with what the pharmacy has of it in stock.
+type Substitute struct {
+	Medicine
+	// InStock is the pharmacy's stock across all lots, in units.
+	InStock int64 `db:"in_stock" json:"in_stock"`
+	// UnitPrice is the lowest unit sale price among the pharmacy's lots in
+	// stock, or nil when it has none.
+	UnitPrice *Money `db:"unit_price" json:"unit_price"`
+	// UnitMRP is the lowest catalog retail price per unit, when known.
+	UnitMRP *Money `db:"unit_mrp" json:"unit_mrp"`
+	// PriceDifference is this brand's unit price minus the requested
+	// medicine's, negative when cheaper. Stocked prices are compared where
+	// they exist, MRPs otherwise; nil when either side has no price.
+	PriceDifference *Money `db:"-" json:"price_difference"`
+}
+
+// MedicineBarcode maps a GTIN to a catalog medicine. PackSize is set when the
+// code identifies a pack of that many units.
+type MedicineBarcode struct {
+	ID         int64  `db:"id" json:"id"`
+	MedicineID int64  `db:"medicine_id" json:"medicine_id"`
+	GTIN       string `db:"gtin" json:"gtin"`
+	PackSize   *int64 `db:"pack_size" json:"pack_size"`
+	CreatedAt  string `db:"created_at" json:"created_at"`
 }
@@ -1,5 +1,7 @@
 package domain
 
+import "time"
+
 type Medicine struct {
 	ID           int64  `db:"id" json:"id"`
 	BrandID      int64  `db:"brand_id" json:"brand_id"`
@@ -7,4 +9,110 @@ type Medicine struct {
 	Type         string `db:"type" json:"type"`
 	GenericName  string `db:"generic_name" json:"generic_name"`
 	Manufacturer string `db:"manufacturer" json:"manufacturer"`
+	Slug         string `db:"slug" json:"slug"`
+	DosageForm   string `db:"dosage_form" json:"dosage_form"`
+	Strength     string `db:"strength" json:"strength"`
+	// PackageContainer and PackageSize are the catalog's pack and price text,
+	// such as "100 ml bottle: ৳ 40.12".
+	PackageContainer string `db:"package_container" json:"package_container"`
+	PackageSize      string `db:"package_size" json:"package_size"`
+	// DiscontinuedAt is set once the brand has left the catalog. Such brands
+	// are hidden from search but still resolve for existing stock and sales.
+	DiscontinuedAt *time.Time `db:"discontinued_at" json:"discontinued_at,omitempty"`
+	// Schedule is otc, prescription or controlled.
+	Schedule string `db:"schedule" json:"schedule"`
+}
+
+// RankedMedicine is a catalog search result with its relevance to the
+// query. Results are ordered by Score, highest first, then by ID.
+type RankedMedicine struct {
+	Medicine
+	Score float64 `db:"score"`
+}
+
+// Dispensing schedules, from least to most restricted. Prescription and
+// controlled medicines are only sold against a prescription; controlled ones
+// are also entered in the dispensing register.
+const (
+	ScheduleOTC          = "otc"
+	SchedulePrescription = "prescription"
+	ScheduleControlled   = "controlled"
+)
+
+// scheduleRank orders schedules from least to most restricted.
+var scheduleRank = map[string]int{
+	ScheduleOTC:          0,
+	SchedulePrescription: 1,
+	ScheduleControlled:   2,
+}
+
+// IsSchedule reports whether s is a dispensing schedule.
+func IsSchedule(s string) bool {
+	_, ok := scheduleRank[s]
+	return ok
+}
+
+// Stricter returns the more restricted of schedules a and b.
+func Stricter(a, b string) string {
+	if scheduleRank[b] > scheduleRank[a] {
+		return b
+	}
+	return a
+}
+
+// MedicinePack is a pack of a catalog medicine with its regulated retail
+// price (MRP), parsed from the catalog's package text.
+type MedicinePack struct {
+	ID          int64  `db:"id" json:"id"`
+	MedicineID  int64  `db:"medicine_id" json:"medicine_id"`
+	Description string `db:"description" json:"description"`
+	// Units is how many dispensing units the pack holds; single containers
+	// such as bottles and vials count as one.
+	Units int64 `db:"units" json:"units"`
+	MRP   Money `db:"mrp" json:"mrp"`
+}
+
+// Catalog submission statuses.
+const (
+	SubmissionPending  = "pending"
+	SubmissionMerged   = "merged"
+	SubmissionAdded    = "added"
+	SubmissionRejected = "rejected"
+)
+
+// CatalogSubmission proposes a pharmacy's custom inventory medicine for the
+// shared catalog. MedicineID is the catalog medicine it was merged into or
+// added as.
+type CatalogSubmission struct {
+	ID           int64   `db:"id" json:"id"`
+	PharmacyID   int64   `db:"pharmacy_id" json:"pharmacy_id"`
+	InventoryID  int64   `db:"inventory_id" json:"inventory_id"`
+	BrandName    string  `db:"brand_name" json:"brand_name"`
+	GenericName  string  `db:"generic_name" json:"generic_name"`
+	Manufacturer string  `db:"manufacturer" json:"manufacturer"`
+	Type         string  `db:"type" json:"type"`
+	Strength     string  `db:"strength" json:"strength"`
+	DosageForm   string  `db:"dosage_form" json:"dosage_form"`
+	Status       string  `db:"status" json:"status"`
+	MedicineID   *int64  `db:"medicine_id" json:"medicine_id,omitempty"`
+	Note         *string `db:"note" json:"note,omitempty"`
+	SubmittedBy  *int64  `db:"submitted_by" json:"submitted_by,omitempty"`
+	ReviewedAt   *string `db:"reviewed_at" json:"reviewed_at,omitempty"`
+	CreatedAt    string  `db:"created_at" json:"created_at"`
+	// Candidates are catalog medicines the submission may duplicate, most
+	// similar first. They are listed for pending submissions.
+	Candidates []CatalogCandidate `db:"-" json:"candidates,omitempty"`
+}
+
+// CatalogCandidate is a catalog medicine resembling a submission.
+type CatalogCandidate struct {
+	Medicine
+	// Similarity runs from 0 to 1.
+	Similarity float64 `db:"similarity" json:"similarity"`
+}
+
+// Relinked counts the rows a merged submission moved onto the catalog.
+type Relinked struct {
+	Inventory int64 `json:"inventory"`
+	SaleItems int64 `json:"sale_items"`
 }
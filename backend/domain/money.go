@@ -0,0 +1,201 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in paisa (1/100 taka). It is stored as NUMERIC(12,2)
+// and encoded in JSON as a decimal number of taka, e.g. 12.50.
+//
+// Rounding rules: every operation that produces fractions of a paisa rounds
+// half away from zero, and sale totals are rounded to whole taka the same way.
+type Money int64
+
+// Paisa returns n paisa.
+func Paisa(n int64) Money { return Money(n) }
+
+// Taka returns n whole taka.
+func Taka(n int64) Money { return Money(n * 100) }
+
+var hundred = big.NewRat(100, 1)
+
+// maxMoneyLength bounds the text ParseMoney accepts, which is far longer
+// than any amount that fits in a Money.
+const maxMoneyLength = 32
+
+// decimal matches a plain decimal number: no exponent, fraction or base
+// prefix, which big.Rat would otherwise accept.
+var decimal = regexp.MustCompile(`^[+-]?([0-9]+\.?[0-9]*|\.[0-9]+)$`)
+
+// ParseMoney parses a decimal taka amount such as "12.5" or "-3.335",
+// rounding to the nearest paisa.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if len(s) > maxMoneyLength || !decimal.MatchString(s) {
+		return 0, fmt.Errorf("invalid money amount %q", truncate(s, maxMoneyLength))
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("invalid money amount %q", s)
+	}
+	r.Mul(r, hundred)
+	return ratToMoney(r)
+}
+
+// ErrDivideByZero is returned by MulRatio for a zero denominator.
+var ErrDivideByZero = errors.New("money divided by zero")
+
+// MulRatio returns m * num / den rounded to the nearest paisa. It is used to
+// price part of a pack exactly: packPrice.MulRatio(quantity, packSize). It
+// fails with ErrDivideByZero or ErrMoneyRange.
+func (m Money) MulRatio(num, den int64) (Money, error) {
+	if den == 0 {
+		return 0, ErrDivideByZero
+	}
+	r := new(big.Rat).SetFrac(new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(num)), big.NewInt(den))
+	return ratToMoney(r)
+}
+
+// Add returns m + n, failing with ErrMoneyRange on overflow.
+func (m Money) Add(n Money) (Money, error) {
+	sum := m + n
+	if (n > 0 && sum < m) || (n < 0 && sum > m) {
+		return 0, ErrMoneyRange
+	}
+	return sum, nil
+}
+
+// Sub returns m - n, failing with ErrMoneyRange on overflow.
+func (m Money) Sub(n Money) (Money, error) {
+	diff := m - n
+	if (n > 0 && diff > m) || (n < 0 && diff < m) {
+		return 0, ErrMoneyRange
+	}
+	return diff, nil
+}
+
+// MaxStored is the largest amount the NUMERIC(12,2) money columns hold.
+const MaxStored Money = 999_999_999_999
+
+// RoundTaka rounds to whole taka.
+func (m Money) RoundTaka() Money {
+	return Money(roundDiv(int64(m), 100) * 100)
+}
+
+// Paisa returns the amount in paisa.
+func (m Money) Paisa() int64 { return int64(m) }
+
+// Float64 returns the amount in taka for metrics and logs; never compute with it.
+func (m Money) Float64() float64 { return float64(m) / 100 }
+
+// String formats the amount in taka with two decimals.
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
+}
+
+// MarshalJSON encodes the amount as a JSON number of taka.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or numeric string of taka without
+// passing through float64.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Scan implements sql.Scanner for NUMERIC columns.
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case string:
+		return m.scanString(v)
+	case []byte:
+		return m.scanString(string(v))
+	case int64:
+		*m = Taka(v)
+		return nil
+	case float64:
+		return m.scanString(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		return fmt.Errorf("unsupported money type %T", src)
+	}
+}
+
+func (m *Money) scanString(s string) error {
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Value implements driver.Valuer, sending an exact decimal string.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// ErrMoneyRange means an amount does not fit in a Money.
+var ErrMoneyRange = errors.New("money amount out of range")
+
+// ratToMoney rounds r (in paisa) half away from zero.
+func ratToMoney(r *big.Rat) (Money, error) {
+	num := new(big.Int).Set(r.Num())
+	den := r.Denom()
+	neg := num.Sign() < 0
+	num.Abs(num)
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Lsh(rem, 1).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if !q.IsInt64() {
+		return 0, ErrMoneyRange
+	}
+	if neg {
+		q.Neg(q)
+	}
+	return Money(q.Int64()), nil
+}
+
+// truncate shortens s to at most n bytes for error messages.
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n] + "..."
+	}
+	return s
+}
+
+// roundDiv divides a by b (b > 0) rounding half away from zero.
+func roundDiv(a, b int64) int64 {
+	if a < 0 {
+		return -((-a + b/2) / b)
+	}
+	return (a + b/2) / b
+}
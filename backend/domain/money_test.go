@@ -0,0 +1,187 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+		ok   bool
+	}{
+		{"12.50", 1250, true},
+		{"12.5", 1250, true},
+		{" 7 ", 700, true},
+		{".5", 50, true},
+		{"3.", 300, true},
+		{"+1.01", 101, true},
+		{"-3.335", -334, true},
+		{"0.005", 1, true},
+		{"-0.005", -1, true},
+		{"0.0049", 0, true},
+		{"92233720368547758.07", math.MaxInt64, true},
+		{"92233720368547758.08", 0, false},
+		{"1e3", 0, false},
+		{"1E3", 0, false},
+		{"1e999999999", 0, false},
+		{"1/3", 0, false},
+		{"0x10", 0, false},
+		{"", 0, false},
+		{".", 0, false},
+		{"12,50", 0, false},
+		{"NaN", 0, false},
+		{"1" + strings.Repeat("0", 40), 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if tt.ok != (err == nil) {
+			t.Errorf("ParseMoney(%q) error = %v, want ok %t", tt.in, err, tt.ok)
+			continue
+		}
+		if tt.ok && got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMulRatio(t *testing.T) {
+	tests := []struct {
+		name     string
+		m        Money
+		num, den int64
+		want     Money
+		err      error
+	}{
+		{"whole pack", Taka(10), 3, 3, Taka(10), nil},
+		{"one of three rounds up", Taka(10), 1, 3, 333, nil},
+		{"two of three rounds up", Taka(10), 2, 3, 667, nil},
+		{"half paisa away from zero", 1, 1, 2, 1, nil},
+		{"negative half paisa away from zero", -1, 1, 2, -1, nil},
+		{"discount basis points", Taka(199), 1250, 10000, 2488, nil},
+		{"zero denominator", Taka(10), 1, 0, 0, ErrDivideByZero},
+		{"overflow", Money(math.MaxInt64), 2, 1, 0, ErrMoneyRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.m.MulRatio(tt.num, tt.den)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdd(t *testing.T) {
+	if got, err := Taka(1).Add(Paisa(50)); err != nil || got != 150 {
+		t.Errorf("Add = %d, %v, want 150", got, err)
+	}
+	if _, err := Money(math.MaxInt64).Add(1); !errors.Is(err, ErrMoneyRange) {
+		t.Errorf("overflow err = %v, want ErrMoneyRange", err)
+	}
+	if _, err := Money(math.MinInt64).Add(-1); !errors.Is(err, ErrMoneyRange) {
+		t.Errorf("underflow err = %v, want ErrMoneyRange", err)
+	}
+}
+
+func TestSub(t *testing.T) {
+	if got, err := Taka(1).Sub(Paisa(150)); err != nil || got != -50 {
+		t.Errorf("Sub = %d, %v, want -50", got, err)
+	}
+	if _, err := Money(math.MinInt64).Sub(1); !errors.Is(err, ErrMoneyRange) {
+		t.Errorf("underflow err = %v, want ErrMoneyRange", err)
+	}
+	if _, err := Money(math.MaxInt64).Sub(-1); !errors.Is(err, ErrMoneyRange) {
+		t.Errorf("overflow err = %v, want ErrMoneyRange", err)
+	}
+	if _, err := Money(0).Sub(math.MinInt64); !errors.Is(err, ErrMoneyRange) {
+		t.Errorf("negating the minimum err = %v, want ErrMoneyRange", err)
+	}
+}
+
+func TestRoundTaka(t *testing.T) {
+	tests := []struct {
+		in, want Money
+	}{
+		{1249, 1200},
+		{1250, 1300},
+		{-1250, -1300},
+		{-1249, -1200},
+		{0, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.in.RoundTaka(); got != tt.want {
+			t.Errorf("%d.RoundTaka() = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		in   Money
+		want string
+	}{
+		{1250, "12.50"},
+		{5, "0.05"},
+		{-5, "-0.05"},
+		{-1250, "-12.50"},
+		{0, "0.00"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	var v struct {
+		A Money  `json:"a"`
+		B Money  `json:"b"`
+		C *Money `json:"c"`
+	}
+	if err := json.Unmarshal([]byte(`{"a": 12.345, "b": "7.10", "c": null}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.A != 1235 || v.B != 710 || v.C != nil {
+		t.Errorf("decoded %d, %d, %v", v.A, v.B, v.C)
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"a":12.35,"b":7.10,"c":null}` {
+		t.Errorf("encoded %s", out)
+	}
+	for _, in := range []string{`{"a": 1e999999999}`, `{"a": "1/3"}`, `{"a": true}`} {
+		if err := json.Unmarshal([]byte(in), &v); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded", in)
+		}
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		src  any
+		want Money
+	}{
+		{"12.50", 1250},
+		{[]byte("-0.01"), -1},
+		{int64(3), 300},
+		{float64(2.5), 250},
+		{nil, 0},
+	}
+	for _, tt := range tests {
+		var m Money
+		if err := m.Scan(tt.src); err != nil || m != tt.want {
+			t.Errorf("Scan(%v) = %d, %v, want %d", tt.src, m, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package domain
+
+// Prescription is a patient's prescription kept by a pharmacy, so refills
+// are dispensed against it instead of being re-entered.
+type Prescription struct {
+	ID          int64  `db:"id" json:"id"`
+	PharmacyID  int64  `db:"pharmacy_id" json:"pharmacy_id"`
+	PatientName string `db:"patient_name" json:"patient_name"`
+	// PatientPhone holds digits only.
+	PatientPhone       string `db:"patient_phone" json:"patient_phone,omitempty"`
+	PatientAddress     string `db:"patient_address" json:"patient_address,omitempty"`
+	Prescriber         string `db:"prescriber" json:"prescriber"`
+	RegistrationNumber string `db:"registration_number" json:"registration_number"`
+	// PrescribedOn is the prescription's date (YYYY-MM-DD).
+	PrescribedOn string `db:"prescribed_on" json:"prescribed_on"`
+	Note         string `db:"note" json:"note,omitempty"`
+	// ScanKey names the uploaded scan in the attachment store.
+	ScanKey   *string            `db:"scan_key" json:"-"`
+	HasScan   bool               `db:"has_scan" json:"has_scan"`
+	CreatedBy *int64             `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt string             `db:"created_at" json:"created_at"`
+	Items     []PrescriptionItem `db:"-" json:"items"`
+}
+
+// PrescriptionItem is one prescribed medicine. Each fill dispenses up to
+// Quantity units and Refills further fills are allowed, so Quantity times
+// Refills+1 units can be dispensed in all.
+type PrescriptionItem struct {
+	ID             int64 `db:"id" json:"id"`
+	PrescriptionID int64 `db:"prescription_id" json:"prescription_id"`
+	// MedicineID is the catalog medicine prescribed, when known. Sale lines
+	// of that medicine fill the item.
+	MedicineID *int64 `db:"medicine_id" json:"medicine_id,omitempty"`
+	// Description is the medicine as written on the prescription.
+	Description string `db:"description" json:"description"`
+	// Dosage is the instructions, such as "1+0+1 after meals for 7 days".
+	Dosage    string `db:"dosage" json:"dosage,omitempty"`
+	Quantity  int64  `db:"quantity" json:"quantity"`
+	Refills   int64  `db:"refills" json:"refills"`
+	Dispensed int64  `db:"dispensed" json:"dispensed"`
+	// Remaining is how many units can still be dispensed.
+	Remaining int64 `db:"remaining" json:"remaining"`
+	// RefillsRemaining counts the fills left after the current one, which
+	// may have been dispensed partially.
+	RefillsRemaining int64 `db:"refills_remaining" json:"refills_remaining"`
+}
+
+// PrescriptionFill is units of a prescription item dispensed by a sale line.
+type PrescriptionFill struct {
+	ID                 int64  `db:"id" json:"id"`
+	PrescriptionItemID int64  `db:"prescription_item_id" json:"prescription_item_id"`
+	SaleID             int64  `db:"sale_id" json:"sale_id"`
+	SaleItemID         int64  `db:"sale_item_id" json:"sale_item_id"`
+	Quantity           int64  `db:"quantity" json:"quantity"`
+	CreatedAt          string `db:"created_at" json:"created_at"`
+}
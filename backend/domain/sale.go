@@ -1,22 +1,124 @@
 package domain
 
 type Sale struct {
-	ID          int64   `db:"id" json:"id"`
-	PharmacyID  int64   `db:"pharmacy_id" json:"pharmacy_id"`
-	UserID      *int64  `db:"user_id" json:"user_id,omitempty"`
-	TotalAmount float64 `db:"total_amount" json:"total_amount"`
-	Discount    float64 `db:"discount" json:"discount"`
-	PaidAmount  float64 `db:"paid_amount" json:"paid_amount"`
-	DueAmount   float64 `db:"due_amount" json:"due_amount"`
-	CreatedAt   string  `db:"created_at" json:"created_at"`
+	ID             int64  `db:"id" json:"id"`
+	PharmacyID     int64  `db:"pharmacy_id" json:"pharmacy_id"`
+	UserID         *int64 `db:"user_id" json:"user_id,omitempty"`
+	APIKeyID       *int64 `db:"api_key_id" json:"api_key_id,omitempty"`
+	TotalAmount    Money  `db:"total_amount" json:"total_amount"`
+	Discount       Money  `db:"discount" json:"discount"`
+	PaidAmount     Money  `db:"paid_amount" json:"paid_amount"`
+	DueAmount      Money  `db:"due_amount" json:"due_amount"`
+	RoundOff       Money  `db:"round_off" json:"round_off"`
+	ChangeReturned Money  `db:"change_returned" json:"change_returned"`
+	// IdempotencyKey is the client's key for a replayable sale; RequestHash
+	// fingerprints the request it was first used with.
+	IdempotencyKey *string `db:"idempotency_key" json:"idempotency_key,omitempty"`
+	RequestHash    *string `db:"request_hash" json:"-"`
+	CreatedAt      string  `db:"created_at" json:"created_at"`
 }
 
 type SaleItem struct {
+	ID          int64  `db:"id" json:"id"`
+	SaleID      int64  `db:"sale_id" json:"sale_id"`
+	MedicineID  *int64 `db:"medicine_id" json:"medicine_id"`
+	InventoryID int64  `db:"inventory_id" json:"inventory_id"`
+	Quantity    int64  `db:"quantity" json:"quantity"`
+	UnitPrice   Money  `db:"unit_price" json:"unit_price"`
+	Subtotal    Money  `db:"subtotal" json:"subtotal"`
+	// Schedule is the schedule the line was sold under.
+	Schedule string `db:"schedule" json:"schedule"`
+}
+
+// SaleItemDetail is a sold line with the medicine name resolved.
+type SaleItemDetail struct {
+	SaleID      int64  `db:"sale_id" json:"sale_id"`
+	MedicineID  *int64 `db:"medicine_id" json:"medicine_id"`
+	InventoryID *int64 `db:"inventory_id" json:"inventory_id"`
+	BrandName   string `db:"brand_name" json:"brand_name"`
+	Quantity    int64  `db:"quantity" json:"quantity"`
+	UnitPrice   Money  `db:"unit_price" json:"unit_price"`
+	Subtotal    Money  `db:"subtotal" json:"subtotal"`
+}
+
+// SaleReport is a sale together with its line items.
+type SaleReport struct {
+	Sale
+	Items []SaleItemDetail `json:"items"`
+}
+
+// SalesSummary aggregates revenue over a period.
+type SalesSummary struct {
+	Revenue    Money `db:"revenue" json:"revenue"`
+	SalesCount int64 `db:"sales_count" json:"sales_count"`
+}
+
+// SaleConflict is a line of an offline sale that sold more than was in
+// stock, or that sold a prescription-only or controlled medicine without a
+// prescription covering it, as told by Kind. For prescription conflicts
+// Available is the units a prescription covered. It stays open until the
+// owner reviews it.
+type SaleConflict struct {
 	ID          int64   `db:"id" json:"id"`
+	Kind        string  `db:"kind" json:"kind"`
+	PharmacyID  int64   `db:"pharmacy_id" json:"pharmacy_id"`
 	SaleID      int64   `db:"sale_id" json:"sale_id"`
-	MedicineID  int64   `db:"medicine_id" json:"medicine_id"`
+	SaleItemID  int64   `db:"sale_item_id" json:"sale_item_id"`
 	InventoryID int64   `db:"inventory_id" json:"inventory_id"`
-	Quantity    int64   `db:"quantity" json:"quantity"`
-	UnitPrice   float64 `db:"unit_price" json:"unit_price"`
-	Subtotal    float64 `db:"subtotal" json:"subtotal"`
+	BrandName   string  `db:"brand_name" json:"brand_name"`
+	Requested   int64   `db:"requested" json:"requested"`
+	Available   int64   `db:"available" json:"available"`
+	Shortfall   int64   `db:"shortfall" json:"shortfall"`
+	Resolution  *string `db:"resolution" json:"resolution,omitempty"`
+	ResolvedBy  *int64  `db:"resolved_by" json:"resolved_by,omitempty"`
+	ResolvedAt  *string `db:"resolved_at" json:"resolved_at,omitempty"`
+	CreatedAt   string  `db:"created_at" json:"created_at"`
+}
+
+// Kinds of sale conflicts.
+const (
+	ConflictStock        = "stock"
+	ConflictPrescription = "prescription"
+)
+
+// SalePrescription is the prescription a sale of prescription-only or
+// controlled medicines was dispensed against.
+type SalePrescription struct {
+	SaleID             int64   `db:"sale_id" json:"sale_id"`
+	Prescriber         string  `db:"prescriber" json:"prescriber"`
+	RegistrationNumber string  `db:"registration_number" json:"registration_number"`
+	PatientName        string  `db:"patient_name" json:"patient_name"`
+	PatientAddress     *string `db:"patient_address" json:"patient_address,omitempty"`
+	// PrescribedOn is the prescription's date (YYYY-MM-DD).
+	PrescribedOn string `db:"prescribed_on" json:"prescribed_on"`
+	// ImageRef points to a scan of the prescription.
+	ImageRef *string `db:"image_ref" json:"image_ref,omitempty"`
+	// PrescriptionID is the stored prescription the sale filled, if any.
+	PrescriptionID *int64 `db:"prescription_id" json:"prescription_id,omitempty"`
+}
+
+// ControlledDispense is an entry of the controlled drug dispensing register:
+// one line of a sale of a controlled medicine. Prescription fields are empty
+// for offline sales recorded without one, and MedicineID for custom medicines.
+type ControlledDispense struct {
+	SaleID             int64   `db:"sale_id" json:"sale_id"`
+	DispensedAt        string  `db:"dispensed_at" json:"dispensed_at"`
+	MedicineID         *int64  `db:"medicine_id" json:"medicine_id"`
+	BrandName          string  `db:"brand_name" json:"brand_name"`
+	GenericName        string  `db:"generic_name" json:"generic_name"`
+	Strength           string  `db:"strength" json:"strength"`
+	DosageForm         string  `db:"dosage_form" json:"dosage_form"`
+	Manufacturer       string  `db:"manufacturer" json:"manufacturer"`
+	BatchNumber        *string `db:"batch_number" json:"batch_number,omitempty"`
+	Quantity           int64   `db:"quantity" json:"quantity"`
+	PatientName        *string `db:"patient_name" json:"patient_name"`
+	PatientAddress     *string `db:"patient_address" json:"patient_address"`
+	Prescriber         *string `db:"prescriber" json:"prescriber"`
+	RegistrationNumber *string `db:"registration_number" json:"registration_number"`
+	PrescribedOn       *string `db:"prescribed_on" json:"prescribed_on"`
+	// DispensedBy is the user who made the sale, or the API key's name.
+	DispensedBy string `db:"dispensed_by" json:"dispensed_by"`
+	// Unverified is set for offline sales no prescription covered, which
+	// are queued for the owner as conflicts.
+	Unverified bool `db:"unverified" json:"unverified"`
 }
@@ -0,0 +1,27 @@
+package domain
+
+// InventoryChanges is a page of a pharmacy's inventory changes. Row ids are
+// never reused, so upserts and deletions in a page may be applied in any
+// order; the next page starts after Cursor.
+type InventoryChanges struct {
+	Upserts []InventoryItem `json:"upserts"`
+	Deleted []int64         `json:"deleted"`
+	Cursor  string          `json:"cursor"`
+	HasMore bool            `json:"has_more"`
+}
+
+// CatalogChanges is a page of medicine catalog changes.
+type CatalogChanges struct {
+	Upserts []Medicine `json:"upserts"`
+	Deleted []int64    `json:"deleted"`
+	Cursor  string     `json:"cursor"`
+	HasMore bool       `json:"has_more"`
+}
+
+// SaleChanges is a page of a pharmacy's sales, each with its line items.
+type SaleChanges struct {
+	Upserts []SaleReport `json:"upserts"`
+	Deleted []int64      `json:"deleted"`
+	Cursor  string       `json:"cursor"`
+	HasMore bool         `json:"has_more"`
+}
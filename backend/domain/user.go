@@ -1,11 +1,14 @@
 package domain
 
+import "time"
+
 type User struct {
-	ID         int    `json:"id" db:"id"`
-	Username   string `json:"username" db:"username"`
-	Email      string `json:"email" db:"email"`
-	Password   string `json:"password,omitempty" db:"password"`
-	Role       string `json:"role" db:"role"`
-	PharmacyID *int64 `json:"pharmacy_id,omitempty" db:"pharmacy_id"`
-	CreatedAt  string `json:"created_at,omitempty" db:"created_at"`
+	ID         int        `json:"id" db:"id"`
+	Username   string     `json:"username" db:"username"`
+	Email      string     `json:"email" db:"email"`
+	Password   string     `json:"password,omitempty" db:"password"`
+	Role       string     `json:"role" db:"role"`
+	PharmacyID *int64     `json:"pharmacy_id,omitempty" db:"pharmacy_id"`
+	CreatedAt  string     `json:"created_at,omitempty" db:"created_at"`
+	DisabledAt *time.Time `json:"disabled_at,omitempty" db:"disabled_at"`
 }
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"medeasy/m/domain"
+	"medeasy/m/internal/service"
+)
+
+// roleAPIKey is the role recorded in the request context for API key callers.
+const roleAPIKey = "api_key"
+
+// apiKeyFromRequest extracts an API key from the X-API-Key header or from a
+// bearer token carrying the API key prefix.
+func apiKeyFromRequest(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get("X-API-Key")); key != "" {
+		return key
+	}
+	header := r.Header.Get("Authorization")
+	if strings.HasPrefix(strings.ToLower(header), "bearer ") {
+		token := strings.TrimSpace(header[len("Bearer "):])
+		if strings.HasPrefix(token, service.APIKeyPrefix) {
+			return token
+		}
+	}
+	return ""
+}
+
+// requireScope lets API keys through only when they hold scope. User tokens
+// are unaffected and still go through the handler's own role checks.
+func (h *Handler) requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			apiKey, ok := r.Context().Value(ctxAPIKey).(domain.APIKey)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !apiKey.HasScope(scope) {
+				respondError(w, http.StatusForbidden, "api key is missing scope "+scope)
+				return
+			}
+			ctx := context.WithValue(r.Context(), ctxGrantedScope, scope)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// usersOnly rejects API key callers on endpoints that act on behalf of a person.
+func (h *Handler) usersOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(ctxAPIKey).(domain.APIKey); ok {
+			respondError(w, http.StatusForbidden, "api keys cannot access this endpoint")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func apiKeyIDFromContext(r *http.Request) int64 {
+	if apiKey, ok := r.Context().Value(ctxAPIKey).(domain.APIKey); ok {
+		return apiKey.ID
+	}
+	return 0
+}
+
+// API key handlers
+
+type apiKeyRequest struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresAt string   `json:"expires_at,omitempty"`
+}
+
+type apiKeyResponse struct {
+	Key    string        `json:"key"`
+	APIKey domain.APIKey `json:"api_key"`
+}
+
+type apiKeyRevokedResponse struct {
+	Status    string    `json:"status"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+func (h *Handler) createAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner) {
+		return
+	}
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
+		return
+	}
+	var req apiKeyRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	apiKey, key, err := h.services.APIKeys.Create(r.Context(), service.NewAPIKey{
+		PharmacyID: pharmacyID,
+		CreatedBy:  userIDFromContext(r),
+		Name:       req.Name,
+		Scopes:     req.Scopes,
+		ExpiresAt:  req.ExpiresAt,
+	})
+	if err != nil {
+		h.serviceError(w, r, "unable to create api key", err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, apiKeyResponse{Key: key, APIKey: apiKey})
+}
+
+func (h *Handler) listAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner) {
+		return
+	}
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
+		return
+	}
+	keys, err := h.services.APIKeys.List(r.Context(), pharmacyID)
+	if err != nil {
+		h.serviceError(w, r, "unable to list api keys", err)
+		return
+	}
+	respondJSON(w, http.StatusOK, keys)
+}
+
+func (h *Handler) revokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner) {
+		return
+	}
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
+		return
+	}
+	id, ok := urlID(w, r, "api key")
+	if !ok {
+		return
+	}
+	revokedAt, err := h.services.APIKeys.Revoke(r.Context(), pharmacyID, id)
+	if err != nil {
+		h.serviceError(w, r, "unable to revoke api key", err)
+		return
+	}
+	respondJSON(w, http.StatusOK, apiKeyRevokedResponse{Status: "revoked", RevokedAt: revokedAt})
+}
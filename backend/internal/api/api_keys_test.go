@@ -0,0 +1,228 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"medeasy/m/domain"
+	"medeasy/m/internal/config"
+	"medeasy/m/internal/service"
+)
+
+const testPharmacyID = 7
+
+// memoryAPIKeys is an in-memory APIKeyRepository.
+type memoryAPIKeys struct {
+	mu   sync.Mutex
+	keys []domain.APIKey
+}
+
+func (m *memoryAPIKeys) ByPrefix(_ context.Context, prefix string) (domain.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range m.keys {
+		if key.Prefix == prefix {
+			return key, nil
+		}
+	}
+	return domain.APIKey{}, service.ErrNotFound
+}
+
+func (m *memoryAPIKeys) TouchLastUsed(context.Context, int64) error { return nil }
+
+func (m *memoryAPIKeys) Create(_ context.Context, key *domain.APIKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key.ID = int64(len(m.keys) + 1)
+	key.CreatedAt = time.Now()
+	m.keys = append(m.keys, *key)
+	return nil
+}
+
+func (m *memoryAPIKeys) List(_ context.Context, pharmacyID int64) ([]domain.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var keys []domain.APIKey
+	for _, key := range m.keys {
+		if key.PharmacyID == pharmacyID {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (m *memoryAPIKeys) Revoke(_ context.Context, id, pharmacyID int64) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, key := range m.keys {
+		if key.ID == id && key.PharmacyID == pharmacyID && key.RevokedAt == nil {
+			now := time.Now()
+			m.keys[i].RevokedAt = &now
+			return now, nil
+		}
+	}
+	return time.Time{}, service.ErrNotFound
+}
+
+// tokenAuth accepts fixed bearer tokens in place of signed ones, so tests
+// need no user repository. API keys go through the real service.
+type tokenAuth struct {
+	service.Auth
+	tokens map[string]service.Claims
+}
+
+func (a tokenAuth) ParseToken(_ context.Context, token string) (service.Claims, error) {
+	if claims, ok := a.tokens[token]; ok {
+		return claims, nil
+	}
+	return service.Claims{}, &service.Error{Kind: service.KindUnauthorized, Message: "invalid token"}
+}
+
+// emptyReports answers the report endpoints the tests call.
+type emptyReports struct {
+	service.Reports
+}
+
+func (emptyReports) Daily(context.Context, int64) (domain.SalesSummary, error) {
+	return domain.SalesSummary{}, nil
+}
+
+func (emptyReports) Sales(context.Context, int64, string, string) ([]domain.SaleReport, error) {
+	return []domain.SaleReport{}, nil
+}
+
+type authTest struct {
+	t      *testing.T
+	router http.Handler
+}
+
+func newAuthTest(t *testing.T) *authTest {
+	services := service.New(service.Repositories{APIKeys: &memoryAPIKeys{}}, service.Config{Secret: "api-key-test-secret"})
+	services.Auth = tokenAuth{Auth: services.Auth, tokens: map[string]service.Claims{
+		"owner":      {UserID: 1, Role: service.RoleOwner, PharmacyID: testPharmacyID},
+		"pharmacist": {UserID: 2, Role: service.RolePharmacist, PharmacyID: testPharmacyID},
+	}}
+	services.Reports = emptyReports{}
+	h := NewWithServices(nil, services, config.Config{})
+	return &authTest{t: t, router: h.Router()}
+}
+
+// do sends a request authenticated by an API key (mek_...) or a test token.
+func (a *authTest) do(method, path, credential, body string) *httptest.ResponseRecorder {
+	a.t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if strings.HasPrefix(credential, service.APIKeyPrefix) {
+		req.Header.Set("X-API-Key", credential)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+credential)
+	}
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// issue creates a key through the owner API and returns its plaintext and id.
+func (a *authTest) issue(scopes ...string) (string, int64) {
+	a.t.Helper()
+	body, _ := json.Marshal(apiKeyRequest{Name: "integration", Scopes: scopes})
+	rec := a.do(http.MethodPost, "/api-keys", "owner", string(body))
+	if rec.Code != http.StatusCreated {
+		a.t.Fatalf("create api key: %d %s", rec.Code, rec.Body)
+	}
+	var resp apiKeyResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		a.t.Fatal(err)
+	}
+	return resp.Key, resp.APIKey.ID
+}
+
+func TestAPIKeyIsRefusedOutsideItsScopes(t *testing.T) {
+	a := newAuthTest(t)
+	catalogKey, _ := a.issue(domain.ScopeCatalogRead)
+	reportsKey, _ := a.issue(domain.ScopeReportsRead)
+
+	tests := []struct {
+		key, method, path string
+		want              int
+	}{
+		{catalogKey, http.MethodGet, "/reports/sales/daily", http.StatusForbidden},
+		{catalogKey, http.MethodGet, "/reports/sales", http.StatusForbidden},
+		{catalogKey, http.MethodGet, "/sync/sales", http.StatusForbidden},
+		{catalogKey, http.MethodPost, "/sales", http.StatusForbidden},
+		{catalogKey, http.MethodPost, "/sales/check", http.StatusForbidden},
+		{reportsKey, http.MethodGet, "/medicines?q=napa", http.StatusForbidden},
+		{reportsKey, http.MethodPost, "/inventory/scan", http.StatusForbidden},
+		{reportsKey, http.MethodGet, "/sync/inventory", http.StatusForbidden},
+		{reportsKey, http.MethodGet, "/reports/sales/daily", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if rec := a.do(tt.method, tt.path, tt.key, "{}"); rec.Code != tt.want {
+			t.Errorf("%s %s with %s key: %d %s, want %d", tt.method, tt.path, tt.key[:16], rec.Code, rec.Body, tt.want)
+		}
+	}
+}
+
+func TestAPIKeyIsRefusedOnUserRoutes(t *testing.T) {
+	a := newAuthTest(t)
+	key, _ := a.issue(domain.APIKeyScopes...)
+
+	for _, op := range operations() {
+		if op.access != accessUser {
+			continue
+		}
+		path := strings.NewReplacer("{id}", "1").Replace(op.path)
+		if rec := a.do(op.method, path, key, "{}"); rec.Code != http.StatusForbidden {
+			t.Errorf("%s %s with an api key: %d %s, want 403", op.method, op.path, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestRevokedAPIKeyIsRefused(t *testing.T) {
+	a := newAuthTest(t)
+	key, id := a.issue(domain.ScopeReportsRead)
+	if rec := a.do(http.MethodGet, "/reports/sales/daily", key, ""); rec.Code != http.StatusOK {
+		t.Fatalf("before revocation: %d %s", rec.Code, rec.Body)
+	}
+	if rec := a.do(http.MethodDelete, fmt.Sprintf("/api-keys/%d", id), "owner", ""); rec.Code != http.StatusOK {
+		t.Fatalf("revoke: %d %s", rec.Code, rec.Body)
+	}
+	if rec := a.do(http.MethodGet, "/reports/sales/daily", key, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("after revocation: %d %s, want 401", rec.Code, rec.Body)
+	}
+}
+
+func TestRequireRoleAdmitsAPIKeysOnlyByScope(t *testing.T) {
+	a := newAuthTest(t)
+	key, _ := a.issue(domain.ScopeReportsRead)
+
+	// The sales report is for owners; a key is admitted by its scope.
+	if rec := a.do(http.MethodGet, "/reports/sales", key, ""); rec.Code != http.StatusOK {
+		t.Errorf("key with reports:read: %d %s, want 200", rec.Code, rec.Body)
+	}
+	if rec := a.do(http.MethodGet, "/reports/sales", "pharmacist", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("pharmacist: %d %s, want 403", rec.Code, rec.Body)
+	}
+
+	h := newSpecHandler()
+	for _, granted := range []bool{false, true} {
+		ctx := context.WithValue(context.Background(), ctxRole, roleAPIKey)
+		if granted {
+			ctx = context.WithValue(ctx, ctxGrantedScope, domain.ScopeReportsRead)
+		}
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+		if ok := h.requireRole(rec, req, service.RoleOwner); ok != granted {
+			t.Errorf("requireRole with granted scope %v = %v", granted, ok)
+		}
+		if !granted && rec.Code != http.StatusForbidden {
+			t.Errorf("requireRole without a granted scope responded %d, want 403", rec.Code)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+
+	"medeasy/m/domain"
+	"medeasy/m/internal/service"
+)
+
+type scanRequest struct {
+	// Code is the scanner's output. GS1 group separators may be sent as
+	// the \u001d character.
+	Code string `json:"code"`
+}
+
+type scanResponse struct {
+	GTIN        string                 `json:"gtin"`
+	BatchNumber string                 `json:"batch_number,omitempty"`
+	ExpiryDate  string                 `json:"expiry_date,omitempty"`
+	Serial      string                 `json:"serial,omitempty"`
+	Medicine    *domain.Medicine       `json:"medicine"`
+	PackSize    *int64                 `json:"pack_size"`
+	Lot         *domain.InventoryItem  `json:"lot"`
+	Lots        []domain.InventoryItem `json:"lots"`
+}
+
+func (h *Handler) scanBarcode(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner, service.RoleEmployee, service.RolePharmacist) {
+		return
+	}
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
+		return
+	}
+	var req scanRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	scan, err := h.services.Inventory.Scan(r.Context(), pharmacyID, req.Code)
+	if err != nil {
+		h.serviceError(w, r, "unable to resolve barcode", err)
+		return
+	}
+	resp := scanResponse{
+		GTIN:        scan.GTIN,
+		BatchNumber: scan.Batch,
+		Serial:      scan.Serial,
+		Medicine:    scan.Medicine,
+		PackSize:    scan.PackSize,
+		Lot:         scan.Lot,
+		Lots:        scan.Lots,
+	}
+	if scan.Expiry != nil {
+		resp.ExpiryDate = scan.Expiry.Format("2006-01-02")
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
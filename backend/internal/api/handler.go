@@ -2,24 +2,25 @@ package api
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"math"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/jmoiron/sqlx"
-	"golang.org/x/crypto/bcrypt"
 
 	"medeasy/m/domain"
+	"medeasy/m/internal/config"
+	"medeasy/m/internal/logging"
+	"medeasy/m/internal/migrations"
+	"medeasy/m/internal/postgres"
+	"medeasy/m/internal/service"
+	"medeasy/m/internal/storage"
 )
 
 type ctxKey string
@@ -28,38 +29,86 @@ const (
 	ctxUserID     ctxKey = "userID"
 	ctxRole       ctxKey = "role"
 	ctxPharmacyID ctxKey = "pharmacyID"
+	ctxAPIKey     ctxKey = "apiKey"
+	// ctxGrantedScope is set once requireScope has admitted an API key.
+	ctxGrantedScope ctxKey = "grantedScope"
 )
 
-// Handler bundles dependencies for HTTP handlers.
+// Handler translates HTTP requests into service calls.
 type Handler struct {
-	db     *sqlx.DB
-	secret string
+	// db is only used for readiness checks; all data access goes through services.
+	db          *sqlx.DB
+	services    service.Services
+	corsOrigins []string
+	// location formats times in exported reports.
+	location *time.Location
+
+	metrics      *apiMetrics
+	metricsToken string
+	// metricsPublic mounts /metrics on the main router behind metricsToken.
+	metricsPublic bool
+
+	draining      atomic.Bool
+	inFlightSales atomic.Int64
 }
 
-// New constructs a Handler.
-func New(db *sqlx.DB, secret string) *Handler {
-	return &Handler{db: db, secret: secret}
+// New constructs a Handler backed by the Postgres repositories.
+func New(db *sqlx.DB, cfg config.Config) *Handler {
+	repos := postgres.New(db)
+	repos.Attachments = storage.NewDisk(cfg.AttachmentsDir)
+	services := service.New(repos, service.Config{Secret: cfg.Secret, TokenTTL: cfg.JWT.TTL, Location: cfg.Location})
+	return NewWithServices(db, services, cfg)
+}
+
+// NewWithServices constructs a Handler on top of the given services.
+func NewWithServices(db *sqlx.DB, services service.Services, cfg config.Config) *Handler {
+	location := cfg.Location
+	if location == nil {
+		location = time.UTC
+	}
+	return &Handler{
+		db:            db,
+		services:      services,
+		corsOrigins:   cfg.CORSOrigins,
+		location:      location,
+		metrics:       newAPIMetrics(db),
+		metricsToken:  cfg.Metrics.Token,
+		metricsPublic: cfg.Metrics.Addr == "" && cfg.Metrics.Token != "",
+	}
 }
 
 // Router wires up the HTTP API.
 func (h *Handler) Router() http.Handler {
+	return h.routes()
+}
+
+func (h *Handler) routes() chi.Router {
 	r := chi.NewRouter()
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"*"}, // Change "*" to a list of allowed domains (e.g., ["http://localhost:3000"])
+		AllowedOrigins:   h.corsOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-API-Key", requestIDHeader, idempotencyKeyHeader, "If-Match"},
+		ExposedHeaders:   []string{requestIDHeader, idempotentReplayedHeader, nextCursorHeader, "ETag"},
 		AllowCredentials: true,
 	}))
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
+	r.Use(h.requestLogger)
+	r.Use(h.instrument)
+	r.Use(h.recoverer)
 
-	r.Get("/health", h.health)
+	r.Get("/health", h.livez)
+	r.Get("/livez", h.livez)
+	r.Get("/readyz", h.readyz)
+	if h.metricsPublic {
+		r.Get("/metrics", h.metricsEndpoint)
+	}
+	r.Get("/openapi.json", h.openAPIDocument)
+	r.Get("/docs", h.docs)
 
 	r.Route("/auth", func(r chi.Router) {
 		r.Post("/register", h.register)
 		r.Post("/login", h.login)
 		r.Group(func(protected chi.Router) {
-			protected.Use(h.authMiddleware)
+			protected.Use(h.authMiddleware, h.usersOnly)
 			protected.Post("/reset-password", h.resetPassword)
 		})
 	})
@@ -68,92 +117,168 @@ func (h *Handler) Router() http.Handler {
 		pr.Use(h.authMiddleware)
 
 		pr.Route("/pharmacies", func(r chi.Router) {
+			r.Use(h.usersOnly)
 			r.Post("/", h.createPharmacy)
 			r.Get("/", h.listPharmacies)
 			r.Put("/{id}", h.updatePharmacy)
 		})
 
-		pr.Get("/medicines", h.searchMedicines)
+		pr.With(h.requireScope(domain.ScopeCatalogRead)).Get("/medicines", h.searchMedicines)
+		pr.With(h.requireScope(domain.ScopeCatalogRead)).Get("/medicines/{id}/packs", h.medicinePacks)
+		pr.With(h.requireScope(domain.ScopeCatalogRead)).Get("/medicines/{id}/substitutes", h.medicineSubstitutes)
 
 		pr.Route("/inventory", func(r chi.Router) {
-			r.Post("/", h.addInventory)
-			r.Put("/{id}", h.updateInventory)
-			r.Post("/{id}/stock", h.updateStock)
-			r.Get("/search", h.searchInventoryMedicines)
-			r.Get("/expiry-alert", h.expiryAlerts)
+			r.With(h.requireScope(domain.ScopeCatalogRead)).Get("/search", h.searchInventoryMedicines)
+			r.With(h.requireScope(domain.ScopeCatalogRead)).Post("/scan", h.scanBarcode)
+			r.Group(func(r chi.Router) {
+				r.Use(h.usersOnly)
+				r.Post("/", h.addInventory)
+				r.Get("/{id}", h.getInventory)
+				r.Put("/{id}", h.updateInventory)
+				r.Post("/{id}/stock", h.updateStock)
+				r.Post("/{id}/submit", h.submitInventory)
+				r.Get("/expiry-alert", h.expiryAlerts)
+			})
+		})
+
+		pr.Route("/catalog/submissions", func(r chi.Router) {
+			r.Use(h.usersOnly)
+			r.Get("/", h.listSubmissions)
+			r.Post("/{id}/merge", h.mergeSubmission)
+		})
+
+		pr.Route("/prescriptions", func(r chi.Router) {
+			r.Use(h.usersOnly)
+			r.Post("/", h.createPrescription)
+			r.Get("/", h.findPrescriptions)
+			r.Get("/{id}", h.getPrescription)
+			r.Put("/{id}/scan", h.uploadPrescriptionScan)
+			r.Get("/{id}/scan", h.prescriptionScan)
 		})
 
 		pr.Route("/sales", func(r chi.Router) {
-			r.Post("/", h.createSale)
+			r.With(h.requireScope(domain.ScopeSalesCreate)).Post("/", h.createSale)
+			r.With(h.requireScope(domain.ScopeSalesCreate)).Post("/check", h.checkSaleInteractions)
+			r.With(h.usersOnly).Get("/conflicts", h.listSaleConflicts)
+			r.With(h.usersOnly).Post("/conflicts/{id}/resolve", h.resolveSaleConflict)
 		})
 
 		pr.Route("/reports", func(r chi.Router) {
+			r.Use(h.requireScope(domain.ScopeReportsRead))
 			r.Get("/sales/daily", h.dailySales)
 			r.Get("/sales/monthly", h.monthlySales)
 			r.Get("/sales", h.salesReport)
+			r.Get("/controlled-register", h.controlledRegister)
+		})
+
+		pr.Route("/sync", func(r chi.Router) {
+			r.With(h.requireScope(domain.ScopeCatalogRead)).Get("/catalog", h.syncCatalog)
+			r.With(h.requireScope(domain.ScopeCatalogRead)).Get("/inventory", h.syncInventory)
+			r.With(h.requireScope(domain.ScopeReportsRead)).Get("/sales", h.syncSales)
+		})
+
+		pr.Route("/api-keys", func(r chi.Router) {
+			r.Use(h.usersOnly)
+			r.Post("/", h.createAPIKey)
+			r.Get("/", h.listAPIKeys)
+			r.Delete("/{id}", h.revokeAPIKey)
 		})
 	})
 
 	return r
 }
 
-func (h *Handler) health(w http.ResponseWriter, r *http.Request) {
-	respondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+// StartDraining makes /readyz fail so no new traffic is routed here during
+// shutdown. The server keeps serving until load balancers have noticed.
+func (h *Handler) StartDraining() {
+	h.draining.Store(true)
 }
 
-// Authentication helpers
+// InFlightSales reports how many sales are currently being written.
+func (h *Handler) InFlightSales() int64 {
+	return h.inFlightSales.Load()
+}
 
-type authClaims struct {
-	UserID     int64  `json:"user_id"`
-	Role       string `json:"role"`
-	PharmacyID int64  `json:"pharmacy_id"`
-	jwt.RegisteredClaims
+type healthResponse struct {
+	Status          string `json:"status"`
+	Error           string `json:"error,omitempty"`
+	SchemaVersion   int64  `json:"schema_version,omitempty"`
+	ExpectedVersion int64  `json:"expected_version,omitempty"`
 }
 
-func (h *Handler) generateToken(userID int64, role string, pharmacyID int64) (string, error) {
-	claims := authClaims{
-		UserID:     userID,
-		Role:       role,
-		PharmacyID: pharmacyID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+func (h *Handler) livez(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+func (h *Handler) readyz(w http.ResponseWriter, r *http.Request) {
+	if h.draining.Load() {
+		respondJSON(w, http.StatusServiceUnavailable, healthResponse{Status: "draining"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+	if err := h.db.PingContext(ctx); err != nil {
+		recordError(r, err)
+		respondJSON(w, http.StatusServiceUnavailable, healthResponse{Status: "unavailable", Error: "database unreachable"})
+		return
+	}
+	current, err := migrations.Current(ctx, h.db)
+	if err != nil {
+		recordError(r, err)
+		respondJSON(w, http.StatusServiceUnavailable, healthResponse{Status: "unavailable", Error: "unable to read schema version"})
+		return
+	}
+	latest, err := migrations.Latest()
+	if err != nil {
+		recordError(r, err)
+		respondJSON(w, http.StatusServiceUnavailable, healthResponse{Status: "unavailable", Error: "unable to load migrations"})
+		return
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(h.secret))
+	if current != latest {
+		respondJSON(w, http.StatusServiceUnavailable, healthResponse{Status: "unavailable", Error: "schema version mismatch", SchemaVersion: current, ExpectedVersion: latest})
+		return
+	}
+	respondJSON(w, http.StatusOK, healthResponse{Status: "ok", SchemaVersion: current})
 }
 
+// Authentication helpers
+
 func (h *Handler) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := apiKeyFromRequest(r); key != "" {
+			apiKey, err := h.services.Auth.AuthenticateAPIKey(r.Context(), key)
+			if err != nil {
+				h.serviceError(w, r, "unable to authenticate api key", err)
+				return
+			}
+			if info := logging.RequestInfoFrom(r.Context()); info != nil {
+				info.PharmacyID = apiKey.PharmacyID
+				info.APIKeyID = apiKey.ID
+			}
+			ctx := context.WithValue(r.Context(), ctxRole, roleAPIKey)
+			ctx = context.WithValue(ctx, ctxAPIKey, apiKey)
+			ctx = context.WithValue(ctx, ctxPharmacyID, apiKey.PharmacyID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
 		header := r.Header.Get("Authorization")
 		if header == "" || !strings.HasPrefix(strings.ToLower(header), "bearer ") {
 			respondError(w, http.StatusUnauthorized, "missing bearer token")
 			return
 		}
-		tokenString := strings.TrimSpace(header[len("Bearer "):])
-		token, err := jwt.ParseWithClaims(tokenString, &authClaims{}, func(token *jwt.Token) (interface{}, error) {
-			if token.Method != jwt.SigningMethodHS256 {
-				return nil, errors.New("unexpected signing method")
-			}
-			return []byte(h.secret), nil
-		})
-		if err != nil || !token.Valid {
-			respondError(w, http.StatusUnauthorized, "invalid token")
-			return
-		}
-		claims, ok := token.Claims.(*authClaims)
-		if !ok {
-			respondError(w, http.StatusUnauthorized, "invalid token claims")
+		claims, err := h.services.Auth.ParseToken(r.Context(), strings.TrimSpace(header[len("Bearer "):]))
+		if err != nil {
+			h.serviceError(w, r, "unable to authenticate token", err)
 			return
 		}
 		ctx := context.WithValue(r.Context(), ctxUserID, claims.UserID)
 		ctx = context.WithValue(ctx, ctxRole, claims.Role)
-		if claims.PharmacyID <= 0 {
-			respondError(w, http.StatusForbidden, "user is not linked to a pharmacy")
-			return
-		}
 		ctx = context.WithValue(ctx, ctxPharmacyID, claims.PharmacyID)
+		if info := logging.RequestInfoFrom(ctx); info != nil {
+			info.UserID = claims.UserID
+			info.PharmacyID = claims.PharmacyID
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -165,6 +290,14 @@ func (h *Handler) requireRole(w http.ResponseWriter, r *http.Request, allowed ..
 		return false
 	}
 	current := role.(string)
+	if current == roleAPIKey {
+		// API keys are admitted by scope rather than by role.
+		if r.Context().Value(ctxGrantedScope) != nil {
+			return true
+		}
+		respondError(w, http.StatusForbidden, "insufficient permissions")
+		return false
+	}
 	for _, allowedRole := range allowed {
 		if current == allowedRole {
 			return true
@@ -174,6 +307,28 @@ func (h *Handler) requireRole(w http.ResponseWriter, r *http.Request, allowed ..
 	return false
 }
 
+// requirePharmacy returns the caller's pharmacy, responding 403 when missing.
+func requirePharmacy(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	pharmacyID := pharmacyIDFromContext(r)
+	if pharmacyID <= 0 {
+		respondError(w, http.StatusForbidden, "invalid pharmacy context")
+		return 0, false
+	}
+	return pharmacyID, true
+}
+
+func userIDFromContext(r *http.Request) int64 {
+	if id, ok := r.Context().Value(ctxUserID).(int64); ok {
+		return id
+	}
+	return 0
+}
+
+func roleFromContext(r *http.Request) string {
+	role, _ := r.Context().Value(ctxRole).(string)
+	return role
+}
+
 func pharmacyIDFromContext(r *http.Request) int64 {
 	if val := r.Context().Value(ctxPharmacyID); val != nil {
 		if id, ok := val.(int64); ok {
@@ -183,6 +338,16 @@ func pharmacyIDFromContext(r *http.Request) int64 {
 	return 0
 }
 
+// urlID parses the {id} URL parameter, responding 400 when malformed.
+func urlID(w http.ResponseWriter, r *http.Request, what string) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid "+what+" id")
+		return 0, false
+	}
+	return id, true
+}
+
 // Auth Handlers
 
 type registerRequest struct {
@@ -208,109 +373,12 @@ func (h *Handler) register(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if req.Username == "" || req.Email == "" || req.Password == "" || req.Role == "" {
-		respondError(w, http.StatusBadRequest, "username, email, password and role are required")
-		return
-	}
-
-	if req.Role != "owner" && req.Role != "employee" {
-		respondError(w, http.StatusBadRequest, "role must be owner or employee")
-		return
-	}
-
-	if req.Role == "owner" && strings.TrimSpace(req.PharmacyName) == "" {
-		respondError(w, http.StatusBadRequest, "pharmacy_name is required for owners")
-		return
-	}
-	if req.Role == "employee" && req.PharmacyID <= 0 {
-		respondError(w, http.StatusBadRequest, "pharmacy_id is required for employees")
-		return
-	}
-
-	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to secure password")
-		return
-	}
-
-	tx, err := h.db.Beginx()
+	result, err := h.services.Auth.Register(r.Context(), service.RegisterInput(req))
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to start registration")
+		h.serviceError(w, r, "unable to complete registration", err)
 		return
 	}
-
-	if req.Role == "employee" {
-		var exists bool
-		if err := tx.Get(&exists, `SELECT EXISTS(SELECT 1 FROM pharmacies WHERE id = $1)`, req.PharmacyID); err != nil || !exists {
-			_ = tx.Rollback()
-			respondError(w, http.StatusBadRequest, "invalid pharmacy_id for employee")
-			return
-		}
-	}
-
-	var (
-		userID           int64
-		assignedPharmacy int64
-	)
-	pharmacyIDValue := sql.NullInt64{}
-	if req.Role == "employee" {
-		pharmacyIDValue = sql.NullInt64{Int64: req.PharmacyID, Valid: true}
-	}
-
-	err = tx.QueryRowx(`INSERT INTO users (username, email, password, role, pharmacy_id) VALUES ($1, $2, $3, $4, $5) RETURNING id`, req.Username, strings.ToLower(req.Email), hashed, req.Role, pharmacyIDValue).Scan(&userID)
-	if err != nil {
-		_ = tx.Rollback()
-		if strings.Contains(err.Error(), "unique constraint") || strings.Contains(err.Error(), "duplicate key") {
-			respondError(w, http.StatusConflict, "email already exists")
-		} else {
-			respondError(w, http.StatusInternalServerError, "db error: "+err.Error())
-		}
-		return
-	}
-
-	var pharmacy *domain.Pharmacy
-	if req.Role == "owner" {
-		var (
-			pharmacyID int64
-			createdAt  string
-		)
-		err = tx.QueryRowx(`INSERT INTO pharmacies (name, address, location, owner_id) VALUES ($1, $2, $3, $4) RETURNING id, created_at`,
-			req.PharmacyName, req.PharmacyAddress, req.PharmacyLocation, userID).Scan(&pharmacyID, &createdAt)
-		if err != nil {
-			_ = tx.Rollback()
-			respondError(w, http.StatusInternalServerError, "unable to create pharmacy for owner")
-			return
-		}
-		if _, err := tx.Exec(`UPDATE users SET pharmacy_id = $1 WHERE id = $2`, pharmacyID, userID); err != nil {
-			_ = tx.Rollback()
-			respondError(w, http.StatusInternalServerError, "unable to link owner to pharmacy")
-			return
-		}
-		assignedPharmacy = pharmacyID
-		pharmacy = &domain.Pharmacy{
-			ID:        pharmacyID,
-			Name:      req.PharmacyName,
-			Address:   req.PharmacyAddress,
-			Location:  req.PharmacyLocation,
-			OwnerID:   &userID,
-			CreatedAt: createdAt,
-		}
-	} else {
-		assignedPharmacy = req.PharmacyID
-	}
-
-	if err := tx.Commit(); err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to complete registration")
-		return
-	}
-
-	token, err := h.generateToken(userID, req.Role, assignedPharmacy)
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to generate token")
-		return
-	}
-
-	respondJSON(w, http.StatusCreated, authResponse{Token: token, User: domain.User{ID: int(userID), Username: req.Username, Email: strings.ToLower(req.Email), Role: req.Role, PharmacyID: &assignedPharmacy}, Pharmacy: pharmacy})
+	respondJSON(w, http.StatusCreated, authResponse{Token: result.Token, User: result.User, Pharmacy: result.Pharmacy})
 }
 
 type loginRequest struct {
@@ -324,57 +392,32 @@ func (h *Handler) login(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-
-	var user domain.User
-	err := h.db.Get(&user, `SELECT id, username, email, password, role, pharmacy_id FROM users WHERE email = $1`, strings.ToLower(req.Email))
-	if err != nil {
-		respondError(w, http.StatusUnauthorized, "invalid credentials")
-		return
-	}
-
-	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)) != nil {
-		respondError(w, http.StatusUnauthorized, "invalid credentials")
-		return
-	}
-
-	if user.PharmacyID == nil || *user.PharmacyID == 0 {
-		respondError(w, http.StatusForbidden, "user is not linked to a pharmacy")
-		return
-	}
-
-	token, err := h.generateToken(int64(user.ID), user.Role, *user.PharmacyID)
+	result, err := h.services.Auth.Login(r.Context(), req.Email, req.Password)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to generate token")
+		if reason := service.ReasonOf(err); reason != "" {
+			h.metrics.loginFailures.Inc(reason)
+		}
+		h.serviceError(w, r, "unable to log in", err)
 		return
 	}
+	respondJSON(w, http.StatusOK, authResponse{Token: result.Token, User: result.User})
+}
 
-	user.Password = ""
-	respondJSON(w, http.StatusOK, authResponse{Token: token, User: user})
+type resetPasswordRequest struct {
+	NewPassword string `json:"new_password"`
 }
 
 func (h *Handler) resetPassword(w http.ResponseWriter, r *http.Request) {
-	var payload struct {
-		NewPassword string `json:"new_password"`
-	}
+	var payload resetPasswordRequest
 	if err := decodeJSON(r, &payload); err != nil {
 		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if payload.NewPassword == "" {
-		respondError(w, http.StatusBadRequest, "new_password is required")
-		return
-	}
-	uid := r.Context().Value(ctxUserID).(int64)
-	hashed, err := bcrypt.GenerateFromPassword([]byte(payload.NewPassword), bcrypt.DefaultCost)
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to secure password")
-		return
-	}
-	if _, err := h.db.Exec(`UPDATE users SET password = $1 WHERE id = $2`, hashed, uid); err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to update password")
+	if err := h.services.Auth.ResetPassword(r.Context(), userIDFromContext(r), payload.NewPassword); err != nil {
+		h.serviceError(w, r, "unable to update password", err)
 		return
 	}
-	respondJSON(w, http.StatusOK, map[string]string{"status": "password updated"})
+	respondJSON(w, http.StatusOK, statusResponse{Status: "password updated"})
 }
 
 // Pharmacy handlers
@@ -385,8 +428,13 @@ type pharmacyRequest struct {
 	Location string `json:"location"`
 }
 
+type pharmacyCreatedResponse struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
 func (h *Handler) createPharmacy(w http.ResponseWriter, r *http.Request) {
-	if !h.requireRole(w, r, "owner") {
+	if !h.requireRole(w, r, service.RoleOwner) {
 		return
 	}
 	var req pharmacyRequest
@@ -394,27 +442,20 @@ func (h *Handler) createPharmacy(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if req.Name == "" {
-		respondError(w, http.StatusBadRequest, "name is required")
-		return
-	}
-	ownerID := r.Context().Value(ctxUserID).(int64)
-	var id int64
-	err := h.db.QueryRowx(`INSERT INTO pharmacies (name, address, location, owner_id) VALUES ($1, $2, $3, $4) RETURNING id`, req.Name, req.Address, req.Location, ownerID).Scan(&id)
+	pharmacy, err := h.services.Pharmacies.Create(r.Context(), userIDFromContext(r), service.PharmacyInput(req))
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to create pharmacy")
+		h.serviceError(w, r, "unable to create pharmacy", err)
 		return
 	}
-	respondJSON(w, http.StatusCreated, map[string]any{"id": id, "name": req.Name})
+	respondJSON(w, http.StatusCreated, pharmacyCreatedResponse{ID: pharmacy.ID, Name: pharmacy.Name})
 }
 
 func (h *Handler) updatePharmacy(w http.ResponseWriter, r *http.Request) {
-	if !h.requireRole(w, r, "owner") {
+	if !h.requireRole(w, r, service.RoleOwner) {
 		return
 	}
-	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
-	if err != nil {
-		respondError(w, http.StatusBadRequest, "invalid pharmacy id")
+	id, ok := urlID(w, r, "pharmacy")
+	if !ok {
 		return
 	}
 	var req pharmacyRequest
@@ -422,83 +463,84 @@ func (h *Handler) updatePharmacy(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if req.Name == "" {
-		respondError(w, http.StatusBadRequest, "name is required")
-		return
-	}
-	if _, err := h.db.Exec(`UPDATE pharmacies SET name = $1, address = $2, location = $3 WHERE id = $4`, req.Name, req.Address, req.Location, id); err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to update pharmacy")
+	if err := h.services.Pharmacies.Update(r.Context(), id, service.PharmacyInput(req)); err != nil {
+		h.serviceError(w, r, "unable to update pharmacy", err)
 		return
 	}
-	respondJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+	respondJSON(w, http.StatusOK, statusResponse{Status: "updated"})
 }
 
 func (h *Handler) listPharmacies(w http.ResponseWriter, r *http.Request) {
-	var pharmacies []domain.Pharmacy
-	if err := h.db.Select(&pharmacies, `SELECT id, name, address, location, owner_id, created_at FROM pharmacies`); err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to list pharmacies")
+	pharmacies, err := h.services.Pharmacies.List(r.Context())
+	if err != nil {
+		h.serviceError(w, r, "unable to list pharmacies", err)
 		return
 	}
 	respondJSON(w, http.StatusOK, pharmacies)
 }
 
 // Medicine search
+// nextCursorHeader carries the cursor of the next page of search results,
+// since the result body stays a plain array.
+const nextCursorHeader = "Next-Cursor"
+
 func (h *Handler) searchMedicines(w http.ResponseWriter, r *http.Request) {
-	query := strings.TrimSpace(r.URL.Query().Get("query"))
-	var medicines []domain.Medicine
-	if query == "" {
-		h.db.Select(&medicines, `SELECT id, brand_id, brand_name, type, generic_name, manufacturer FROM medicines ORDER BY brand_name LIMIT 25`)
-	} else {
-		like := "%" + query + "%"
-		h.db.Select(&medicines, `SELECT id, brand_id, brand_name, type, generic_name, manufacturer FROM medicines WHERE brand_name ILIKE $1 OR generic_name ILIKE $2 ORDER BY brand_name LIMIT 25`, like, like)
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	page, err := h.services.Catalog.Search(r.Context(), r.URL.Query().Get("query"), r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		h.serviceError(w, r, "unable to search medicines", err)
+		return
+	}
+	if page.Next != "" {
+		w.Header().Set(nextCursorHeader, page.Next)
 	}
-	respondJSON(w, http.StatusOK, medicines)
+	respondJSON(w, http.StatusOK, page.Medicines)
 }
 
-type inventorySearchResult struct {
-	InventoryID  int64   `db:"inventory_id" json:"inventory_id"`
-	MedicineID   *int64  `db:"medicine_id" json:"medicine_id"`
-	BrandName    string  `db:"brand_name" json:"brand_name"`
-	GenericName  string  `db:"generic_name" json:"generic_name"`
-	Manufacturer string  `db:"manufacturer" json:"manufacturer"`
-	Type         string  `db:"type" json:"type"`
-	Quantity     int64   `db:"quantity" json:"quantity"`
-	UnitCost     float64 `db:"cost_price" json:"unit_cost"`
-	UnitPrice    float64 `db:"sale_price" json:"unit_price"`
-	TotalCost    float64 `db:"total_cost" json:"total_cost"`
-	ExpiryDate   *string `db:"expiry_date" json:"expiry_date"`
+func (h *Handler) medicinePacks(w http.ResponseWriter, r *http.Request) {
+	id, ok := urlID(w, r, "medicine")
+	if !ok {
+		return
+	}
+	packs, err := h.services.Catalog.Packs(r.Context(), id)
+	if err != nil {
+		h.serviceError(w, r, "unable to list medicine packs", err)
+		return
+	}
+	respondJSON(w, http.StatusOK, packs)
 }
 
-func (h *Handler) searchInventoryMedicines(w http.ResponseWriter, r *http.Request) {
-	if !h.requireRole(w, r, "owner", "employee") {
+func (h *Handler) medicineSubstitutes(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner, service.RoleEmployee, service.RolePharmacist) {
 		return
 	}
-	pharmacyID := pharmacyIDFromContext(r)
-	if pharmacyID <= 0 {
-		respondError(w, http.StatusForbidden, "invalid pharmacy context")
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
+		return
+	}
+	id, ok := urlID(w, r, "medicine")
+	if !ok {
 		return
 	}
-	query := strings.TrimSpace(r.URL.Query().Get("query"))
-	args := []any{pharmacyID}
-	sqlQuery := `SELECT i.id AS inventory_id, i.medicine_id, i.quantity, i.cost_price, i.sale_price, i.expiry_date, 
-	             COALESCE(i.brand_name, m.brand_name, 'Unknown') as brand_name, 
-	             COALESCE(i.generic_name, m.generic_name, '') as generic_name, 
-	             COALESCE(i.manufacturer, m.manufacturer, '') as manufacturer, 
-	             COALESCE(i.type, m.type, '') as type, 
-	             (i.cost_price * i.quantity) AS total_cost
-                FROM inventory i
-                LEFT JOIN medicines m ON m.id = i.medicine_id
-                WHERE i.pharmacy_id = $1 AND i.quantity > 0`
-	if query != "" {
-		like := "%" + query + "%"
-		args = append(args, like)
-		sqlQuery += " AND (COALESCE(i.brand_name, m.brand_name) ILIKE $2 OR COALESCE(i.generic_name, m.generic_name) ILIKE $2)"
+	substitutes, err := h.services.Inventory.Substitutes(r.Context(), pharmacyID, id)
+	if err != nil {
+		h.serviceError(w, r, "unable to find substitutes", err)
+		return
 	}
-	sqlQuery += " ORDER BY brand_name LIMIT 25"
+	respondJSON(w, http.StatusOK, substitutes)
+}
 
-	var results []inventorySearchResult
-	if err := h.db.Select(&results, sqlQuery, args...); err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to search inventory")
+func (h *Handler) searchInventoryMedicines(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner, service.RoleEmployee, service.RolePharmacist) {
+		return
+	}
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
+		return
+	}
+	results, err := h.services.Inventory.Search(r.Context(), pharmacyID, r.URL.Query().Get("query"))
+	if err != nil {
+		h.serviceError(w, r, "unable to search inventory", err)
 		return
 	}
 	respondJSON(w, http.StatusOK, results)
@@ -507,99 +549,116 @@ func (h *Handler) searchInventoryMedicines(w http.ResponseWriter, r *http.Reques
 // Inventory handlers
 
 type inventoryRequest struct {
-	PharmacyID   int64   `json:"pharmacy_id"`
-	MedicineID   *int64  `json:"medicine_id"`
-	BrandName    string  `json:"brand_name"`
-	GenericName  string  `json:"generic_name"`
-	Manufacturer string  `json:"manufacturer"`
-	Type         string  `json:"type"`
-	Quantity     int64   `json:"quantity"`
-	CostPrice    float64 `json:"cost_price"`
-	SalePrice    float64 `json:"sale_price"`
-	ExpiryDate   string  `json:"expiry_date"`
+	PharmacyID   int64        `json:"pharmacy_id"`
+	MedicineID   *int64       `json:"medicine_id"`
+	BrandName    string       `json:"brand_name"`
+	GenericName  string       `json:"generic_name"`
+	Manufacturer string       `json:"manufacturer"`
+	Type         string       `json:"type"`
+	Quantity     int64        `json:"quantity"`
+	CostPrice    domain.Money `json:"cost_price"`
+	SalePrice    domain.Money `json:"sale_price"`
+	PackSize     int64        `json:"pack_size,omitempty"`
+	ExpiryDate   string       `json:"expiry_date"`
+	BatchNumber  string       `json:"batch_number,omitempty"`
+	// Barcode is a scan of the received pack; see POST /inventory/scan.
+	Barcode string `json:"barcode,omitempty"`
 }
 
-func (h *Handler) addInventory(w http.ResponseWriter, r *http.Request) {
-	if !h.requireRole(w, r, "owner", "employee") {
-		return
+func (req inventoryRequest) input() service.InventoryInput {
+	return service.InventoryInput{
+		MedicineID:   req.MedicineID,
+		BrandName:    req.BrandName,
+		GenericName:  req.GenericName,
+		Manufacturer: req.Manufacturer,
+		Type:         req.Type,
+		Quantity:     req.Quantity,
+		CostPrice:    req.CostPrice,
+		SalePrice:    req.SalePrice,
+		PackSize:     req.PackSize,
+		ExpiryDate:   req.ExpiryDate,
+		BatchNumber:  req.BatchNumber,
+		Barcode:      req.Barcode,
 	}
-	pharmacyID := pharmacyIDFromContext(r)
-	if pharmacyID <= 0 {
-		respondError(w, http.StatusForbidden, "invalid pharmacy context")
-		return
-	}
-	var req inventoryRequest
-	if err := decodeJSON(r, &req); err != nil {
-		respondError(w, http.StatusBadRequest, err.Error())
-		return
-	}
-	if req.Quantity <= 0 || req.CostPrice <= 0 || req.SalePrice <= 0 {
-		respondError(w, http.StatusBadRequest, "quantity, cost_price and sale_price are required")
-		return
+}
+
+type inventoryPriceResponse struct {
+	Status        string       `json:"status"`
+	UnitCostPrice domain.Money `json:"unit_cost_price"`
+	UnitSalePrice domain.Money `json:"unit_sale_price"`
+	PackSize      int64        `json:"pack_size"`
+	PackSalePrice domain.Money `json:"pack_sale_price"`
+	Version       int64        `json:"version"`
+	// MRP is the catalog's retail price for one pack, when known.
+	MRP              domain.Money `json:"mrp,omitempty"`
+	SalePriceFromMRP bool         `json:"sale_price_from_mrp,omitempty"`
+	Warnings         []string     `json:"warnings,omitempty"`
+}
+
+func priceResponse(status string, item domain.InventoryItem, check service.PriceCheck) inventoryPriceResponse {
+	return inventoryPriceResponse{
+		Status:           status,
+		UnitCostPrice:    item.CostPrice,
+		UnitSalePrice:    item.SalePrice,
+		PackSize:         item.PackSize,
+		PackSalePrice:    item.PackSalePrice,
+		Version:          item.Version,
+		MRP:              check.MRP,
+		SalePriceFromMRP: check.Suggested,
+		Warnings:         check.Warnings,
 	}
+}
 
-	var brandName, genericName, manufacturer, typeStr string
-	if req.MedicineID != nil && *req.MedicineID != 0 {
-		// Fetch details from medicines table
-		err := h.db.QueryRow("SELECT brand_name, generic_name, manufacturer, type FROM medicines WHERE id = $1", *req.MedicineID).
-			Scan(&brandName, &genericName, &manufacturer, &typeStr)
-		if err != nil {
-			respondError(w, http.StatusBadRequest, "invalid medicine_id")
-			return
-		}
-	} else {
-		// Custom medicine
-		if req.BrandName == "" {
-			respondError(w, http.StatusBadRequest, "brand_name is required for custom medicine")
-			return
+// setETag reports the inventory item's version as a strong entity tag.
+func setETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// ifMatchVersion reads the version an If-Match header expects. A tag this
+// server did not issue can never match.
+func ifMatchVersion(r *http.Request) int64 {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	switch value {
+	case "":
+		return service.NoVersion
+	case "*":
+		return service.AnyVersion
+	}
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		if version, err := strconv.ParseInt(unquoted, 10, 64); err == nil && version > 0 {
+			return version
 		}
-		brandName = req.BrandName
-		genericName = req.GenericName
-		manufacturer = req.Manufacturer
-		typeStr = req.Type
-		req.MedicineID = nil // Ensure it's nil for DB insertion if it was 0
 	}
+	return math.MaxInt64
+}
 
-	unitCost := req.CostPrice / float64(req.Quantity)
-	unitSale := req.SalePrice / float64(req.Quantity)
-	_, err := h.db.Exec(`INSERT INTO inventory (pharmacy_id, medicine_id, brand_name, generic_name, manufacturer, type, quantity, cost_price, sale_price, expiry_date) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
-		pharmacyID, req.MedicineID, brandName, genericName, manufacturer, typeStr, req.Quantity, unitCost, unitSale, nullIfEmpty(req.ExpiryDate))
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to add inventory")
+func (h *Handler) getInventory(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner, service.RoleEmployee, service.RolePharmacist) {
 		return
 	}
-	respondJSON(w, http.StatusCreated, map[string]any{
-		"status":          "inventory added",
-		"unit_cost_price": unitCost,
-		"unit_sale_price": unitSale,
-	})
-}
-
-func (h *Handler) updateInventory(w http.ResponseWriter, r *http.Request) {
-	if !h.requireRole(w, r, "owner", "employee") {
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
 		return
 	}
-	pharmacyID := pharmacyIDFromContext(r)
-	if pharmacyID <= 0 {
-		respondError(w, http.StatusForbidden, "invalid pharmacy context")
+	id, ok := urlID(w, r, "inventory")
+	if !ok {
 		return
 	}
-	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	item, err := h.services.Inventory.Get(r.Context(), pharmacyID, id)
 	if err != nil {
-		respondError(w, http.StatusBadRequest, "invalid inventory id")
+		h.serviceError(w, r, "unable to fetch inventory", err)
 		return
 	}
-	var existingPharmacyID int64
-	if err := h.db.Get(&existingPharmacyID, `SELECT pharmacy_id FROM inventory WHERE id = $1`, id); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			respondError(w, http.StatusNotFound, "inventory not found")
-			return
-		}
-		respondError(w, http.StatusInternalServerError, "unable to load inventory")
+	setETag(w, item.Version)
+	respondJSON(w, http.StatusOK, item)
+}
+
+func (h *Handler) addInventory(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner, service.RoleEmployee, service.RolePharmacist) {
 		return
 	}
-	if existingPharmacyID != pharmacyID {
-		respondError(w, http.StatusForbidden, "inventory does not belong to your pharmacy")
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
 		return
 	}
 	var req inventoryRequest
@@ -607,109 +666,102 @@ func (h *Handler) updateInventory(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if req.Quantity < 0 || req.CostPrice <= 0 || req.SalePrice <= 0 {
-		respondError(w, http.StatusBadRequest, "quantity, cost_price and sale_price are required")
+	item, check, err := h.services.Inventory.Add(r.Context(), pharmacyID, req.input())
+	if err != nil {
+		h.serviceError(w, r, "unable to add inventory", err)
 		return
 	}
-	if req.Quantity == 0 {
-		respondError(w, http.StatusBadRequest, "quantity must be greater than zero")
+	setETag(w, item.Version)
+	respondJSON(w, http.StatusCreated, priceResponse("inventory added", item, check))
+}
+
+func (h *Handler) updateInventory(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner, service.RoleEmployee, service.RolePharmacist) {
 		return
 	}
-	unitCost := req.CostPrice / float64(req.Quantity)
-	unitSale := req.SalePrice / float64(req.Quantity)
-
-	// For update, we might want to update names too if it's a custom medicine, but for now let's keep it simple and just update stock/prices.
-	// If we want to support updating names, we'd need to fetch current state.
-	// Let's assume for now we only update quantity/prices/expiry.
-
-	_, err = h.db.Exec(`UPDATE inventory SET quantity = $1, cost_price = $2, sale_price = $3, expiry_date = $4, updated_at = CURRENT_TIMESTAMP WHERE id = $5`,
-		req.Quantity, unitCost, unitSale, nullIfEmpty(req.ExpiryDate), id)
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to update inventory")
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
 		return
 	}
-	respondJSON(w, http.StatusOK, map[string]any{
-		"status":          "updated",
-		"unit_cost_price": unitCost,
-		"unit_sale_price": unitSale,
-	})
-}
-
-func (h *Handler) updateStock(w http.ResponseWriter, r *http.Request) {
-	if !h.requireRole(w, r, "owner", "employee") {
+	id, ok := urlID(w, r, "inventory")
+	if !ok {
 		return
 	}
-	pharmacyID := pharmacyIDFromContext(r)
-	if pharmacyID <= 0 {
-		respondError(w, http.StatusForbidden, "invalid pharmacy context")
+	var req inventoryRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	item, check, err := h.services.Inventory.Update(r.Context(), pharmacyID, id, ifMatchVersion(r), req.input())
 	if err != nil {
-		respondError(w, http.StatusBadRequest, "invalid inventory id")
+		h.serviceError(w, r, "unable to update inventory", err)
 		return
 	}
-	var existingPharmacyID int64
-	if err := h.db.Get(&existingPharmacyID, `SELECT pharmacy_id FROM inventory WHERE id = $1`, id); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			respondError(w, http.StatusNotFound, "inventory not found")
-			return
-		}
-		respondError(w, http.StatusInternalServerError, "unable to load inventory")
+	setETag(w, item.Version)
+	respondJSON(w, http.StatusOK, priceResponse("updated", item, check))
+}
+
+// stockRequest sets the stock level (quantity, which needs If-Match) or
+// changes it by delta.
+type stockRequest struct {
+	Quantity *int64 `json:"quantity,omitempty"`
+	Delta    *int64 `json:"delta,omitempty"`
+}
+
+type stockResponse struct {
+	Status   string `json:"status"`
+	Quantity int64  `json:"quantity"`
+	Version  int64  `json:"version"`
+}
+
+func (h *Handler) updateStock(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner, service.RoleEmployee, service.RolePharmacist) {
 		return
 	}
-	if existingPharmacyID != pharmacyID {
-		respondError(w, http.StatusForbidden, "inventory does not belong to your pharmacy")
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
 		return
 	}
-	var payload struct {
-		Quantity int64 `json:"quantity"`
+	id, ok := urlID(w, r, "inventory")
+	if !ok {
+		return
 	}
+	var payload stockRequest
 	if err := decodeJSON(r, &payload); err != nil {
 		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if payload.Quantity < 0 {
-		respondError(w, http.StatusBadRequest, "quantity must be positive")
+	var item domain.InventoryItem
+	var err error
+	switch {
+	case payload.Quantity != nil && payload.Delta != nil:
+		respondError(w, http.StatusBadRequest, "send either quantity or delta, not both")
+		return
+	case payload.Delta != nil:
+		item, err = h.services.Inventory.AdjustStock(r.Context(), pharmacyID, id, *payload.Delta, ifMatchVersion(r))
+	case payload.Quantity != nil:
+		item, err = h.services.Inventory.SetStock(r.Context(), pharmacyID, id, *payload.Quantity, ifMatchVersion(r))
+	default:
+		respondError(w, http.StatusBadRequest, "quantity or delta is required")
 		return
 	}
-	_, err = h.db.Exec(`UPDATE inventory SET quantity = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, payload.Quantity, id)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to update stock")
+		h.serviceError(w, r, "unable to update stock", err)
 		return
 	}
-	respondJSON(w, http.StatusOK, map[string]string{"status": "stock updated"})
-}
-
-type expiryAlertResponse struct {
-	InventoryID int64  `db:"id" json:"inventory_id"`
-	BrandName   string `db:"brand_name" json:"brand_name"`
-	Quantity    int64  `db:"quantity" json:"quantity"`
-	ExpiryDate  string `db:"expiry_date" json:"expiry_date"`
+	setETag(w, item.Version)
+	respondJSON(w, http.StatusOK, stockResponse{Status: "stock updated", Quantity: item.Quantity, Version: item.Version})
 }
 
 func (h *Handler) expiryAlerts(w http.ResponseWriter, r *http.Request) {
-	pharmacyID := pharmacyIDFromContext(r)
-	if pharmacyID <= 0 {
-		respondError(w, http.StatusForbidden, "invalid pharmacy context")
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
 		return
 	}
 	days, _ := strconv.Atoi(r.URL.Query().Get("days"))
-	if days <= 0 {
-		days = 30
-	}
-	var items []expiryAlertResponse
-	query := `SELECT i.id, m.brand_name, i.quantity, i.expiry_date 
-              FROM inventory i
-              JOIN medicines m ON m.id = i.medicine_id
-              WHERE i.pharmacy_id = $1 
-              AND i.quantity > 0
-              AND i.expiry_date IS NOT NULL
-              AND i.expiry_date <= (CURRENT_DATE + ($2 * INTERVAL '1 day'))
-              ORDER BY i.expiry_date ASC`
-
-	if err := h.db.Select(&items, query, pharmacyID, days); err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to fetch alerts")
+	items, err := h.services.Inventory.ExpiryAlerts(r.Context(), pharmacyID, days)
+	if err != nil {
+		h.serviceError(w, r, "unable to fetch alerts", err)
 		return
 	}
 	respondJSON(w, http.StatusOK, items)
@@ -721,19 +773,78 @@ type saleItemRequest struct {
 	InventoryID int64  `json:"inventory_id"`
 	MedicineID  *int64 `json:"medicine_id"`
 	Quantity    int64  `json:"quantity"`
+	// PrescriptionItemID picks the item of prescription_id the line fills.
+	PrescriptionItemID int64 `json:"prescription_item_id,omitempty"`
 }
 
 type saleRequest struct {
 	Items           []saleItemRequest `json:"items"`
 	DiscountPercent float64           `json:"discount_percent"`
-	PaidAmount      float64           `json:"paid_amount"`
-	RoundOff        float64           `json:"round_off"`
+	PaidAmount      domain.Money      `json:"paid_amount"`
+	RoundOff        domain.Money      `json:"round_off"`
+	// ClientSaleID is an alternative to the Idempotency-Key header for
+	// clients that generate a UUID per queued sale.
+	ClientSaleID string `json:"client_sale_id,omitempty"`
+	// CreatedAt is when the sale was made on the client, for offline queues.
+	CreatedAt string `json:"created_at,omitempty"`
+	// Offline records oversold lines as conflicts instead of rejecting the
+	// sale, for queued sales whose money was already taken.
+	Offline bool `json:"offline,omitempty"`
+	// InteractionOverride allows selling medicines that interact severely.
+	InteractionOverride *interactionOverrideRequest `json:"interaction_override,omitempty"`
+	// Prescription is required when the sale includes prescription-only or
+	// controlled medicines.
+	Prescription *prescriptionRequest `json:"prescription,omitempty"`
+	// PrescriptionID fills a stored prescription instead of prescription.
+	PrescriptionID int64 `json:"prescription_id,omitempty"`
+}
+
+type prescriptionRequest struct {
+	Prescriber         string `json:"prescriber"`
+	RegistrationNumber string `json:"registration_number"`
+	PatientName        string `json:"patient_name"`
+	PatientAddress     string `json:"patient_address,omitempty"`
+	PrescribedOn       string `json:"prescribed_on"`
+	ImageRef           string `json:"image_ref,omitempty"`
+}
+
+type interactionOverrideRequest struct {
+	Reason string `json:"reason"`
+}
+
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+type saleResponse struct {
+	SaleID         int64        `json:"sale_id"`
+	Total          domain.Money `json:"total"`
+	Discount       domain.Money `json:"discount"`
+	RoundOff       domain.Money `json:"round_off"`
+	NetPayable     domain.Money `json:"net_payable"`
+	PaidAmount     domain.Money `json:"paid_amount"`
+	ChangeReturned domain.Money `json:"change_returned"`
+	DueAmount      domain.Money `json:"due_amount"`
+	// Conflicts lists lines of an offline sale that oversold stock or had
+	// no prescription.
+	Conflicts []domain.SaleConflict `json:"conflicts,omitempty"`
+	// Interactions lists known interactions between the sold medicines.
+	Interactions []domain.InteractionWarning `json:"interactions,omitempty"`
+	// PrescriptionFills lists what the sale dispensed against prescription_id.
+	PrescriptionFills []domain.PrescriptionFill `json:"prescription_fills,omitempty"`
 }
 
 func (h *Handler) createSale(w http.ResponseWriter, r *http.Request) {
-	if !h.requireRole(w, r, "owner", "employee") {
+	if !h.requireRole(w, r, service.RoleOwner, service.RoleEmployee, service.RolePharmacist) {
 		return
 	}
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
+		return
+	}
+	h.inFlightSales.Add(1)
+	defer h.inFlightSales.Add(-1)
 
 	var req saleRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -741,271 +852,211 @@ func (h *Handler) createSale(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(req.Items) == 0 {
-		respondError(w, http.StatusBadRequest, "no items in sale")
-		return
-	}
-
-	pharmacyID := pharmacyIDFromContext(r)
-	userID := r.Context().Value(ctxUserID).(int64)
-	if pharmacyID <= 0 || userID <= 0 {
-		respondError(w, http.StatusForbidden, "invalid context")
-		return
+	key := strings.TrimSpace(r.Header.Get(idempotencyKeyHeader))
+	if req.ClientSaleID != "" {
+		if key != "" && key != req.ClientSaleID {
+			respondError(w, http.StatusBadRequest, "Idempotency-Key and client_sale_id differ")
+			return
+		}
+		key = req.ClientSaleID
+	}
+
+	in := service.NewSale{
+		PharmacyID:      pharmacyID,
+		UserID:          userIDFromContext(r),
+		Role:            roleFromContext(r),
+		APIKeyID:        apiKeyIDFromContext(r),
+		Items:           make([]service.SaleLine, len(req.Items)),
+		DiscountPercent: req.DiscountPercent,
+		PaidAmount:      req.PaidAmount,
+		RoundOff:        req.RoundOff,
+		IdempotencyKey:  key,
+		CreatedAt:       req.CreatedAt,
+		Offline:         req.Offline,
+		PrescriptionID:  req.PrescriptionID,
+	}
+	for i, item := range req.Items {
+		in.Items[i] = service.SaleLine{InventoryID: item.InventoryID, Quantity: item.Quantity, PrescriptionItemID: item.PrescriptionItemID}
+	}
+	if req.InteractionOverride != nil {
+		in.Override = &service.InteractionOverride{Reason: req.InteractionOverride.Reason}
+	}
+	if p := req.Prescription; p != nil {
+		in.Prescription = &service.Prescription{
+			Prescriber:         p.Prescriber,
+			RegistrationNumber: p.RegistrationNumber,
+			PatientName:        p.PatientName,
+			PatientAddress:     p.PatientAddress,
+			PrescribedOn:       p.PrescribedOn,
+			ImageRef:           p.ImageRef,
+		}
 	}
 
-	tx, err := h.db.Beginx()
+	receipt, err := h.services.Sales.Create(r.Context(), in)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "database error")
+		if service.ReasonOf(err) == "insufficient_stock" {
+			h.metrics.insufficientStock.Inc(pharmacyLabel(pharmacyID))
+		}
+		h.serviceError(w, r, "unable to create sale", err)
 		return
 	}
-	defer tx.Rollback()
-
-	var total float64
-	for _, item := range req.Items {
-		var inv domain.InventoryItem
-		err := tx.Get(&inv, "SELECT * FROM inventory WHERE id = $1 AND pharmacy_id = $2", item.InventoryID, pharmacyID)
-		if err != nil {
-			respondError(w, http.StatusBadRequest, fmt.Sprintf("inventory item %d not found", item.InventoryID))
-			return
-		}
-
-		if inv.Quantity < item.Quantity {
-			respondError(w, http.StatusBadRequest, fmt.Sprintf("insufficient stock for item %d", item.InventoryID))
-			return
+	if receipt.Replayed {
+		w.Header().Set(idempotentReplayedHeader, "true")
+	} else {
+		h.metrics.salesCreated.Inc(pharmacyLabel(pharmacyID))
+		h.metrics.saleLineItems.Add(float64(receipt.Lines), pharmacyLabel(pharmacyID))
+		h.metrics.salesRevenue.Add(receipt.NetPayable.Float64(), pharmacyLabel(pharmacyID))
+		for _, conflict := range receipt.Conflicts {
+			if conflict.Kind == domain.ConflictStock {
+				h.metrics.stockConflicts.Inc(pharmacyLabel(pharmacyID))
+			}
 		}
-
-		// Use current price from inventory
-		itemTotal := inv.SalePrice * float64(item.Quantity)
-		total += itemTotal
 	}
 
-	// Calculate amounts with rounding
-	// Enforce integer logic: Round everything to nearest integer
-	totalRounded := math.Round(total)
-	discountAmount := math.Round((totalRounded * req.DiscountPercent) / 100)
-	roundOff := math.Round(req.RoundOff)
-
-	netPayable := totalRounded - discountAmount + roundOff
-	paidAmount := math.Round(req.PaidAmount)
+	respondJSON(w, http.StatusCreated, saleResponse{
+		SaleID:            receipt.SaleID,
+		Total:             receipt.Total,
+		Discount:          receipt.Discount,
+		RoundOff:          receipt.RoundOff,
+		NetPayable:        receipt.NetPayable,
+		PaidAmount:        receipt.Paid,
+		ChangeReturned:    receipt.ChangeReturned,
+		DueAmount:         receipt.Due,
+		Conflicts:         receipt.Conflicts,
+		Interactions:      receipt.Interactions,
+		PrescriptionFills: receipt.Fills,
+	})
+}
 
-	var changeReturned float64
-	var dueAmount float64
+type interactionCheckRequest struct {
+	GenericNames []string `json:"generic_names"`
+}
 
-	if paidAmount >= netPayable {
-		changeReturned = paidAmount - netPayable
-		dueAmount = 0
-	} else {
-		changeReturned = 0
-		dueAmount = netPayable - paidAmount
-	}
+type interactionCheckResponse struct {
+	Interactions []domain.InteractionWarning `json:"interactions"`
+	// OverrideRequired is set when a sale of the cart needs an
+	// interaction_override.
+	OverrideRequired bool `json:"override_required"`
+}
 
-	// Insert Sale
-	var saleID int64
-	err = tx.QueryRow(`
-		INSERT INTO sales (pharmacy_id, user_id, total_amount, discount, paid_amount, due_amount, round_off, change_returned)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
-		pharmacyID, userID, totalRounded, discountAmount, paidAmount, dueAmount, roundOff, changeReturned).Scan(&saleID)
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to create sale record")
+func (h *Handler) checkSaleInteractions(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner, service.RoleEmployee, service.RolePharmacist) {
 		return
 	}
-
-	// Process items and update inventory
-	for _, item := range req.Items {
-		var inv domain.InventoryItem
-		// Re-fetch to be safe within tx, though we fetched above.
-		// Optimization: could cache above, but this is safer for consistency.
-		err := tx.Get(&inv, "SELECT * FROM inventory WHERE id = $1", item.InventoryID)
-		if err != nil {
-			continue
-		}
-
-		subtotal := inv.SalePrice * float64(item.Quantity)
-		_, err = tx.Exec(`
-			INSERT INTO sale_items (sale_id, medicine_id, inventory_id, quantity, unit_price, subtotal)
-			VALUES ($1, $2, $3, $4, $5, $6)`,
-			saleID, inv.MedicineID, inv.ID, item.Quantity, inv.SalePrice, subtotal)
-		if err != nil {
-			respondError(w, http.StatusInternalServerError, "unable to add sale items")
-			return
-		}
-
-		_, err = tx.Exec(`UPDATE inventory SET quantity = quantity - $1 WHERE id = $2`, item.Quantity, inv.ID)
-		if err != nil {
-			respondError(w, http.StatusInternalServerError, "unable to update inventory")
-			return
-		}
+	var req interactionCheckRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
 	}
-
-	if err := tx.Commit(); err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to finalize sale")
+	warnings, err := h.services.Sales.CheckInteractions(r.Context(), req.GenericNames)
+	if err != nil {
+		h.serviceError(w, r, "unable to check interactions", err)
 		return
 	}
+	resp := interactionCheckResponse{Interactions: warnings}
+	for _, warning := range warnings {
+		resp.OverrideRequired = resp.OverrideRequired || warning.Severity == domain.SeveritySevere
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
 
-	respondJSON(w, http.StatusCreated, map[string]any{
-		"sale_id":         saleID,
-		"total":           totalRounded,
-		"discount":        discountAmount,
-		"round_off":       roundOff,
-		"net_payable":     netPayable,
-		"paid_amount":     paidAmount,
-		"change_returned": changeReturned,
-		"due_amount":      dueAmount,
-	})
+type resolveConflictRequest struct {
+	Resolution string `json:"resolution"`
 }
 
-// Reports
-func (h *Handler) dailySales(w http.ResponseWriter, r *http.Request) {
-	pharmacyID := pharmacyIDFromContext(r)
-	if pharmacyID <= 0 {
-		respondError(w, http.StatusForbidden, "invalid pharmacy context")
+func (h *Handler) listSaleConflicts(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner) {
 		return
 	}
-	query := `SELECT COALESCE(SUM(total_amount - discount),0) AS revenue, COUNT(*) AS count FROM sales WHERE DATE(created_at) = CURRENT_DATE AND pharmacy_id = $1`
-	args := []interface{}{pharmacyID}
-	var revenue float64
-	var count int64
-	err := h.db.QueryRow(query, args...).Scan(&revenue, &count)
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
+		return
+	}
+	conflicts, err := h.services.Sales.Conflicts(r.Context(), pharmacyID, r.URL.Query().Get("status"))
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to fetch daily sales")
+		h.serviceError(w, r, "unable to list conflicts", err)
 		return
 	}
-	respondJSON(w, http.StatusOK, map[string]any{"revenue": revenue, "sales_count": count})
+	respondJSON(w, http.StatusOK, conflicts)
 }
 
-func (h *Handler) monthlySales(w http.ResponseWriter, r *http.Request) {
-	pharmacyID := pharmacyIDFromContext(r)
-	if pharmacyID <= 0 {
-		respondError(w, http.StatusForbidden, "invalid pharmacy context")
+func (h *Handler) resolveSaleConflict(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner) {
 		return
 	}
-	query := `SELECT COALESCE(SUM(total_amount - discount),0) AS revenue, COUNT(*) AS count FROM sales WHERE DATE(created_at) >= date_trunc('month', CURRENT_DATE) AND pharmacy_id = $1`
-	args := []interface{}{pharmacyID}
-	var revenue float64
-	var count int64
-	err := h.db.QueryRow(query, args...).Scan(&revenue, &count)
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to fetch monthly sales")
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
 		return
 	}
-	respondJSON(w, http.StatusOK, map[string]any{"revenue": revenue, "sales_count": count})
-}
-
-type saleItemDetail struct {
-	SaleID      int64   `db:"sale_id" json:"sale_id"`
-	MedicineID  *int64  `db:"medicine_id" json:"medicine_id"`
-	InventoryID *int64  `db:"inventory_id" json:"inventory_id"`
-	BrandName   string  `db:"brand_name" json:"brand_name"`
-	Quantity    int64   `db:"quantity" json:"quantity"`
-	UnitPrice   float64 `db:"unit_price" json:"unit_price"`
-	Subtotal    float64 `db:"subtotal" json:"subtotal"`
-}
-
-type saleReportEntry struct {
-	domain.Sale
-	Items []saleItemDetail `json:"items"`
-}
-
-func (h *Handler) salesReport(w http.ResponseWriter, r *http.Request) {
-	if !h.requireRole(w, r, "owner") {
+	id, ok := urlID(w, r, "conflict")
+	if !ok {
 		return
 	}
-
-	var (
-		args    []any
-		clauses []string
-	)
-
-	pharmacyID := pharmacyIDFromContext(r)
-	if pharmacyID <= 0 {
-		respondError(w, http.StatusForbidden, "invalid pharmacy context")
+	var req resolveConflictRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
-	args = append(args, pharmacyID)
-	clauses = append(clauses, fmt.Sprintf("pharmacy_id = $%d", len(args)))
-
-	startDate := strings.TrimSpace(r.URL.Query().Get("start_date"))
-	if startDate != "" {
-		if _, err := time.Parse("2006-01-02", startDate); err != nil {
-			respondError(w, http.StatusBadRequest, "start_date must be in YYYY-MM-DD format")
-			return
-		}
-		args = append(args, startDate)
-		clauses = append(clauses, fmt.Sprintf("DATE(created_at) >= $%d", len(args)))
-	}
-
-	endDate := strings.TrimSpace(r.URL.Query().Get("end_date"))
-	if endDate != "" {
-		if _, err := time.Parse("2006-01-02", endDate); err != nil {
-			respondError(w, http.StatusBadRequest, "end_date must be in YYYY-MM-DD format")
-			return
-		}
-		args = append(args, endDate)
-		clauses = append(clauses, fmt.Sprintf("DATE(created_at) <= $%d", len(args)))
+	conflict, err := h.services.Sales.ResolveConflict(r.Context(), pharmacyID, id, userIDFromContext(r), req.Resolution)
+	if err != nil {
+		h.serviceError(w, r, "unable to resolve conflict", err)
+		return
 	}
+	respondJSON(w, http.StatusOK, conflict)
+}
 
-	query := `SELECT id, pharmacy_id, user_id, total_amount, discount, paid_amount, due_amount, created_at FROM sales`
-	if len(clauses) > 0 {
-		query += " WHERE " + strings.Join(clauses, " AND ")
-	}
-	query += " ORDER BY created_at DESC"
+// Reports
 
-	var sales []domain.Sale
-	if err := h.db.Select(&sales, query, args...); err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to fetch sales report")
+func (h *Handler) dailySales(w http.ResponseWriter, r *http.Request) {
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
 		return
 	}
-	if len(sales) == 0 {
-		respondJSON(w, http.StatusOK, []saleReportEntry{})
+	summary, err := h.services.Reports.Daily(r.Context(), pharmacyID)
+	if err != nil {
+		h.serviceError(w, r, "unable to fetch daily sales", err)
 		return
 	}
+	respondJSON(w, http.StatusOK, summary)
+}
 
-	ids := make([]int64, len(sales))
-	for i, sale := range sales {
-		ids[i] = sale.ID
+func (h *Handler) monthlySales(w http.ResponseWriter, r *http.Request) {
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
+		return
 	}
-
-	itemsQuery, itemsArgs, err := sqlx.In(`SELECT si.sale_id, si.medicine_id, si.inventory_id, si.quantity, si.unit_price, si.subtotal, 
-	            COALESCE(m.brand_name, i.brand_name, 'Custom Medicine') as brand_name
-                FROM sale_items si
-                LEFT JOIN medicines m ON m.id = si.medicine_id
-                LEFT JOIN inventory i ON i.id = si.inventory_id
-                WHERE si.sale_id IN (?)`, ids)
+	summary, err := h.services.Reports.Monthly(r.Context(), pharmacyID)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to prepare sale items query")
+		h.serviceError(w, r, "unable to fetch monthly sales", err)
 		return
 	}
-	itemsQuery = h.db.Rebind(itemsQuery)
+	respondJSON(w, http.StatusOK, summary)
+}
 
-	var rows []saleItemDetail
-	if err := h.db.Select(&rows, itemsQuery, itemsArgs...); err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to load sale items")
+func (h *Handler) salesReport(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner) {
 		return
 	}
-	itemsBySale := make(map[int64][]saleItemDetail)
-	for _, row := range rows {
-		itemsBySale[row.SaleID] = append(itemsBySale[row.SaleID], row)
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
+		return
 	}
-
-	report := make([]saleReportEntry, len(sales))
-	for i, sale := range sales {
-		items := itemsBySale[sale.ID]
-		if items == nil {
-			items = []saleItemDetail{}
-		}
-		report[i] = saleReportEntry{Sale: sale, Items: items}
+	report, err := h.services.Reports.Sales(r.Context(), pharmacyID, r.URL.Query().Get("start_date"), r.URL.Query().Get("end_date"))
+	if err != nil {
+		h.serviceError(w, r, "unable to fetch sales report", err)
+		return
 	}
-
 	respondJSON(w, http.StatusOK, report)
 }
 
 // Helpers
-func nullIfEmpty(val string) *string {
-	trimmed := strings.TrimSpace(val)
-	if trimmed == "" {
-		return nil
-	}
-	return &trimmed
+
+type statusResponse struct {
+	Status string `json:"status"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
 }
 
 func decodeJSON(r *http.Request, dest interface{}) error {
@@ -1023,5 +1074,5 @@ func respondJSON(w http.ResponseWriter, status int, payload interface{}) {
 }
 
 func respondError(w http.ResponseWriter, status int, message string) {
-	respondJSON(w, status, map[string]string{"error": message})
+	respondJSON(w, status, errorResponse{Error: message})
 }
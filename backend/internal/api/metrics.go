@@ -0,0 +1,112 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jmoiron/sqlx"
+
+	"medeasy/m/internal/metrics"
+)
+
+// apiMetrics holds the series exported at /metrics.
+type apiMetrics struct {
+	registry          *metrics.Registry
+	requestDuration   *metrics.HistogramVec
+	salesCreated      *metrics.CounterVec
+	saleLineItems     *metrics.CounterVec
+	salesRevenue      *metrics.CounterVec
+	loginFailures     *metrics.CounterVec
+	insufficientStock *metrics.CounterVec
+	stockConflicts    *metrics.CounterVec
+}
+
+func newAPIMetrics(db *sqlx.DB) *apiMetrics {
+	reg := metrics.NewRegistry()
+	m := &apiMetrics{
+		registry: reg,
+		requestDuration: reg.NewHistogramVec("medeasy_http_request_duration_seconds",
+			"HTTP request latency by route pattern and status.", metrics.DefaultBuckets, "method", "route", "status"),
+		salesCreated: reg.NewCounterVec("medeasy_sales_created_total",
+			"Sales recorded.", "pharmacy_id"),
+		saleLineItems: reg.NewCounterVec("medeasy_sale_line_items_total",
+			"Line items across recorded sales.", "pharmacy_id"),
+		salesRevenue: reg.NewCounterVec("medeasy_sales_revenue_taka_total",
+			"Net payable amount of recorded sales in taka.", "pharmacy_id"),
+		loginFailures: reg.NewCounterVec("medeasy_login_failures_total",
+			"Rejected login attempts.", "reason"),
+		insufficientStock: reg.NewCounterVec("medeasy_sale_insufficient_stock_total",
+			"Sales rejected because an item did not have enough stock.", "pharmacy_id"),
+		stockConflicts: reg.NewCounterVec("medeasy_sale_stock_conflicts_total",
+			"Offline sale lines accepted although they sold more than was in stock.", "pharmacy_id"),
+	}
+
+	reg.RegisterRuntime()
+	if db != nil {
+		reg.NewGaugeFunc("medeasy_db_max_open_connections", "Maximum number of open database connections.",
+			func() float64 { return float64(db.Stats().MaxOpenConnections) })
+		reg.NewGaugeFunc("medeasy_db_open_connections", "Established database connections, in use and idle.",
+			func() float64 { return float64(db.Stats().OpenConnections) })
+		reg.NewGaugeFunc("medeasy_db_in_use_connections", "Database connections currently in use.",
+			func() float64 { return float64(db.Stats().InUse) })
+		reg.NewGaugeFunc("medeasy_db_idle_connections", "Idle database connections.",
+			func() float64 { return float64(db.Stats().Idle) })
+		reg.NewCounterFunc("medeasy_db_wait_count_total", "Connections waited for because the pool was exhausted.",
+			func() float64 { return float64(db.Stats().WaitCount) })
+		reg.NewCounterFunc("medeasy_db_wait_duration_seconds_total", "Time spent waiting for a database connection.",
+			func() float64 { return db.Stats().WaitDuration.Seconds() })
+		reg.NewCounterFunc("medeasy_db_max_idle_closed_total", "Connections closed due to the idle limit.",
+			func() float64 { return float64(db.Stats().MaxIdleClosed) })
+		reg.NewCounterFunc("medeasy_db_max_lifetime_closed_total", "Connections closed due to the lifetime limit.",
+			func() float64 { return float64(db.Stats().MaxLifetimeClosed) })
+	}
+	return m
+}
+
+// instrument records request latency labelled by the matched chi route pattern.
+func (h *Handler) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := routePattern(r)
+		if route == "" {
+			// Unmatched paths would otherwise create a series per URL.
+			route = "unmatched"
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		h.metrics.requestDuration.Observe(time.Since(start).Seconds(), r.Method, route, strconv.Itoa(status))
+	})
+}
+
+// MetricsHandler serves the metrics without authentication, for use on a
+// separate, non-public listener.
+func (h *Handler) MetricsHandler() http.Handler {
+	return h.metrics.registry.Handler()
+}
+
+// metricsEndpoint serves /metrics on the public router behind a bearer token.
+func (h *Handler) metricsEndpoint(w http.ResponseWriter, r *http.Request) {
+	header := r.Header.Get("Authorization")
+	token := ""
+	if strings.HasPrefix(strings.ToLower(header), "bearer ") {
+		token = strings.TrimSpace(header[len("Bearer "):])
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.metricsToken)) != 1 {
+		respondError(w, http.StatusUnauthorized, "invalid metrics token")
+		return
+	}
+	h.metrics.registry.Handler().ServeHTTP(w, r)
+}
+
+func pharmacyLabel(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
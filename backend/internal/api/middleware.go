@@ -0,0 +1,139 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"medeasy/m/internal/logging"
+	"medeasy/m/internal/service"
+)
+
+// requestIDHeader carries the request ID in both directions.
+const requestIDHeader = "X-Request-ID"
+
+// requestLogger assigns a request ID, echoes it in the response and writes one
+// structured access log line per request once the handler has finished.
+func (h *Handler) requestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		info := &logging.RequestInfo{RequestID: requestID(r)}
+		w.Header().Set(requestIDHeader, info.RequestID)
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(logging.WithRequestInfo(r.Context(), info)))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		attrs := append(info.Attrs(),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("route", routePattern(r)),
+			slog.Int("status", status),
+			slog.Int("bytes", ww.BytesWritten()),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+		)
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+			if info.Err != nil {
+				attrs = append(attrs, slog.String("error", info.Err.Error()))
+			}
+		}
+		slog.Log(r.Context(), level, "request", attrs...)
+	})
+}
+
+// recoverer turns a panic into a logged 500 instead of a dropped connection.
+func (h *Handler) recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			logging.FromContext(r.Context()).Error("panic", slog.Any("panic", rec), slog.String("stack", string(debug.Stack())))
+			h.serverError(w, r, "internal server error", fmt.Errorf("panic: %v", rec))
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serverError responds with a generic 5xx message and records the underlying
+// error so it is logged with the request.
+func (h *Handler) serverError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	recordError(r, err)
+	respondError(w, http.StatusInternalServerError, message)
+}
+
+// serviceError maps a service.Error to its HTTP status and message; any other
+// error is treated as a server error described by message.
+func (h *Handler) serviceError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	var svcErr *service.Error
+	if !errors.As(err, &svcErr) {
+		h.serverError(w, r, message, err)
+		return
+	}
+	status := http.StatusBadRequest
+	switch svcErr.Kind {
+	case service.KindUnauthorized:
+		status = http.StatusUnauthorized
+	case service.KindForbidden:
+		status = http.StatusForbidden
+	case service.KindNotFound:
+		status = http.StatusNotFound
+	case service.KindConflict:
+		status = http.StatusConflict
+	case service.KindPreconditionRequired:
+		status = http.StatusPreconditionRequired
+	case service.KindPreconditionFailed:
+		status = http.StatusPreconditionFailed
+	}
+	respondError(w, status, svcErr.Message)
+}
+
+// recordError attaches err to the request's access log line.
+func recordError(r *http.Request, err error) {
+	if info := logging.RequestInfoFrom(r.Context()); info != nil && err != nil {
+		info.Err = err
+	}
+}
+
+// requestID reuses a well-formed inbound X-Request-ID or generates a new one.
+func requestID(r *http.Request) string {
+	if id := r.Header.Get(requestIDHeader); id != "" && len(id) <= 64 && printableASCII(id) {
+		return id
+	}
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func printableASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x21 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return rctx.RoutePattern()
+	}
+	return ""
+}
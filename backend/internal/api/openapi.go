@@ -0,0 +1,419 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-chi/chi/v5"
+
+	"medeasy/m/domain"
+	"medeasy/m/internal/service"
+)
+
+// apiVersion is reported in the OpenAPI document.
+const apiVersion = "1.0.0"
+
+// access describes how an operation authenticates.
+type access int
+
+const (
+	accessPublic access = iota
+	// accessUser requires a user JWT.
+	accessUser
+	// accessScoped accepts a user JWT or an API key holding scope.
+	accessScoped
+	// accessMetrics requires the metrics token.
+	accessMetrics
+)
+
+type param struct {
+	name        string
+	in          string
+	description string
+	schema      map[string]any
+	required    bool
+}
+
+// operation documents one route. request and response are zero values of the
+// types the handler decodes and encodes; their schemas are derived by reflection.
+type operation struct {
+	method      string
+	path        string
+	tag         string
+	summary     string
+	description string
+	access      access
+	scope       string
+	params      []param
+	request     any
+	status      int
+	response    any
+	contentType string
+	// upload lists the accepted types of a raw file request body.
+	upload []string
+}
+
+var (
+	idParam        = param{name: "id", in: "path", required: true, schema: map[string]any{"type": "integer", "format": "int64"}}
+	queryParam     = param{name: "query", in: "query", description: "Case-insensitive match on brand or generic name.", schema: map[string]any{"type": "string"}}
+	startDateParam = param{name: "start_date", in: "query", description: "Inclusive start date (YYYY-MM-DD).", schema: map[string]any{"type": "string", "format": "date"}}
+	endDateParam   = param{name: "end_date", in: "query", description: "Inclusive end date (YYYY-MM-DD).", schema: map[string]any{"type": "string", "format": "date"}}
+	ifMatchParam   = param{name: "If-Match", in: "header", description: "ETag of the item as last read, or * to overwrite regardless.", schema: map[string]any{"type": "string"}}
+	sinceParam     = param{name: "since", in: "query", description: "Cursor returned by the previous page; omit to start from the beginning.", schema: map[string]any{"type": "string"}}
+	limitParam     = param{name: "limit", in: "query", description: "Changes per page (default 500, at most 1000).", schema: map[string]any{"type": "integer"}}
+)
+
+// operations lists every route served by Handler.Router.
+func operations() []operation {
+	return []operation{
+		{method: http.MethodGet, path: "/health", tag: "Health", summary: "Liveness probe (alias of /livez)", status: http.StatusOK, response: healthResponse{}},
+		{method: http.MethodGet, path: "/livez", tag: "Health", summary: "Liveness probe", status: http.StatusOK, response: healthResponse{}},
+		{method: http.MethodGet, path: "/readyz", tag: "Health", summary: "Readiness probe", description: "Fails with 503 when the database is unreachable, the schema is not at the latest migration, or the server is draining.", status: http.StatusOK, response: healthResponse{}},
+		{method: http.MethodGet, path: "/metrics", tag: "Health", summary: "Prometheus metrics", description: "Only served here when METRICS_TOKEN is set and METRICS_ADDR is not.", access: accessMetrics, status: http.StatusOK, contentType: "text/plain"},
+		{method: http.MethodGet, path: "/openapi.json", tag: "Docs", summary: "This OpenAPI document", status: http.StatusOK, contentType: "application/json"},
+		{method: http.MethodGet, path: "/docs", tag: "Docs", summary: "Interactive API browser", status: http.StatusOK, contentType: "text/html"},
+
+		{method: http.MethodPost, path: "/auth/register", tag: "Auth", summary: "Register an owner (with a new pharmacy) or an employee", request: registerRequest{}, status: http.StatusCreated, response: authResponse{}},
+		{method: http.MethodPost, path: "/auth/login", tag: "Auth", summary: "Log in and receive a JWT", request: loginRequest{}, status: http.StatusOK, response: authResponse{}},
+		{method: http.MethodPost, path: "/auth/reset-password", tag: "Auth", summary: "Change the current user's password", access: accessUser, request: resetPasswordRequest{}, status: http.StatusOK, response: statusResponse{}},
+
+		{method: http.MethodPost, path: "/pharmacies", tag: "Pharmacies", summary: "Create a pharmacy (owner)", access: accessUser, request: pharmacyRequest{}, status: http.StatusCreated, response: pharmacyCreatedResponse{}},
+		{method: http.MethodGet, path: "/pharmacies", tag: "Pharmacies", summary: "List pharmacies", access: accessUser, status: http.StatusOK, response: []domain.Pharmacy{}},
+		{method: http.MethodPut, path: "/pharmacies/{id}", tag: "Pharmacies", summary: "Update a pharmacy (owner)", access: accessUser, params: []param{idParam}, request: pharmacyRequest{}, status: http.StatusOK, response: statusResponse{}},
+
+		{method: http.MethodGet, path: "/medicines", tag: "Medicines", summary: "Search the medicine catalog", description: "Matches brand, generic name, manufacturer and strength, tolerating typos. Brands and generics starting with the query come first, then the closest matches. When more results follow, the Next-Cursor response header holds the cursor for the next page.", access: accessScoped, scope: domain.ScopeCatalogRead, params: []param{queryParam, {name: "cursor", in: "query", description: "Next-Cursor header of the previous page.", schema: map[string]any{"type": "string"}}, {name: "limit", in: "query", description: "Page size (default 25, at most 100).", schema: map[string]any{"type": "integer"}}}, status: http.StatusOK, response: []domain.Medicine{}},
+		{method: http.MethodGet, path: "/medicines/{id}/packs", tag: "Medicines", summary: "Priced packs of a catalog medicine", description: "mrp is the regulated retail price of the whole pack; units is how many tablets or capsules it holds, or 1 for single containers.", access: accessScoped, scope: domain.ScopeCatalogRead, params: []param{idParam}, status: http.StatusOK, response: []domain.MedicinePack{}},
+		{method: http.MethodGet, path: "/medicines/{id}/substitutes", tag: "Medicines", summary: "Other brands of the same generic, strength and dosage form", description: "Each brand carries the pharmacy's stock and lowest unit price, its unit MRP, and price_difference against the requested medicine (negative is cheaper). Brands in stock come first, then the cheapest.", access: accessScoped, scope: domain.ScopeCatalogRead, params: []param{idParam}, status: http.StatusOK, response: []domain.Substitute{}},
+
+		{method: http.MethodGet, path: "/inventory/search", tag: "Inventory", summary: "Search in-stock inventory", access: accessScoped, scope: domain.ScopeCatalogRead, params: []param{queryParam}, status: http.StatusOK, response: []domain.InventorySearchResult{}},
+		{method: http.MethodPost, path: "/inventory/scan", tag: "Inventory", summary: "Resolve a scanned barcode", description: "Parses EAN/UPC codes and GS1 DataMatrix or GS1-128 element strings (GTIN, batch, expiry, serial). medicine is null when the GTIN is not registered. lot is the in-stock lot with the scanned batch, or the one expiring first when the code has no batch.", access: accessScoped, scope: domain.ScopeCatalogRead, request: scanRequest{}, status: http.StatusOK, response: scanResponse{}},
+		{method: http.MethodPost, path: "/inventory", tag: "Inventory", summary: "Receive stock", description: "cost_price and sale_price are totals for the whole quantity, or the price of one pack when pack_size is set. For catalog medicines a zero sale_price is filled in from the MRP, and a sale_price above the MRP is accepted with a warning. A registered barcode identifies the medicine and fills in pack_size, batch_number and expiry_date when they are empty.", access: accessUser, request: inventoryRequest{}, status: http.StatusCreated, response: inventoryPriceResponse{}},
+		{method: http.MethodGet, path: "/inventory/{id}", tag: "Inventory", summary: "Get an inventory item", description: "The ETag header carries the item's version for If-Match.", access: accessUser, params: []param{idParam}, status: http.StatusOK, response: domain.InventoryItem{}},
+		{method: http.MethodPut, path: "/inventory/{id}", tag: "Inventory", summary: "Update an inventory item", description: "Requires If-Match: 428 without it, 412 when the item changed since it was read.", access: accessUser, params: []param{idParam, ifMatchParam}, request: inventoryRequest{}, status: http.StatusOK, response: inventoryPriceResponse{}},
+		{method: http.MethodPost, path: "/inventory/{id}/stock", tag: "Inventory", summary: "Set or adjust the stock quantity", description: "Send quantity to set the stock level, which requires If-Match (428 without it, 412 when stale), or delta to add or remove units, which fails with 400 rather than going below zero.", access: accessUser, params: []param{idParam, ifMatchParam}, request: stockRequest{}, status: http.StatusOK, response: stockResponse{}},
+		{method: http.MethodGet, path: "/inventory/expiry-alert", tag: "Inventory", summary: "Items expiring soon", access: accessUser, params: []param{{name: "days", in: "query", description: "Look-ahead window in days (default 30).", schema: map[string]any{"type": "integer"}}}, status: http.StatusOK, response: []domain.ExpiryAlert{}},
+		{method: http.MethodPost, path: "/inventory/{id}/submit", tag: "Catalog Submissions", summary: "Submit a custom medicine to the catalog (owner)", description: "Proposes an inventory item without a catalog medicine for the shared catalog. The response lists candidates, catalog medicines with a similar name that it may duplicate. An item can have one pending submission (409).", access: accessUser, params: []param{idParam}, request: submitRequest{}, status: http.StatusCreated, response: domain.CatalogSubmission{}},
+		{method: http.MethodGet, path: "/catalog/submissions", tag: "Catalog Submissions", summary: "The pharmacy's catalog submissions (owner)", description: "Pending submissions carry their candidates.", access: accessUser, params: []param{{name: "status", in: "query", description: "pending, merged, added or rejected; all when omitted.", schema: map[string]any{"type": "string", "enum": []string{domain.SubmissionPending, domain.SubmissionMerged, domain.SubmissionAdded, domain.SubmissionRejected}}}}, status: http.StatusOK, response: []domain.CatalogSubmission{}},
+		{method: http.MethodPost, path: "/catalog/submissions/{id}/merge", tag: "Catalog Submissions", summary: "Merge a submission into a catalog medicine (owner)", description: "Links the submitted item, the pharmacy's other custom items with the same brand and generic name, and their past sale lines to the catalog medicine. The items take the medicine's names. 409 when the submission was already reviewed.", access: accessUser, params: []param{idParam}, request: mergeRequest{}, status: http.StatusOK, response: mergeResponse{}},
+
+		{method: http.MethodPost, path: "/sales", tag: "Sales", summary: "Record a sale", description: "With offline set, lines that sell more than is in stock are accepted, stock stops at zero and each shortfall is returned in conflicts and queued for the owner; offline sales require an idempotency key. With an Idempotency-Key header or client_sale_id, retries return the original sale with the Idempotent-Replayed header set; reusing a key for a different sale is a 409. created_at records when an offline sale was made (at most 30 days ago). Known interactions between the sold generics are returned in interactions; a severe one fails the sale with 400, offline or not, unless interaction_override gives the reason; only owners and pharmacists may send one (403 otherwise, and for API keys), and the signed-in user is recorded with it. Selling a prescription-only or controlled medicine, custom ones classified by generic name, requires prescription (prescriber, registration_number, patient_name, prescribed_on) and fails with 400 without it; offline sales are accepted, and each uncovered line is returned in conflicts with kind prescription and queued for the owner. prescription_id dispenses against a stored prescription instead: each line fills the item named by its prescription_item_id, or else the first item prescribing its medicine with units left, and the units filled are returned in prescription_fills. A line taking more than its item has left fails with 400, as does a prescription-only or controlled medicine the prescription does not cover; offline lines fill what is left and queue the rest as prescription conflicts.", access: accessScoped, scope: domain.ScopeSalesCreate, params: []param{{name: idempotencyKeyHeader, in: "header", description: "Client-chosen key, unique per pharmacy, that makes retries safe.", schema: map[string]any{"type": "string", "maxLength": 255}}}, request: saleRequest{}, status: http.StatusCreated, response: saleResponse{}},
+		{method: http.MethodPost, path: "/sales/check", tag: "Sales", summary: "Check a cart for drug interactions", description: "Splits combination generics into ingredients and returns the known interactions between different generics, severe first. override_required is set when selling the cart needs an interaction_override.", access: accessScoped, scope: domain.ScopeSalesCreate, request: interactionCheckRequest{}, status: http.StatusOK, response: interactionCheckResponse{}},
+		{method: http.MethodGet, path: "/sales/conflicts", tag: "Sales", summary: "Offline sales that oversold stock or had no prescription (owner)", description: "kind is stock for lines that sold more than was in stock, or prescription for prescription-only and controlled medicines sold without a prescription covering them; for these available is the units a prescription covered.", access: accessUser, params: []param{{name: "status", in: "query", description: "open (default), resolved or all.", schema: map[string]any{"type": "string", "enum": []string{service.ConflictsOpen, service.ConflictsResolved, service.ConflictsAll}}}}, status: http.StatusOK, response: []domain.SaleConflict{}},
+		{method: http.MethodPost, path: "/sales/conflicts/{id}/resolve", tag: "Sales", summary: "Close a conflict (owner)", description: "Records how the owner reconciled the stock or reviewed the sale. Adjust the stock itself through the inventory endpoints.", access: accessUser, params: []param{idParam}, request: resolveConflictRequest{}, status: http.StatusOK, response: domain.SaleConflict{}},
+
+		{method: http.MethodPost, path: "/prescriptions", tag: "Prescriptions", summary: "Store a prescription", description: "Each item gives the medicine_id of a catalog medicine or a description as written, dosage instructions, the quantity dispensed per fill and the refills allowed after the first fill. patient_phone is stored as digits only.", access: accessUser, request: newPrescriptionRequest{}, status: http.StatusCreated, response: domain.Prescription{}},
+		{method: http.MethodGet, path: "/prescriptions", tag: "Prescriptions", summary: "Find a patient's prescriptions", description: "A patient query without letters matches the phone number exactly; otherwise it matches the name, tolerating misspellings. Newest first, at most 50. Each item carries the units remaining and refills_remaining, the fills left after the current one.", access: accessUser, params: []param{{name: "patient", in: "query", required: true, description: "Phone number or name.", schema: map[string]any{"type": "string"}}}, status: http.StatusOK, response: []domain.Prescription{}},
+		{method: http.MethodGet, path: "/prescriptions/{id}", tag: "Prescriptions", summary: "Get a prescription", access: accessUser, params: []param{idParam}, status: http.StatusOK, response: domain.Prescription{}},
+		{method: http.MethodPut, path: "/prescriptions/{id}/scan", tag: "Prescriptions", summary: "Upload a scan of a prescription", description: "The body is the file itself, a JPEG, PNG or PDF of at most 10 MB, detected from its content. Replaces the current scan; sales already dispensed keep a reference to the scan they were made against.", access: accessUser, params: []param{idParam}, upload: []string{"image/jpeg", "image/png", "application/pdf"}, status: http.StatusOK, response: domain.Prescription{}},
+		{method: http.MethodGet, path: "/prescriptions/{id}/scan", tag: "Prescriptions", summary: "Download the scan of a prescription", description: "404 when no scan was uploaded.", access: accessUser, params: []param{idParam}, status: http.StatusOK, contentType: "application/octet-stream"},
+
+		{method: http.MethodGet, path: "/reports/sales/daily", tag: "Reports", summary: "Today's revenue", access: accessScoped, scope: domain.ScopeReportsRead, status: http.StatusOK, response: domain.SalesSummary{}},
+		{method: http.MethodGet, path: "/reports/sales/monthly", tag: "Reports", summary: "This month's revenue", access: accessScoped, scope: domain.ScopeReportsRead, status: http.StatusOK, response: domain.SalesSummary{}},
+		{method: http.MethodGet, path: "/reports/sales", tag: "Reports", summary: "Sales with line items (owner)", access: accessScoped, scope: domain.ScopeReportsRead, params: []param{startDateParam, endDateParam}, status: http.StatusOK, response: []domain.SaleReport{}},
+		{method: http.MethodGet, path: "/reports/controlled-register", tag: "Reports", summary: "Controlled drug dispensing register (owner)", description: "Every sold line of a controlled medicine, oldest first, with the prescription it was dispensed against. unverified marks offline sales no prescription covered. format=csv returns the register as a CSV attachment with a serial number per entry.", access: accessScoped, scope: domain.ScopeReportsRead, params: []param{startDateParam, endDateParam, {name: "format", in: "query", description: "json (default) or csv.", schema: map[string]any{"type": "string", "enum": []string{"json", "csv"}}}}, status: http.StatusOK, response: []domain.ControlledDispense{}},
+
+		{method: http.MethodGet, path: "/sync/catalog", tag: "Sync", summary: "Catalog changes since a cursor", access: accessScoped, scope: domain.ScopeCatalogRead, params: []param{sinceParam, limitParam}, status: http.StatusOK, response: domain.CatalogChanges{}},
+		{method: http.MethodGet, path: "/sync/inventory", tag: "Sync", summary: "Inventory changes since a cursor", description: "Includes items that are out of stock.", access: accessScoped, scope: domain.ScopeCatalogRead, params: []param{sinceParam, limitParam}, status: http.StatusOK, response: domain.InventoryChanges{}},
+		{method: http.MethodGet, path: "/sync/sales", tag: "Sync", summary: "Sales changes since a cursor (owner)", access: accessScoped, scope: domain.ScopeReportsRead, params: []param{sinceParam, limitParam}, status: http.StatusOK, response: domain.SaleChanges{}},
+
+		{method: http.MethodPost, path: "/api-keys", tag: "API Keys", summary: "Create an API key (owner)", description: "The plaintext key is only returned in this response.", access: accessUser, request: apiKeyRequest{}, status: http.StatusCreated, response: apiKeyResponse{}},
+		{method: http.MethodGet, path: "/api-keys", tag: "API Keys", summary: "List API keys (owner)", access: accessUser, status: http.StatusOK, response: []domain.APIKey{}},
+		{method: http.MethodDelete, path: "/api-keys/{id}", tag: "API Keys", summary: "Revoke an API key (owner)", access: accessUser, params: []param{idParam}, status: http.StatusOK, response: apiKeyRevokedResponse{}},
+	}
+}
+
+// OpenAPI builds the OpenAPI 3 document for the API.
+func OpenAPI() map[string]any {
+	b := &schemaBuilder{components: map[string]any{}, names: map[string]reflect.Type{}}
+	errorSchema := b.schema(reflect.TypeOf(errorResponse{}))
+
+	paths := map[string]any{}
+	for _, op := range operations() {
+		item, _ := paths[op.path].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[op.path] = item
+		}
+
+		success := map[string]any{"description": http.StatusText(op.status)}
+		switch {
+		case op.response != nil:
+			success["content"] = map[string]any{"application/json": map[string]any{"schema": b.schema(reflect.TypeOf(op.response))}}
+		case op.contentType != "":
+			success["content"] = map[string]any{op.contentType: map[string]any{"schema": map[string]any{"type": "string"}}}
+		}
+		responses := map[string]any{
+			fmt.Sprint(op.status): success,
+			"default":             map[string]any{"description": "Error", "content": map[string]any{"application/json": map[string]any{"schema": errorSchema}}},
+		}
+
+		o := map[string]any{
+			"operationId": operationID(op),
+			"tags":        []string{op.tag},
+			"summary":     op.summary,
+			"responses":   responses,
+		}
+		description := op.description
+		switch op.access {
+		case accessPublic:
+			o["security"] = []any{}
+		case accessUser:
+			o["security"] = []any{map[string]any{"bearerAuth": []string{}}}
+		case accessScoped:
+			o["security"] = []any{map[string]any{"bearerAuth": []string{}}, map[string]any{"apiKeyAuth": []string{}}}
+			o["x-api-key-scope"] = op.scope
+			description = strings.TrimSpace(description + " API keys need the `" + op.scope + "` scope.")
+		case accessMetrics:
+			o["security"] = []any{map[string]any{"metricsToken": []string{}}}
+		}
+		if description != "" {
+			o["description"] = description
+		}
+		if len(op.params) > 0 {
+			params := make([]any, len(op.params))
+			for i, p := range op.params {
+				spec := map[string]any{"name": p.name, "in": p.in, "required": p.required, "schema": p.schema}
+				if p.description != "" {
+					spec["description"] = p.description
+				}
+				params[i] = spec
+			}
+			o["parameters"] = params
+		}
+		if op.request != nil {
+			o["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": b.schema(reflect.TypeOf(op.request))}},
+			}
+		}
+		if len(op.upload) > 0 {
+			content := map[string]any{}
+			for _, contentType := range op.upload {
+				content[contentType] = map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}}
+			}
+			o["requestBody"] = map[string]any{"required": true, "content": content}
+		}
+		item[strings.ToLower(op.method)] = o
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "MedEasy POS API",
+			"version":     apiVersion,
+			"description": "Point-of-sale API for pharmacies. Authenticate with a user JWT from /auth/login, or with a per-pharmacy API key on endpoints that accept one.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": b.components,
+			"securitySchemes": map[string]any{
+				"bearerAuth":   map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"apiKeyAuth":   map[string]any{"type": "apiKey", "in": "header", "name": "X-API-Key", "description": "May also be sent as `Authorization: Bearer mek_...`."},
+				"metricsToken": map[string]any{"type": "http", "scheme": "bearer", "description": "The METRICS_TOKEN value."},
+			},
+		},
+	}
+}
+
+// UndocumentedRoutes returns "METHOD /path" for every route in the router
+// that has no matching operation in the OpenAPI document.
+func (h *Handler) UndocumentedRoutes() ([]string, error) {
+	documented := map[string]bool{}
+	for _, op := range operations() {
+		documented[op.method+" "+op.path] = true
+	}
+	var missing []string
+	err := chi.Walk(h.routes(), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if route != "/" {
+			route = strings.TrimSuffix(route, "/")
+		}
+		if !documented[method+" "+route] {
+			missing = append(missing, method+" "+route)
+		}
+		return nil
+	})
+	sort.Strings(missing)
+	return missing, err
+}
+
+func (h *Handler) openAPIDocument(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, OpenAPI())
+}
+
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>MedEasy POS API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+func (h *Handler) docs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(docsPage))
+}
+
+func operationID(op operation) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(op.method))
+	upper := true
+	for _, r := range op.path {
+		switch {
+		case r == '/' || r == '-' || r == '.' || r == '_':
+			upper = true
+		case r == '{':
+			b.WriteString("By")
+			upper = true
+		case r == '}':
+		default:
+			if upper {
+				r = unicode.ToUpper(r)
+				upper = false
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// schemaBuilder converts Go types into OpenAPI schemas, registering named
+// structs as reusable components.
+type schemaBuilder struct {
+	components map[string]any
+	names      map[string]reflect.Type
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOverrides describes types whose JSON encoding differs from their Go kind.
+var schemaOverrides = map[reflect.Type]map[string]any{
+	reflect.TypeOf(domain.Money(0)): {"type": "number", "format": "decimal", "multipleOf": 0.01, "description": "Amount in taka with at most two decimals."},
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	if override, ok := schemaOverrides[t]; ok {
+		out := make(map[string]any, len(override))
+		for k, v := range override {
+			out[k] = v
+		}
+		return out
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		inner := b.schema(t.Elem())
+		if _, isRef := inner["$ref"]; isRef {
+			return map[string]any{"allOf": []any{inner}, "nullable": true}
+		}
+		nullable := map[string]any{"nullable": true}
+		for k, v := range inner {
+			nullable[k] = v
+		}
+		return nullable
+	case reflect.Struct:
+		if t == timeType {
+			return map[string]any{"type": "string", "format": "date-time"}
+		}
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		name := b.componentName(t)
+		if _, ok := b.components[name]; !ok {
+			b.components[name] = map[string]any{} // placeholder guards recursion
+			b.components[name] = b.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number", "format": "double"}
+	default:
+		return map[string]any{}
+	}
+}
+
+func (b *schemaBuilder) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	b.fields(t, properties, &required)
+	obj := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		obj["required"] = required
+	}
+	return obj
+}
+
+func (b *schemaBuilder) fields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				b.fields(ft, properties, required)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		properties[name] = b.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// componentName exports the Go type name, qualifying it with the package when
+// two packages use the same name.
+func (b *schemaBuilder) componentName(t reflect.Type) string {
+	runes := []rune(t.Name())
+	runes[0] = unicode.ToUpper(runes[0])
+	name := string(runes)
+	if existing, ok := b.names[name]; ok && existing != t {
+		pkg := t.PkgPath()
+		if idx := strings.LastIndex(pkg, "/"); idx >= 0 {
+			pkg = pkg[idx+1:]
+		}
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	b.names[name] = t
+	return name
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"medeasy/m/domain"
+	"medeasy/m/internal/config"
+)
+
+// newSpecHandler builds a handler without a database. A metrics token makes
+// the router register every optional route.
+func newSpecHandler() *Handler {
+	return New(nil, config.Config{Metrics: config.MetricsConfig{Token: "openapi-test"}})
+}
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+	missing, err := newSpecHandler().UndocumentedRoutes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) > 0 {
+		t.Errorf("routes missing from the OpenAPI spec:\n  %s", strings.Join(missing, "\n  "))
+	}
+}
+
+func TestEveryOperationIsRouted(t *testing.T) {
+	routed := map[string]bool{}
+	err := chi.Walk(newSpecHandler().routes(), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if route != "/" {
+			route = strings.TrimSuffix(route, "/")
+		}
+		routed[method+" "+route] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range operations() {
+		if !routed[op.method+" "+op.path] {
+			t.Errorf("%s %s is documented but not routed", op.method, op.path)
+		}
+	}
+}
+
+func TestOpenAPIDocumentEncodes(t *testing.T) {
+	data, err := json.Marshal(OpenAPI())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("openapi = %q, want 3.x", doc.OpenAPI)
+	}
+	for _, op := range operations() {
+		if _, ok := doc.Paths[op.path][strings.ToLower(op.method)]; !ok {
+			t.Errorf("%s %s is missing from the document", op.method, op.path)
+		}
+	}
+}
+
+// TestScopeTableIsCurrent keeps the API key scope table in docs.md in step
+// with the scoped operations.
+func TestScopeTableIsCurrent(t *testing.T) {
+	docs, err := os.ReadFile("../../docs.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	documented := map[string]string{}
+	for _, line := range strings.Split(string(docs), "\n") {
+		cells := strings.Split(line, "|")
+		if len(cells) != 4 {
+			continue
+		}
+		scope := strings.Trim(strings.TrimSpace(cells[1]), "`")
+		if !slices.Contains(domain.APIKeyScopes, scope) {
+			continue
+		}
+		for _, route := range strings.Split(cells[2], ",") {
+			documented[strings.Trim(strings.TrimSpace(route), "`")] = scope
+		}
+	}
+
+	scoped := map[string]string{}
+	for _, op := range operations() {
+		if op.access == accessScoped {
+			scoped[op.method+" "+op.path] = op.scope
+		}
+	}
+	for route, scope := range scoped {
+		if documented[route] != scope {
+			t.Errorf("%s needs %s but the docs.md scope table lists it under %q", route, scope, documented[route])
+		}
+	}
+	for route := range documented {
+		if _, ok := scoped[route]; !ok {
+			t.Errorf("docs.md scope table lists %s, which API keys cannot call", route)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"medeasy/m/internal/service"
+)
+
+type newPrescriptionRequest struct {
+	PatientName        string                    `json:"patient_name"`
+	PatientPhone       string                    `json:"patient_phone,omitempty"`
+	PatientAddress     string                    `json:"patient_address,omitempty"`
+	Prescriber         string                    `json:"prescriber"`
+	RegistrationNumber string                    `json:"registration_number"`
+	PrescribedOn       string                    `json:"prescribed_on"`
+	Note               string                    `json:"note,omitempty"`
+	Items              []prescriptionItemRequest `json:"items"`
+}
+
+type prescriptionItemRequest struct {
+	MedicineID  *int64 `json:"medicine_id,omitempty"`
+	Description string `json:"description,omitempty"`
+	Dosage      string `json:"dosage,omitempty"`
+	// Quantity is the units dispensed per fill.
+	Quantity int64 `json:"quantity"`
+	Refills  int64 `json:"refills,omitempty"`
+}
+
+func (h *Handler) createPrescription(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner, service.RoleEmployee, service.RolePharmacist) {
+		return
+	}
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
+		return
+	}
+	var req newPrescriptionRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	in := service.PrescriptionInput{
+		PatientName:        req.PatientName,
+		PatientPhone:       req.PatientPhone,
+		PatientAddress:     req.PatientAddress,
+		Prescriber:         req.Prescriber,
+		RegistrationNumber: req.RegistrationNumber,
+		PrescribedOn:       req.PrescribedOn,
+		Note:               req.Note,
+		Items:              make([]service.PrescriptionItemInput, len(req.Items)),
+	}
+	for i, item := range req.Items {
+		in.Items[i] = service.PrescriptionItemInput{
+			MedicineID:  item.MedicineID,
+			Description: item.Description,
+			Dosage:      item.Dosage,
+			Quantity:    item.Quantity,
+			Refills:     item.Refills,
+		}
+	}
+	prescription, err := h.services.Prescriptions.Create(r.Context(), pharmacyID, userIDFromContext(r), in)
+	if err != nil {
+		h.serviceError(w, r, "unable to save prescription", err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, prescription)
+}
+
+func (h *Handler) findPrescriptions(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner, service.RoleEmployee, service.RolePharmacist) {
+		return
+	}
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
+		return
+	}
+	prescriptions, err := h.services.Prescriptions.Find(r.Context(), pharmacyID, r.URL.Query().Get("patient"))
+	if err != nil {
+		h.serviceError(w, r, "unable to find prescriptions", err)
+		return
+	}
+	respondJSON(w, http.StatusOK, prescriptions)
+}
+
+func (h *Handler) getPrescription(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner, service.RoleEmployee, service.RolePharmacist) {
+		return
+	}
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
+		return
+	}
+	id, ok := urlID(w, r, "prescription")
+	if !ok {
+		return
+	}
+	prescription, err := h.services.Prescriptions.Get(r.Context(), pharmacyID, id)
+	if err != nil {
+		h.serviceError(w, r, "unable to get prescription", err)
+		return
+	}
+	respondJSON(w, http.StatusOK, prescription)
+}
+
+func (h *Handler) uploadPrescriptionScan(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner, service.RoleEmployee, service.RolePharmacist) {
+		return
+	}
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
+		return
+	}
+	id, ok := urlID(w, r, "prescription")
+	if !ok {
+		return
+	}
+	// One byte over the limit lets the service report the scan as too large.
+	body := http.MaxBytesReader(w, r.Body, service.MaxScanBytes+1)
+	prescription, err := h.services.Prescriptions.AttachScan(r.Context(), pharmacyID, id, body)
+	if err != nil {
+		h.serviceError(w, r, "unable to store scan", err)
+		return
+	}
+	respondJSON(w, http.StatusOK, prescription)
+}
+
+func (h *Handler) prescriptionScan(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner, service.RoleEmployee, service.RolePharmacist) {
+		return
+	}
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
+		return
+	}
+	id, ok := urlID(w, r, "prescription")
+	if !ok {
+		return
+	}
+	scan, contentType, err := h.services.Prescriptions.Scan(r.Context(), pharmacyID, id)
+	if err != nil {
+		h.serviceError(w, r, "unable to read scan", err)
+		return
+	}
+	defer scan.Close()
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, scan); err != nil {
+		recordError(r, err)
+	}
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"medeasy/m/internal/service"
+)
+
+// registerHeader lists the columns of the controlled drug register as kept
+// for inspections.
+var registerHeader = []string{
+	"Serial No", "Date", "Sale No", "Patient Name", "Patient Address", "Prescriber", "Prescriber Registration No",
+	"Prescription Date", "Medicine", "Generic Name", "Strength", "Dosage Form", "Manufacturer", "Batch No",
+	"Quantity Dispensed", "Dispensed By", "Unverified",
+}
+
+func (h *Handler) controlledRegister(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner) {
+		return
+	}
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		respondError(w, http.StatusBadRequest, "format must be json or csv")
+		return
+	}
+	register, err := h.services.Reports.ControlledRegister(r.Context(), pharmacyID, r.URL.Query().Get("start_date"), r.URL.Query().Get("end_date"))
+	if err != nil {
+		h.serviceError(w, r, "unable to fetch controlled drug register", err)
+		return
+	}
+	if format != "csv" {
+		respondJSON(w, http.StatusOK, register)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="controlled-register-%s.csv"`, time.Now().Format("2006-01-02")))
+	out := csv.NewWriter(w)
+	_ = out.Write(registerHeader)
+	for i, entry := range register {
+		date := entry.DispensedAt
+		if t, err := time.Parse(time.RFC3339Nano, entry.DispensedAt); err == nil {
+			date = t.In(h.location).Format("2006-01-02 15:04")
+		}
+		_ = out.Write([]string{
+			strconv.Itoa(i + 1), date, strconv.FormatInt(entry.SaleID, 10),
+			value(entry.PatientName), value(entry.PatientAddress), value(entry.Prescriber), value(entry.RegistrationNumber),
+			value(entry.PrescribedOn), entry.BrandName, entry.GenericName, entry.Strength, entry.DosageForm, entry.Manufacturer,
+			value(entry.BatchNumber), strconv.FormatInt(entry.Quantity, 10), entry.DispensedBy, unverified(entry.Unverified),
+		})
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		recordError(r, err)
+	}
+}
+
+func unverified(b bool) string {
+	if b {
+		return "Unverified"
+	}
+	return ""
+}
+
+func value(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"net/http"
+
+	"medeasy/m/domain"
+	"medeasy/m/internal/service"
+)
+
+type submitRequest struct {
+	// Strength and DosageForm complete what the inventory item records.
+	Strength   string `json:"strength,omitempty"`
+	DosageForm string `json:"dosage_form,omitempty"`
+}
+
+func (h *Handler) submitInventory(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner) {
+		return
+	}
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
+		return
+	}
+	id, ok := urlID(w, r, "inventory")
+	if !ok {
+		return
+	}
+	var req submitRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	submission, err := h.services.Submissions.Submit(r.Context(), pharmacyID, userIDFromContext(r), id, service.SubmissionInput{
+		Strength:   req.Strength,
+		DosageForm: req.DosageForm,
+	})
+	if err != nil {
+		h.serviceError(w, r, "unable to submit medicine", err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, submission)
+}
+
+func (h *Handler) listSubmissions(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner) {
+		return
+	}
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
+		return
+	}
+	submissions, err := h.services.Submissions.List(r.Context(), pharmacyID, r.URL.Query().Get("status"))
+	if err != nil {
+		h.serviceError(w, r, "unable to list submissions", err)
+		return
+	}
+	respondJSON(w, http.StatusOK, submissions)
+}
+
+type mergeRequest struct {
+	MedicineID int64 `json:"medicine_id"`
+}
+
+type mergeResponse struct {
+	Submission domain.CatalogSubmission `json:"submission"`
+	Relinked   domain.Relinked          `json:"relinked"`
+}
+
+func (h *Handler) mergeSubmission(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner) {
+		return
+	}
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
+		return
+	}
+	id, ok := urlID(w, r, "submission")
+	if !ok {
+		return
+	}
+	var req mergeRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	submission, relinked, err := h.services.Submissions.Merge(r.Context(), pharmacyID, id, req.MedicineID)
+	if err != nil {
+		h.serviceError(w, r, "unable to merge submission", err)
+		return
+	}
+	respondJSON(w, http.StatusOK, mergeResponse{Submission: submission, Relinked: relinked})
+}
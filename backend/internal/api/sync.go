@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"medeasy/m/internal/service"
+)
+
+// syncParams reads the cursor and page size shared by the sync endpoints.
+func syncParams(r *http.Request) (string, int) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	return r.URL.Query().Get("since"), limit
+}
+
+func (h *Handler) syncInventory(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner, service.RoleEmployee, service.RolePharmacist) {
+		return
+	}
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
+		return
+	}
+	since, limit := syncParams(r)
+	changes, err := h.services.Sync.Inventory(r.Context(), pharmacyID, since, limit)
+	if err != nil {
+		h.serviceError(w, r, "unable to sync inventory", err)
+		return
+	}
+	respondJSON(w, http.StatusOK, changes)
+}
+
+func (h *Handler) syncCatalog(w http.ResponseWriter, r *http.Request) {
+	since, limit := syncParams(r)
+	changes, err := h.services.Sync.Catalog(r.Context(), since, limit)
+	if err != nil {
+		h.serviceError(w, r, "unable to sync catalog", err)
+		return
+	}
+	respondJSON(w, http.StatusOK, changes)
+}
+
+func (h *Handler) syncSales(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, service.RoleOwner) {
+		return
+	}
+	pharmacyID, ok := requirePharmacy(w, r)
+	if !ok {
+		return
+	}
+	since, limit := syncParams(r)
+	changes, err := h.services.Sync.Sales(r.Context(), pharmacyID, since, limit)
+	if err != nil {
+		h.serviceError(w, r, "unable to sync sales", err)
+		return
+	}
+	respondJSON(w, http.StatusOK, changes)
+}
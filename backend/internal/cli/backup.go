@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"medeasy/m/internal/config"
+)
+
+// backup writes a custom-format pg_dump archive of the configured database.
+func backup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	file := fs.String("file", "medeasy-"+time.Now().Format("20060102-150405")+".dump", "archive to write")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	cmd, err := pgCommand(cfg.DatabaseDSN, "pg_dump", "--format=custom", "--no-owner", "--file="+*file)
+	if err != nil {
+		return err
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pg_dump: %w", err)
+	}
+	fmt.Printf("backup written to %s\n", *file)
+	return nil
+}
+
+// restore replaces the configured database's contents with a pg_dump archive.
+func restore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	file := fs.String("file", "", "archive produced by backup")
+	yes := fs.Bool("yes", false, "skip the confirmation prompt")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *file == "" {
+		return fmt.Errorf("%w: --file is required", errUsage)
+	}
+	if _, err := os.Stat(*file); err != nil {
+		return err
+	}
+	if !*yes && !confirm(fmt.Sprintf("restore %s over the current database? existing data will be replaced", *file)) {
+		return fmt.Errorf("restore cancelled")
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	cmd, err := pgCommand(cfg.DatabaseDSN, "pg_restore", "--clean", "--if-exists", "--no-owner", "--single-transaction", *file)
+	if err != nil {
+		return err
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pg_restore: %w", err)
+	}
+	fmt.Printf("restored %s\n", *file)
+	return nil
+}
+
+// pgCommand prepares a PostgreSQL client tool to run against dsn. The
+// password is passed in PGPASSWORD rather than on the command line, where
+// any local user could read it from the process list.
+func pgCommand(dsn, name string, args ...string) (*exec.Cmd, error) {
+	parsed, err := pgconn.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DATABASE_DSN: %w", err)
+	}
+	conninfo, err := withoutPassword(dsn)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(name, append([]string{"--dbname=" + conninfo}, args...)...)
+	cmd.Env = os.Environ()
+	if parsed.Password != "" {
+		cmd.Env = append(cmd.Env, "PGPASSWORD="+parsed.Password)
+	}
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd, nil
+}
+
+// withoutPassword removes the password from a connection URL or from a
+// keyword/value connection string.
+func withoutPassword(dsn string) (string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", fmt.Errorf("invalid DATABASE_DSN: %w", err)
+		}
+		if u.User != nil {
+			u.User = url.User(u.User.Username())
+		}
+		query := u.Query()
+		query.Del("password")
+		u.RawQuery = query.Encode()
+		return u.String(), nil
+	}
+
+	var kept []string
+	rest := strings.TrimSpace(dsn)
+	for rest != "" {
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			return "", fmt.Errorf("invalid DATABASE_DSN: expected key=value at %q", rest)
+		}
+		key := strings.TrimSpace(rest[:eq])
+		rest = strings.TrimLeft(rest[eq+1:], " \t\r\n")
+		end := valueEnd(rest)
+		if end < 0 {
+			return "", fmt.Errorf("invalid DATABASE_DSN: unterminated quoted value of %s", key)
+		}
+		if key != "password" {
+			kept = append(kept, key+"="+rest[:end])
+		}
+		rest = strings.TrimLeft(rest[end:], " \t\r\n")
+	}
+	return strings.Join(kept, " "), nil
+}
+
+// valueEnd returns the length of the keyword value s starts with: a quoted
+// value up to its closing quote, otherwise up to whitespace. Backslashes
+// escape the next character. It is -1 when a quote is not closed.
+func valueEnd(s string) int {
+	quoted := strings.HasPrefix(s, "'")
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			i++
+		case quoted && c == '\'' && i > 0:
+			return i + 1
+		case !quoted && (c == ' ' || c == '\t' || c == '\r' || c == '\n'):
+			return i
+		}
+	}
+	if quoted {
+		return -1
+	}
+	return len(s)
+}
+
+func confirm(prompt string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", prompt)
+	var answer string
+	_, _ = fmt.Scanln(&answer)
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
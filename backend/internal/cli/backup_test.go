@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWithoutPassword(t *testing.T) {
+	tests := []struct {
+		dsn  string
+		want string
+	}{
+		{"postgres://medeasy:s3cret@db:5432/medeasy?sslmode=disable", "postgres://medeasy@db:5432/medeasy?sslmode=disable"},
+		{"postgresql://medeasy@db/medeasy?password=s3cret&sslmode=require", "postgresql://medeasy@db/medeasy?sslmode=require"},
+		{"host=db user=medeasy password=s3cret dbname=medeasy", "host=db user=medeasy dbname=medeasy"},
+		{"host = db password = s3cret dbname=medeasy", "host=db dbname=medeasy"},
+		{`host=db password='s3cret \' with spaces' sslmode=disable`, "host=db sslmode=disable"},
+		{`password=s3cret\ too host=db`, "host=db"},
+	}
+	for _, tt := range tests {
+		got, err := withoutPassword(tt.dsn)
+		if err != nil || got != tt.want {
+			t.Errorf("withoutPassword(%q) = %q, %v; want %q", tt.dsn, got, err, tt.want)
+		}
+	}
+}
+
+func TestPGCommandKeepsPasswordOutOfArgs(t *testing.T) {
+	cmd, err := pgCommand("postgres://medeasy:s3cret@db/medeasy", "pg_dump", "--format=custom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, arg := range cmd.Args {
+		if strings.Contains(arg, "s3cret") {
+			t.Errorf("argument %q holds the password", arg)
+		}
+	}
+	if !slices.Contains(cmd.Env, "PGPASSWORD=s3cret") {
+		t.Error("PGPASSWORD is not set")
+	}
+}
+
+func TestWithoutPasswordRejectsMalformed(t *testing.T) {
+	for _, dsn := range []string{"host=db password='s3cret", "host"} {
+		if got, err := withoutPassword(dsn); err == nil {
+			t.Errorf("withoutPassword(%q) = %q, want an error", dsn, got)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"medeasy/m/domain"
+	"medeasy/m/internal/postgres"
+	"medeasy/m/internal/seed"
+	"medeasy/m/internal/service"
+)
+
+func catalogCommand(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "submissions":
+			return catalogSubmissions(args[1:])
+		case "approve", "reject", "merge":
+			return catalogReview(args[0], args[1:])
+		case "barcode":
+			return catalogBarcode(args[1:])
+		}
+	}
+	if len(args) == 0 || (args[0] != "diff" && args[0] != "import") {
+		return fmt.Errorf("%w: catalog diff|import|submissions|approve|reject|merge|barcode", errUsage)
+	}
+	apply := args[0] == "import"
+	fs := flag.NewFlagSet("catalog "+args[0], flag.ContinueOnError)
+	file := fs.String("file", "", "new medicine catalog CSV")
+	yes := fs.Bool("yes", false, "apply without the confirmation prompt")
+	if err := fs.Parse(args[1:]); err != nil {
+		return errUsage
+	}
+	if *file == "" {
+		return fmt.Errorf("%w: --file is required", errUsage)
+	}
+
+	rows, err := seed.ReadCatalog(*file)
+	if err != nil {
+		return err
+	}
+	_, db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	diff, err := seed.DiffCatalog(ctx, db, rows)
+	if err != nil {
+		return err
+	}
+	if err := printCatalogDiff(diff); err != nil {
+		return err
+	}
+	if diff.Empty() || !apply {
+		return nil
+	}
+	if !*yes && !confirm(fmt.Sprintf("apply these changes from %s?", *file)) {
+		return fmt.Errorf("import cancelled")
+	}
+	result, err := seed.ApplyCatalog(ctx, db, rows, diff)
+	if err != nil {
+		return err
+	}
+	for _, failure := range result.Failures {
+		fmt.Printf("unparsed package: %s\n", failure)
+	}
+	fmt.Printf("added %d, changed %d and discontinued %d medicines; loaded %d packs and reclassified %d medicines\n",
+		len(diff.Added), len(diff.Changed), len(diff.Removed), result.Packs, result.Classified)
+	return nil
+}
+
+// printCatalogDiff lists added (+), changed (~) and discontinued (-) brands.
+func printCatalogDiff(diff seed.CatalogDiff) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "\tBRAND ID\tBRAND\tMANUFACTURER\tCHANGED")
+	for _, row := range diff.Added {
+		fmt.Fprintf(tw, "+\t%d\t%s\t%s\t\n", row.BrandID, row.BrandName, row.Manufacturer)
+	}
+	for _, change := range diff.Changed {
+		fmt.Fprintf(tw, "~\t%d\t%s\t%s\t%s\n", change.After.BrandID, change.After.BrandName, change.After.Manufacturer, strings.Join(change.Fields, ", "))
+	}
+	for _, medicine := range diff.Removed {
+		fmt.Fprintf(tw, "-\t%d\t%s\t%s\t\n", medicine.BrandID, medicine.BrandName, medicine.Manufacturer)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("%d added, %d changed, %d to discontinue\n", len(diff.Added), len(diff.Changed), len(diff.Removed))
+	return nil
+}
+
+// catalogSubmissions lists the custom medicines pharmacies submitted, with
+// the catalog medicines each pending one may duplicate.
+func catalogSubmissions(args []string) error {
+	fs := flag.NewFlagSet("catalog submissions", flag.ContinueOnError)
+	status := fs.String("status", domain.SubmissionPending, "pending, merged, added, rejected or all")
+	pharmacyID := fs.Int64("pharmacy-id", 0, "only this pharmacy's submissions")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *status == "all" {
+		*status = ""
+	}
+
+	_, db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	submissions, err := service.New(postgres.New(db), service.Config{}).Submissions.List(context.Background(), *pharmacyID, *status)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tPHARMACY\tSTATUS\tBRAND\tGENERIC\tSTRENGTH\tMANUFACTURER\tMEDICINE")
+	for _, s := range submissions {
+		medicine := ""
+		if s.MedicineID != nil {
+			medicine = strconv.FormatInt(*s.MedicineID, 10)
+		}
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n", s.ID, s.PharmacyID, s.Status, s.BrandName, s.GenericName, s.Strength, s.Manufacturer, medicine)
+		for _, c := range s.Candidates {
+			fmt.Fprintf(tw, "\t\t~ %.2f\t%s\t%s\t%s\t%s\t%d\n", c.Similarity, c.BrandName, c.GenericName, c.Strength, c.Manufacturer, c.ID)
+		}
+	}
+	return tw.Flush()
+}
+
+// catalogReview closes a pending submission: approve adds it to the catalog
+// as a new medicine, merge links it to an existing one, reject declines it.
+func catalogReview(action string, args []string) error {
+	fs := flag.NewFlagSet("catalog "+action, flag.ContinueOnError)
+	medicineID := fs.Int64("medicine-id", 0, "catalog medicine to merge into")
+	note := fs.String("note", "", "reason for rejecting, shown to the pharmacy")
+	yes := fs.Bool("yes", false, "apply without the confirmation prompt")
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		// The id comes first; flag parsing stops at the first argument.
+		args = append(args[1:], args[0])
+	}
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return fmt.Errorf("%w: catalog %s <submission id> [flags]", errUsage, action)
+	}
+	id, err := strconv.ParseInt(fs.Arg(0), 10, 64)
+	if err != nil || id <= 0 {
+		return fmt.Errorf("%w: invalid submission id %q", errUsage, fs.Arg(0))
+	}
+	if action == "merge" && *medicineID <= 0 {
+		return fmt.Errorf("%w: --medicine-id is required", errUsage)
+	}
+
+	_, db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	submissions := service.New(postgres.New(db), service.Config{}).Submissions
+	ctx := context.Background()
+
+	if action == "reject" {
+		submission, err := submissions.Reject(ctx, id, *note)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("rejected submission %d (%s)\n", submission.ID, submission.BrandName)
+		return nil
+	}
+	if !*yes && !confirm(fmt.Sprintf("%s submission %d and relink its inventory and sales?", action, id)) {
+		return fmt.Errorf("%s cancelled", action)
+	}
+	var submission domain.CatalogSubmission
+	var relinked domain.Relinked
+	if action == "approve" {
+		submission, relinked, err = submissions.Approve(ctx, id)
+	} else {
+		submission, relinked, err = submissions.Merge(ctx, 0, id, *medicineID)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s %s as medicine %d; relinked %d inventory items and %d sale lines\n",
+		submission.Status, submission.BrandName, *submission.MedicineID, relinked.Inventory, relinked.SaleItems)
+	return nil
+}
+
+// catalogBarcode registers the barcode printed on a catalog medicine. Scans
+// of the code resolve to the medicine in every pharmacy.
+func catalogBarcode(args []string) error {
+	fs := flag.NewFlagSet("catalog barcode", flag.ContinueOnError)
+	code := fs.String("code", "", "EAN-13, UPC-A, EAN-8 or GS1 code as scanned")
+	packSize := fs.Int64("pack-size", 0, "units in the packs the code is printed on, if specific to a pack")
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		// The id comes first; flag parsing stops at the first argument.
+		args = append(args[1:], args[0])
+	}
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return fmt.Errorf("%w: catalog barcode <medicine id> --code code [--pack-size n]", errUsage)
+	}
+	medicineID, err := strconv.ParseInt(fs.Arg(0), 10, 64)
+	if err != nil || medicineID <= 0 {
+		return fmt.Errorf("%w: invalid medicine id %q", errUsage, fs.Arg(0))
+	}
+	if *code == "" {
+		return fmt.Errorf("%w: --code is required", errUsage)
+	}
+
+	_, db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	barcode, err := service.New(postgres.New(db), service.Config{}).Catalog.AddBarcode(context.Background(), medicineID, *code, *packSize)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("registered GTIN %s for medicine %d\n", barcode.GTIN, barcode.MedicineID)
+	return nil
+}
@@ -0,0 +1,90 @@
+// Package cli implements the subcommands of the MedEasy server binary.
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+
+	"medeasy/m/internal/config"
+	"medeasy/m/internal/database"
+)
+
+const usage = `usage: medeasy <command> [arguments]
+
+commands:
+  serve                                  run the HTTP server (default)
+  migrate up|down [n]|status             apply, roll back or inspect schema migrations
+  seed medicines [--file path]           load the medicine catalog CSV, backfilling missing attributes and packs
+  seed interactions [--file path]        replace the drug interaction knowledge base
+  seed schedules [--file path]           classify medicines as otc, prescription or controlled
+  catalog diff|import --file path [--yes]
+                                         compare a new catalog CSV with the database, or apply it
+  catalog submissions [--status s] [--pharmacy-id n]
+                                         list custom medicines submitted by pharmacies
+  catalog approve|reject|merge <id> [--medicine-id n] [--note text] [--yes]
+                                         add a submission to the catalog, decline it, or merge it
+  catalog barcode <medicine-id> --code c [--pack-size n]
+                                         register the barcode printed on a catalog medicine
+  user create|reset-password|set-role|disable|enable
+                                         manage user accounts and pharmacist roles
+  pharmacy list                          list pharmacies with their owners
+  backup [--file path]                   dump the database with pg_dump
+  restore --file path [--yes]            restore a dump with pg_restore
+  openapi dump|check                     print the OpenAPI spec or fail on undocumented routes
+`
+
+// errUsage signals that the arguments were malformed and usage should be shown.
+var errUsage = errors.New("invalid usage")
+
+// Run dispatches args (without the program name) to a subcommand.
+func Run(args []string) error {
+	if len(args) == 0 {
+		return serve(nil)
+	}
+	var err error
+	switch args[0] {
+	case "serve":
+		err = serve(args[1:])
+	case "migrate":
+		err = migrate(args[1:])
+	case "seed":
+		err = seedCommand(args[1:])
+	case "catalog":
+		err = catalogCommand(args[1:])
+	case "user":
+		err = userCommand(args[1:])
+	case "pharmacy":
+		err = pharmacyCommand(args[1:])
+	case "backup":
+		err = backup(args[1:])
+	case "restore":
+		err = restore(args[1:])
+	case "openapi":
+		err = openAPICommand(args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return nil
+	default:
+		err = fmt.Errorf("%w: unknown command %q", errUsage, args[0])
+	}
+	if errors.Is(err, errUsage) {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	return err
+}
+
+// connect loads configuration and opens the database.
+func connect() (config.Config, *sqlx.DB, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return config.Config{}, nil, err
+	}
+	db, err := database.Connect(cfg)
+	if err != nil {
+		return config.Config{}, nil, err
+	}
+	return cfg, db, nil
+}
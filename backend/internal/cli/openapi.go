@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"medeasy/m/internal/api"
+	"medeasy/m/internal/config"
+)
+
+func openAPICommand(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: openapi dump|check", errUsage)
+	}
+	switch args[0] {
+	case "dump":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(api.OpenAPI())
+	case "check":
+		// A metrics token makes the router register every optional route.
+		handler := api.New(nil, config.Config{Metrics: config.MetricsConfig{Token: "openapi-check"}})
+		missing, err := handler.UndocumentedRoutes()
+		if err != nil {
+			return err
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("routes missing from the OpenAPI spec:\n  %s", strings.Join(missing, "\n  "))
+		}
+		fmt.Println("every route is documented")
+		return nil
+	default:
+		return fmt.Errorf("%w: openapi dump|check", errUsage)
+	}
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+func pharmacyCommand(args []string) error {
+	if len(args) != 1 || args[0] != "list" {
+		return fmt.Errorf("%w: pharmacy list", errUsage)
+	}
+	_, db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var rows []struct {
+		ID         int64   `db:"id"`
+		Name       string  `db:"name"`
+		OwnerEmail *string `db:"owner_email"`
+		Users      int64   `db:"users"`
+		CreatedAt  string  `db:"created_at"`
+	}
+	err = db.Select(&rows, `SELECT p.id, p.name, o.email AS owner_email,
+	        (SELECT COUNT(*) FROM users u WHERE u.pharmacy_id = p.id) AS users,
+	        TO_CHAR(p.created_at, 'YYYY-MM-DD') AS created_at
+	        FROM pharmacies p
+	        LEFT JOIN users o ON o.id = p.owner_id
+	        ORDER BY p.id`)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tOWNER\tUSERS\tCREATED")
+	for _, row := range rows {
+		owner := "-"
+		if row.OwnerEmail != nil {
+			owner = *row.OwnerEmail
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\n", row.ID, row.Name, owner, row.Users, row.CreatedAt)
+	}
+	return tw.Flush()
+}
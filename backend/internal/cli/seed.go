@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+
+	"medeasy/m/internal/seed"
+)
+
+func seedCommand(args []string) error {
+	if len(args) > 0 && args[0] == "interactions" {
+		return seedInteractions(args[1:])
+	}
+	if len(args) > 0 && args[0] == "schedules" {
+		return seedSchedules(args[1:])
+	}
+	if len(args) == 0 || args[0] != "medicines" {
+		return fmt.Errorf("%w: seed medicines|interactions|schedules [--file path]", errUsage)
+	}
+	fs := flag.NewFlagSet("seed medicines", flag.ContinueOnError)
+	file := fs.String("file", "", "medicine catalog CSV (defaults to CATALOG_CSV)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return errUsage
+	}
+
+	cfg, db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if *file == "" {
+		*file = cfg.CatalogCSV
+	}
+	result, err := seed.LoadMedicines(db, *file)
+	if err != nil {
+		return err
+	}
+	for _, failure := range result.Failures {
+		fmt.Printf("unparsed package: %s\n", failure)
+	}
+	fmt.Printf("inserted or backfilled %d medicines and %d packs from %s and reclassified %d; %d package texts could not be parsed\n",
+		result.Medicines, result.Packs, *file, result.Classified, len(result.Failures))
+	return nil
+}
+
+// seedInteractions replaces the drug interaction knowledge base.
+func seedInteractions(args []string) error {
+	fs := flag.NewFlagSet("seed interactions", flag.ContinueOnError)
+	file := fs.String("file", "", "drug interaction CSV (defaults to INTERACTIONS_CSV)")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	cfg, db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if *file == "" {
+		*file = cfg.InteractionsCSV
+	}
+	count, err := seed.LoadInteractions(db, *file)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("loaded %d drug interactions from %s\n", count, *file)
+	return nil
+}
+
+// seedSchedules reclassifies every medicine from the schedule file.
+func seedSchedules(args []string) error {
+	fs := flag.NewFlagSet("seed schedules", flag.ContinueOnError)
+	file := fs.String("file", "", "ingredient schedule CSV (defaults to SCHEDULES_CSV)")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	cfg, db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if *file == "" {
+		*file = cfg.SchedulesCSV
+	}
+	count, err := seed.ApplySchedules(db, *file)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("changed the schedule of %d medicines from %s\n", count, *file)
+	return nil
+}
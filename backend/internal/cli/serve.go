@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"medeasy/m/internal/api"
+	"medeasy/m/internal/logging"
+	"medeasy/m/internal/migrations"
+	"medeasy/m/internal/seed"
+)
+
+func serve(args []string) error {
+	if len(args) > 0 {
+		return errUsage
+	}
+	cfg, db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	logger := logging.New(os.Stdout, cfg.LogLevel)
+	slog.SetDefault(logger)
+
+	applied, err := migrations.Up(context.Background(), db)
+	if err != nil {
+		return err
+	}
+	for _, m := range applied {
+		slog.Info("applied migration", slog.Int64("version", m.Version), slog.String("name", m.Name))
+	}
+
+	// The catalog is seeded on first boot only; `seed medicines` reloads it
+	// and backfills attributes added by upgrades.
+	loaded, err := seed.Loaded(db, seed.SeedMedicines)
+	if err != nil {
+		return err
+	}
+	if !loaded {
+		result, err := seed.LoadMedicines(db, cfg.CatalogCSV)
+		if err != nil {
+			slog.Error("unable to seed medicine catalog", slog.String("error", err.Error()))
+		} else {
+			loaded = true
+			for _, failure := range result.Failures {
+				slog.Warn("unable to parse medicine package", slog.Int("line", failure.Line),
+					slog.String("brand_id", failure.BrandID), slog.String("error", failure.Err.Error()))
+			}
+			slog.Info("seeded medicine catalog", slog.Int("rows", result.Medicines), slog.Int("packs", result.Packs),
+				slog.Int("unparsed_packages", len(result.Failures)))
+		}
+	}
+
+	// The interaction knowledge base is seeded once; use `seed interactions`
+	// to reload it after editing the file.
+	needed, err := seed.InteractionsNeedLoad(db)
+	if err != nil {
+		return err
+	}
+	if needed {
+		count, err := seed.LoadInteractions(db, cfg.InteractionsCSV)
+		if err != nil {
+			slog.Error("unable to seed drug interactions", slog.String("error", err.Error()))
+		} else {
+			slog.Info("seeded drug interactions", slog.Int("rows", count))
+		}
+	}
+
+	// Medicines are classified once the catalog is loaded; use `seed
+	// schedules` to reclassify them after editing the file.
+	classified, err := seed.Loaded(db, seed.SeedSchedules)
+	if err != nil {
+		return err
+	}
+	if loaded && !classified {
+		count, err := seed.ApplySchedules(db, cfg.SchedulesCSV)
+		if err != nil {
+			slog.Error("unable to classify medicine schedules", slog.String("error", err.Error()))
+		} else {
+			slog.Info("classified medicine schedules", slog.Int("medicines", count))
+		}
+	}
+
+	handler := api.New(db, cfg)
+	srv := &http.Server{
+		Addr:              ":" + cfg.HTTPPort,
+		Handler:           handler.Router(),
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 2)
+	go func() {
+		slog.Info("MedEasy POS server starting", slog.String("addr", srv.Addr), slog.String("env", cfg.Env))
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	// Metrics get their own listener when configured so they are never public.
+	var metricsSrv *http.Server
+	if cfg.Metrics.Addr != "" {
+		metricsSrv = &http.Server{
+			Addr:              cfg.Metrics.Addr,
+			Handler:           handler.MetricsHandler(),
+			ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+			ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+		}
+		go func() {
+			slog.Info("metrics listener starting", slog.String("addr", metricsSrv.Addr))
+			serveErr <- metricsSrv.ListenAndServe()
+		}()
+	}
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	// Fail readiness first and keep serving for the drain delay, so load
+	// balancers polling /readyz stop routing here before the listener
+	// closes. Then let in-flight requests (sales in particular) finish
+	// before closing the pool.
+	slog.Info("draining", slog.Duration("delay", cfg.HTTP.DrainDelay))
+	handler.StartDraining()
+	time.Sleep(cfg.HTTP.DrainDelay)
+	slog.Info("shutting down", slog.Int64("in_flight_sales", handler.InFlightSales()))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer cancel()
+	if metricsSrv != nil {
+		defer metricsSrv.Close()
+	}
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown incomplete with %d sales in flight: %w", handler.InFlightSales(), err)
+	}
+	slog.Info("server stopped")
+	return nil
+}
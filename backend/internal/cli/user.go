@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func userCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: user create|reset-password|set-role|disable|enable", errUsage)
+	}
+	switch args[0] {
+	case "create":
+		return userCreate(args[1:])
+	case "reset-password":
+		return userResetPassword(args[1:])
+	case "set-role":
+		return userSetRole(args[1:])
+	case "disable":
+		return userSetDisabled(args[1:], true)
+	case "enable":
+		return userSetDisabled(args[1:], false)
+	default:
+		return fmt.Errorf("%w: unknown user command %q", errUsage, args[0])
+	}
+}
+
+func userCreate(args []string) error {
+	fs := flag.NewFlagSet("user create", flag.ContinueOnError)
+	username := fs.String("username", "", "display name")
+	email := fs.String("email", "", "login email")
+	password := fs.String("password", "", "password (prompted when omitted)")
+	role := fs.String("role", "employee", "owner, employee or pharmacist")
+	pharmacyID := fs.Int64("pharmacy-id", 0, "pharmacy for employees and pharmacists")
+	pharmacyName := fs.String("pharmacy-name", "", "pharmacy to create for owners")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *username == "" || *email == "" {
+		return fmt.Errorf("%w: --username and --email are required", errUsage)
+	}
+	if *role != "owner" && *role != "employee" && *role != "pharmacist" {
+		return fmt.Errorf("role must be owner, employee or pharmacist")
+	}
+	if *role == "owner" && strings.TrimSpace(*pharmacyName) == "" {
+		return fmt.Errorf("--pharmacy-name is required for owners")
+	}
+	if *role != "owner" && *pharmacyID <= 0 {
+		return fmt.Errorf("--pharmacy-id is required for employees and pharmacists")
+	}
+	hashed, err := hashPassword(*password)
+	if err != nil {
+		return err
+	}
+
+	_, db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var userID int64
+	if *role != "owner" {
+		var exists bool
+		if err := tx.Get(&exists, `SELECT EXISTS(SELECT 1 FROM pharmacies WHERE id = $1)`, *pharmacyID); err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("pharmacy %d does not exist", *pharmacyID)
+		}
+		err = tx.QueryRowx(`INSERT INTO users (username, email, password, role, pharmacy_id) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+			*username, strings.ToLower(*email), hashed, *role, *pharmacyID).Scan(&userID)
+	} else {
+		err = tx.QueryRowx(`INSERT INTO users (username, email, password, role) VALUES ($1, $2, $3, $4) RETURNING id`,
+			*username, strings.ToLower(*email), hashed, *role).Scan(&userID)
+		if err == nil {
+			err = tx.QueryRowx(`INSERT INTO pharmacies (name, owner_id) VALUES ($1, $2) RETURNING id`, *pharmacyName, userID).Scan(pharmacyID)
+		}
+		if err == nil {
+			_, err = tx.Exec(`UPDATE users SET pharmacy_id = $1 WHERE id = $2`, *pharmacyID, userID)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	fmt.Printf("created %s %d (%s) in pharmacy %d\n", *role, userID, strings.ToLower(*email), *pharmacyID)
+	return nil
+}
+
+func userResetPassword(args []string) error {
+	fs := flag.NewFlagSet("user reset-password", flag.ContinueOnError)
+	email := fs.String("email", "", "login email")
+	password := fs.String("password", "", "new password (prompted when omitted)")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *email == "" {
+		return fmt.Errorf("%w: --email is required", errUsage)
+	}
+	hashed, err := hashPassword(*password)
+	if err != nil {
+		return err
+	}
+
+	_, db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	res, err := db.Exec(`UPDATE users SET password = $1 WHERE email = $2`, hashed, strings.ToLower(*email))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("no user with email %s", *email)
+	}
+	fmt.Printf("password updated for %s\n", strings.ToLower(*email))
+	return nil
+}
+
+// userSetRole moves a user between the employee and pharmacist roles.
+// Owners keep theirs, since each owns a pharmacy.
+func userSetRole(args []string) error {
+	fs := flag.NewFlagSet("user set-role", flag.ContinueOnError)
+	email := fs.String("email", "", "login email")
+	role := fs.String("role", "", "employee or pharmacist")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *email == "" {
+		return fmt.Errorf("%w: --email is required", errUsage)
+	}
+	if *role != "employee" && *role != "pharmacist" {
+		return fmt.Errorf("%w: --role must be employee or pharmacist", errUsage)
+	}
+
+	_, db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	res, err := db.Exec(`UPDATE users SET role = $1 WHERE email = $2 AND role <> 'owner'`, *role, strings.ToLower(*email))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("no employee or pharmacist with email %s", *email)
+	}
+	fmt.Printf("%s is now a %s\n", strings.ToLower(*email), *role)
+	return nil
+}
+
+// userSetDisabled blocks or restores logins. Tokens already issued are
+// refused from the next request.
+func userSetDisabled(args []string, disabled bool) error {
+	name := "user enable"
+	if disabled {
+		name = "user disable"
+	}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	email := fs.String("email", "", "login email")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *email == "" {
+		return fmt.Errorf("%w: --email is required", errUsage)
+	}
+
+	_, db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	query := `UPDATE users SET disabled_at = NULL WHERE email = $1`
+	if disabled {
+		query = `UPDATE users SET disabled_at = COALESCE(disabled_at, NOW()) WHERE email = $1`
+	}
+	res, err := db.Exec(query, strings.ToLower(*email))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("no user with email %s", *email)
+	}
+	if disabled {
+		fmt.Printf("disabled %s\n", strings.ToLower(*email))
+	} else {
+		fmt.Printf("enabled %s\n", strings.ToLower(*email))
+	}
+	return nil
+}
+
+// hashPassword bcrypts password, reading it from stdin when it is empty so it
+// does not have to appear in shell history.
+func hashPassword(password string) ([]byte, error) {
+	if password == "" {
+		fmt.Fprint(os.Stderr, "password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return nil, fmt.Errorf("unable to read password: %w", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if password == "" {
+		return nil, fmt.Errorf("password must not be empty")
+	}
+	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+}
@@ -1,18 +1,38 @@
 package database
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
+
+	"medeasy/m/internal/config"
 )
 
-// Connect opens a PostgreSQL database using the provided DSN.
-func Connect(dsn string) *sqlx.DB {
-	db, err := sqlx.Connect("pgx", dsn)
+// Connect opens a PostgreSQL connection pool sized and zoned by cfg.
+func Connect(cfg config.Config) (*sqlx.DB, error) {
+	connConfig, err := pgx.ParseConfig(cfg.DatabaseDSN)
 	if err != nil {
-		log.Fatalf("failed to connect to database: %v", err)
+		return nil, fmt.Errorf("invalid DATABASE_DSN: %w", err)
+	}
+	// Sessions use the pharmacy's timezone so CURRENT_DATE matches the shop's day.
+	connConfig.RuntimeParams["timezone"] = cfg.Timezone
+	// Migrations report what they changed in data as notices.
+	connConfig.OnNotice = func(_ *pgconn.PgConn, n *pgconn.Notice) {
+		slog.Info("database notice", slog.String("severity", n.Severity), slog.String("message", n.Message))
+	}
+
+	db := sqlx.NewDb(stdlib.OpenDB(*connConfig), "pgx")
+	db.SetMaxOpenConns(cfg.DB.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.DB.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.DB.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.DB.ConnMaxIdleTime)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
-	db.SetMaxOpenConns(10)
-	return db
+	return db, nil
 }
@@ -0,0 +1,74 @@
+// Package logging configures JSON structured logging and carries
+// request-scoped log context.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+)
+
+// New returns a JSON logger writing to w at the given level
+// (debug, info, warn or error).
+func New(w io.Writer, level string) *slog.Logger {
+	var lvl slog.Level
+	switch level {
+	case "debug":
+		lvl = slog.LevelDebug
+	case "warn":
+		lvl = slog.LevelWarn
+	case "error":
+		lvl = slog.LevelError
+	default:
+		lvl = slog.LevelInfo
+	}
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: lvl}))
+}
+
+// RequestInfo accumulates identifiers about a request as it passes through
+// middleware, so the access log line written at the end can include them.
+type RequestInfo struct {
+	RequestID  string
+	UserID     int64
+	PharmacyID int64
+	APIKeyID   int64
+	// Err is the underlying cause of a 5xx response.
+	Err error
+}
+
+// Attrs returns the identifiers that are known so far.
+func (i *RequestInfo) Attrs() []any {
+	attrs := []any{slog.String("request_id", i.RequestID)}
+	if i.UserID > 0 {
+		attrs = append(attrs, slog.Int64("user_id", i.UserID))
+	}
+	if i.PharmacyID > 0 {
+		attrs = append(attrs, slog.Int64("pharmacy_id", i.PharmacyID))
+	}
+	if i.APIKeyID > 0 {
+		attrs = append(attrs, slog.Int64("api_key_id", i.APIKeyID))
+	}
+	return attrs
+}
+
+type ctxKey struct{}
+
+// WithRequestInfo stores info in ctx.
+func WithRequestInfo(ctx context.Context, info *RequestInfo) context.Context {
+	return context.WithValue(ctx, ctxKey{}, info)
+}
+
+// RequestInfoFrom returns the request info stored in ctx, if any.
+func RequestInfoFrom(ctx context.Context) *RequestInfo {
+	info, _ := ctx.Value(ctxKey{}).(*RequestInfo)
+	return info
+}
+
+// FromContext returns the default logger annotated with the request's
+// identifiers when ctx belongs to an HTTP request.
+func FromContext(ctx context.Context) *slog.Logger {
+	if info := RequestInfoFrom(ctx); info != nil {
+		return slog.Default().With(info.Attrs()...)
+	}
+	return slog.Default()
+}
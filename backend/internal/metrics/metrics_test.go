@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func render(t *testing.T, r *Registry) string {
+	t.Helper()
+	var b strings.Builder
+	if err := r.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestCounterVec(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("sales_total", "Recorded sales.", "pharmacy_id")
+	c.Inc("2")
+	c.Add(2.5, "10")
+	c.Inc("2")
+	c.Add(-1, "2")
+	c.Add(math.NaN(), "2")
+
+	want := `# HELP sales_total Recorded sales.
+# TYPE sales_total counter
+sales_total{pharmacy_id="10"} 2.5
+sales_total{pharmacy_id="2"} 2
+`
+	if got := render(t, r); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("errors_total", "Errors by path.\nSee C:\\logs.", "path", "reason")
+	c.Inc(`/a"b`, "line\nbreak \\ slash")
+
+	want := `# HELP errors_total Errors by path.\nSee C:\\logs.
+# TYPE errors_total counter
+errors_total{path="/a\"b",reason="line\nbreak \\ slash"} 1
+`
+	if got := render(t, r); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHistogramBucketsAreCumulative(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogramVec("latency_seconds", "Latency.", []float64{1, 0.1, 0.5}, "route")
+	for _, v := range []float64{0.05, 0.1, 0.3, 0.7, 2} {
+		h.Observe(v, "/sales")
+	}
+
+	want := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/sales",le="0.1"} 2
+latency_seconds_bucket{route="/sales",le="0.5"} 3
+latency_seconds_bucket{route="/sales",le="1"} 4
+latency_seconds_bucket{route="/sales",le="+Inf"} 5
+latency_seconds_sum{route="/sales"} 3.15
+latency_seconds_count{route="/sales"} 5
+`
+	if got := render(t, r); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFuncMetrics(t *testing.T) {
+	r := NewRegistry()
+	r.NewGaugeFunc("open_connections", "Open connections.", func() float64 { return 3 })
+	r.NewCounterFunc("wait_seconds_total", "Time waited.", func() float64 { return math.Inf(1) })
+
+	want := `# HELP open_connections Open connections.
+# TYPE open_connections gauge
+open_connections 3
+# HELP wait_seconds_total Time waited.
+# TYPE wait_seconds_total counter
+wait_seconds_total +Inf
+`
+	if got := render(t, r); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("up_total", "Up.").Inc()
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "\nup_total 1\n") {
+		t.Errorf("body = %q", rec.Body.String())
+	}
+}
+
+func TestDuplicateAndLabelCountPanic(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("dup_total", "Dup.", "a")
+	for name, fn := range map[string]func(){
+		"duplicate":   func() { r.NewCounterVec("dup_total", "Dup.") },
+		"label count": func() { c.Inc("x", "y") },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("did not panic")
+				}
+			}()
+			fn()
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+func (r *Registry) registerProcess() {
+	r.NewCounterFunc("process_cpu_seconds_total", "User and system CPU time spent by the process.", processCPUSeconds)
+	r.NewGaugeFunc("process_resident_memory_bytes", "Resident memory of the process.", processResidentBytes)
+	r.NewGaugeFunc("process_open_fds", "Open file descriptors.", processOpenFDs)
+	r.NewGaugeFunc("process_max_fds", "Limit on open file descriptors.", processMaxFDs)
+}
+
+func processCPUSeconds() float64 {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return math.NaN()
+	}
+	return float64(usage.Utime.Nano()+usage.Stime.Nano()) / 1e9
+}
+
+func processResidentBytes() float64 {
+	// statm holds sizes in pages: total, then resident.
+	statm, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return math.NaN()
+	}
+	fields := bytes.Fields(statm)
+	if len(fields) < 2 {
+		return math.NaN()
+	}
+	pages, err := strconv.ParseUint(string(fields[1]), 10, 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return float64(pages * uint64(os.Getpagesize()))
+}
+
+func processOpenFDs() float64 {
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return math.NaN()
+	}
+	return float64(len(fds))
+}
+
+func processMaxFDs() float64 {
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
+		return math.NaN()
+	}
+	return float64(limit.Cur)
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package metrics
+
+// registerProcess exports no process series outside Linux, which is the only
+// platform the server is deployed on.
+func (r *Registry) registerProcess() {}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"math"
+	"runtime"
+	rtmetrics "runtime/metrics"
+)
+
+// runtimeSeries maps exported names to runtime/metrics keys.
+var runtimeSeries = []struct {
+	name, help, kind, key string
+}{
+	{"go_memory_total_bytes", "Memory mapped by the Go runtime.", "gauge", "/memory/classes/total:bytes"},
+	{"go_heap_objects_bytes", "Memory occupied by live and not yet swept heap objects.", "gauge", "/memory/classes/heap/objects:bytes"},
+	{"go_heap_goal_bytes", "Heap size at which the next GC cycle starts.", "gauge", "/gc/heap/goal:bytes"},
+	{"go_heap_allocs_bytes_total", "Bytes allocated on the heap.", "counter", "/gc/heap/allocs:bytes"},
+	{"go_heap_allocs_objects_total", "Objects allocated on the heap.", "counter", "/gc/heap/allocs:objects"},
+	{"go_gc_cycles_total", "Completed GC cycles.", "counter", "/gc/cycles/total:gc-cycles"},
+	{"go_gc_cpu_seconds_total", "Estimated CPU time spent in the GC.", "counter", "/cpu/classes/gc/total:cpu-seconds"},
+	{"go_gomaxprocs", "Value of GOMAXPROCS.", "gauge", "/sched/gomaxprocs:threads"},
+}
+
+// RegisterRuntime registers goroutine, heap and GC series read from the Go
+// runtime, and process CPU, memory and file descriptor series where the
+// platform provides them.
+func (r *Registry) RegisterRuntime() {
+	r.NewGaugeFunc("go_goroutines", "Goroutines that currently exist.",
+		func() float64 { return float64(runtime.NumGoroutine()) })
+	for _, s := range runtimeSeries {
+		key := s.key
+		r.register(s.name, &funcMetric{name: s.name, help: s.help, kind: s.kind,
+			fn: func() float64 { return readRuntime(key) }})
+	}
+	r.registerProcess()
+}
+
+// readRuntime reads one runtime/metrics value, or NaN when this Go version
+// does not support it.
+func readRuntime(key string) float64 {
+	sample := []rtmetrics.Sample{{Name: key}}
+	rtmetrics.Read(sample)
+	switch v := sample[0].Value; v.Kind() {
+	case rtmetrics.KindUint64:
+		return float64(v.Uint64())
+	case rtmetrics.KindFloat64:
+		return v.Float64()
+	default:
+		return math.NaN()
+	}
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"math"
+	"runtime"
+	rtmetrics "runtime/metrics"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRuntimeSeriesExist(t *testing.T) {
+	supported := map[string]bool{}
+	for _, d := range rtmetrics.All() {
+		supported[d.Name] = true
+	}
+	for _, s := range runtimeSeries {
+		if !supported[s.key] {
+			t.Errorf("%s reads %s, which this Go version does not provide", s.name, s.key)
+		}
+	}
+}
+
+func TestRegisterRuntime(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterRuntime()
+	values := map[string]float64{}
+	for _, line := range strings.Split(render(t, r), "\n") {
+		name, value, ok := strings.Cut(line, " ")
+		if !ok || strings.HasPrefix(line, "#") {
+			continue
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+		values[name] = v
+	}
+
+	want := []string{"go_goroutines"}
+	for _, s := range runtimeSeries {
+		want = append(want, s.name)
+	}
+	if runtime.GOOS == "linux" {
+		want = append(want, "process_cpu_seconds_total", "process_resident_memory_bytes", "process_open_fds", "process_max_fds")
+	}
+	for _, name := range want {
+		v, ok := values[name]
+		if !ok {
+			t.Errorf("%s is not exported", name)
+		} else if math.IsNaN(v) || v < 0 {
+			t.Errorf("%s = %v", name, v)
+		}
+	}
+	for _, name := range []string{"go_goroutines", "go_memory_total_bytes", "go_gomaxprocs"} {
+		if values[name] <= 0 {
+			t.Errorf("%s = %v, want positive", name, values[name])
+		}
+	}
+}
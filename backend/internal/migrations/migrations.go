@@ -1,85 +1,257 @@
 package migrations
 
 import (
-	"log"
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
-// Run creates the database schema required for the POS backend.
-func Run(db *sqlx.DB) {
-	schema := []string{
-		`CREATE TABLE IF NOT EXISTS users (
-			id SERIAL PRIMARY KEY,
-			username TEXT NOT NULL,
-			email TEXT NOT NULL UNIQUE,
-			password TEXT NOT NULL,
-			role TEXT NOT NULL,
-			pharmacy_id INTEGER,
-			created_at TIMESTAMPTZ DEFAULT NOW()
-		);`,
-		`ALTER TABLE users ADD COLUMN IF NOT EXISTS pharmacy_id INTEGER;`,
-		`CREATE TABLE IF NOT EXISTS pharmacies (
-            id SERIAL PRIMARY KEY,
-            name TEXT NOT NULL,
-            address TEXT,
-            location TEXT,
-            owner_id INTEGER REFERENCES users(id),
-            created_at TIMESTAMPTZ DEFAULT NOW()
-        );`,
-		`CREATE TABLE IF NOT EXISTS medicines (
-            id SERIAL PRIMARY KEY,
-            brand_id INTEGER,
-            brand_name TEXT NOT NULL,
-            type TEXT,
-            generic_name TEXT,
-            manufacturer TEXT,
-            UNIQUE(brand_id)
-        );`,
-		`CREATE TABLE IF NOT EXISTS inventory (
-            id SERIAL PRIMARY KEY,
-            pharmacy_id INTEGER NOT NULL REFERENCES pharmacies(id),
-            medicine_id INTEGER NOT NULL REFERENCES medicines(id),
-            quantity INTEGER NOT NULL,
-            cost_price DOUBLE PRECISION NOT NULL,
-            sale_price DOUBLE PRECISION NOT NULL,
-            expiry_date DATE,
-            created_at TIMESTAMPTZ DEFAULT NOW(),
-            updated_at TIMESTAMPTZ DEFAULT NOW()
-        );`,
-		`CREATE TABLE IF NOT EXISTS sales (
-            id SERIAL PRIMARY KEY,
-            pharmacy_id INTEGER NOT NULL REFERENCES pharmacies(id),
-            user_id INTEGER REFERENCES users(id),
-            total_amount DOUBLE PRECISION NOT NULL,
-            discount DOUBLE PRECISION DEFAULT 0,
-            paid_amount DOUBLE PRECISION DEFAULT 0,
-            due_amount DOUBLE PRECISION DEFAULT 0,
-            created_at TIMESTAMPTZ DEFAULT NOW()
-        );`,
-		`CREATE TABLE IF NOT EXISTS sale_items (
-			id SERIAL PRIMARY KEY,
-			sale_id INTEGER NOT NULL REFERENCES sales(id),
-			medicine_id INTEGER NOT NULL REFERENCES medicines(id),
-			inventory_id INTEGER REFERENCES inventory(id),
-			quantity INTEGER NOT NULL,
-			unit_price DOUBLE PRECISION NOT NULL,
-			subtotal DOUBLE PRECISION NOT NULL
-		);`,
-		`ALTER TABLE sale_items ADD COLUMN IF NOT EXISTS inventory_id INTEGER REFERENCES inventory(id);`,
-		`ALTER TABLE sales ADD COLUMN IF NOT EXISTS round_off DOUBLE PRECISION DEFAULT 0;`,
-		`ALTER TABLE sales ADD COLUMN IF NOT EXISTS change_returned DOUBLE PRECISION DEFAULT 0;`,
-		`ALTER TABLE inventory ADD COLUMN IF NOT EXISTS brand_name TEXT;`,
-		`ALTER TABLE inventory ADD COLUMN IF NOT EXISTS generic_name TEXT;`,
-		`ALTER TABLE inventory ADD COLUMN IF NOT EXISTS manufacturer TEXT;`,
-		`ALTER TABLE inventory ADD COLUMN IF NOT EXISTS type TEXT;`,
-		`ALTER TABLE inventory ALTER COLUMN medicine_id DROP NOT NULL;`,
-		`ALTER TABLE sale_items ALTER COLUMN medicine_id DROP NOT NULL;`,
-	}
-
-	for _, stmt := range schema {
-		if _, err := db.Exec(stmt); err != nil {
-			log.Fatalf("migration failed: %v", err)
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockID is the pg_advisory_lock key that serialises migrators across replicas.
+const lockID int64 = 7_302_461_105
+
+// Migration is one numbered schema change with its reverse step.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes a known migration and whether it has been applied.
+type Status struct {
+	Version   int64      `db:"version" json:"version"`
+	Name      string     `db:"name" json:"name"`
+	AppliedAt *time.Time `db:"applied_at" json:"applied_at,omitempty"`
+}
+
+// Load returns every embedded migration ordered by version.
+func Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migration %s: expected .up.sql or .down.sql suffix", name)
+		}
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		prefix, label, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: expected NNNN_name", name)
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version %q", name, prefix)
+		}
+		body, err := fs.ReadFile(files, path.Join("sql", name))
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		} else if m.Name != label {
+			return nil, fmt.Errorf("migration %d: mismatched names %q and %q", version, m.Name, label)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s: both up and down files are required", m.Version, m.Name)
 		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Latest returns the highest embedded migration version.
+func Latest() (int64, error) {
+	migrations, err := Load()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// Current returns the highest applied migration version, or 0 for a fresh database.
+func Current(ctx context.Context, db *sqlx.DB) (int64, error) {
+	var exists bool
+	if err := db.GetContext(ctx, &exists, `SELECT to_regclass('schema_migrations') IS NOT NULL`); err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, nil
+	}
+	var version int64
+	err := db.GetContext(ctx, &version, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`)
+	return version, err
+}
+
+// Up applies every pending migration, each in its own transaction, and
+// returns the ones it applied.
+func Up(ctx context.Context, db *sqlx.DB) ([]Migration, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	var applied []Migration
+	err = withLock(ctx, db, func(conn *sqlx.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if done[m.Version] {
+				continue
+			}
+			if err := apply(ctx, conn, m.Up, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name); err != nil {
+				return fmt.Errorf("migration %04d_%s up: %w", m.Version, m.Name, err)
+			}
+			applied = append(applied, m)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the last n applied migrations, newest first.
+func Down(ctx context.Context, db *sqlx.DB, n int) ([]Migration, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("number of migrations to roll back must be positive")
+	}
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	var reverted []Migration
+	err = withLock(ctx, db, func(conn *sqlx.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(migrations) - 1; i >= 0 && len(reverted) < n; i-- {
+			m := migrations[i]
+			if !done[m.Version] {
+				continue
+			}
+			if err := apply(ctx, conn, m.Down, `DELETE FROM schema_migrations WHERE version = $1`, m.Version); err != nil {
+				return fmt.Errorf("migration %04d_%s down: %w", m.Version, m.Name, err)
+			}
+			reverted = append(reverted, m)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Statuses lists every embedded migration alongside when it was applied.
+func Statuses(ctx context.Context, db *sqlx.DB) ([]Status, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	appliedAt := map[int64]time.Time{}
+	var exists bool
+	if err := db.GetContext(ctx, &exists, `SELECT to_regclass('schema_migrations') IS NOT NULL`); err != nil {
+		return nil, err
+	}
+	if exists {
+		var rows []Status
+		if err := db.SelectContext(ctx, &rows, `SELECT version, name, applied_at FROM schema_migrations`); err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			appliedAt[row.Version] = *row.AppliedAt
+		}
+	}
+	statuses := make([]Status, len(migrations))
+	for i, m := range migrations {
+		statuses[i] = Status{Version: m.Version, Name: m.Name}
+		if t, ok := appliedAt[m.Version]; ok {
+			statuses[i].AppliedAt = &t
+		}
+	}
+	return statuses, nil
+}
+
+// withLock runs fn on a dedicated connection holding the migration advisory
+// lock, so replicas booting together apply each migration exactly once.
+func withLock(ctx context.Context, db *sqlx.DB, fn func(conn *sqlx.Conn) error) error {
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+func appliedVersions(ctx context.Context, conn *sqlx.Conn) (map[int64]bool, error) {
+	var versions []int64
+	if err := conn.SelectContext(ctx, &versions, `SELECT version FROM schema_migrations`); err != nil {
+		return nil, err
+	}
+	done := make(map[int64]bool, len(versions))
+	for _, v := range versions {
+		done[v] = true
+	}
+	return done, nil
+}
+
+// apply runs a migration body and its bookkeeping statement in one transaction.
+func apply(ctx context.Context, conn *sqlx.Conn, body, bookkeeping string, args ...any) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, body); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		return err
 	}
+	return tx.Commit()
 }
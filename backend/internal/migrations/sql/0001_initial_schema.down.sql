@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS sale_items;
+DROP TABLE IF EXISTS sales;
+DROP TABLE IF EXISTS inventory;
+DROP TABLE IF EXISTS medicines;
+DROP TABLE IF EXISTS pharmacies;
+DROP TABLE IF EXISTS users;
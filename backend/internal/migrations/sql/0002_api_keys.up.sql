@@ -0,0 +1,19 @@
+-- Per-pharmacy API keys for machine-to-machine integrations.
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    pharmacy_id INTEGER NOT NULL REFERENCES pharmacies(id),
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL UNIQUE,
+    secret_hash TEXT NOT NULL,
+    scopes TEXT NOT NULL,
+    created_by INTEGER REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_pharmacy_id_idx ON api_keys (pharmacy_id);
+
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS api_key_id INTEGER REFERENCES api_keys(id);
@@ -0,0 +1,3 @@
+-- Lets operators disable an account from the admin CLI without deleting it.
+
+ALTER TABLE users ADD COLUMN disabled_at TIMESTAMPTZ;
@@ -0,0 +1,25 @@
+ALTER TABLE sale_items
+    ALTER COLUMN unit_price TYPE DOUBLE PRECISION,
+    ALTER COLUMN subtotal TYPE DOUBLE PRECISION;
+
+ALTER TABLE sales
+    ALTER COLUMN total_amount TYPE DOUBLE PRECISION,
+    ALTER COLUMN discount TYPE DOUBLE PRECISION,
+    ALTER COLUMN paid_amount TYPE DOUBLE PRECISION,
+    ALTER COLUMN due_amount TYPE DOUBLE PRECISION,
+    ALTER COLUMN round_off TYPE DOUBLE PRECISION,
+    ALTER COLUMN change_returned TYPE DOUBLE PRECISION;
+
+ALTER TABLE inventory
+    ALTER COLUMN cost_price TYPE DOUBLE PRECISION,
+    ALTER COLUMN sale_price TYPE DOUBLE PRECISION;
+
+UPDATE inventory SET
+    cost_price = pack_cost_price::double precision / pack_size,
+    sale_price = pack_sale_price::double precision / pack_size;
+
+ALTER TABLE inventory
+    DROP CONSTRAINT inventory_pack_size_positive,
+    DROP COLUMN pack_sale_price,
+    DROP COLUMN pack_cost_price,
+    DROP COLUMN pack_size;
@@ -0,0 +1,37 @@
+-- Store money as NUMERIC(12,2) instead of DOUBLE PRECISION.
+--
+-- Inventory keeps the price of the pack it was received in together with the
+-- pack size, so a unit of a 10-taka strip of 3 sells for exactly 3.33, 3.33
+-- and 3.34 rather than 3.3333333 each. cost_price and sale_price remain as the
+-- rounded per-unit prices shown to users. Existing rows only know their unit
+-- price, so they become packs of one at that price rounded to the paisa.
+
+ALTER TABLE inventory
+    ADD COLUMN pack_size INTEGER,
+    ADD COLUMN pack_cost_price NUMERIC(12,2),
+    ADD COLUMN pack_sale_price NUMERIC(12,2);
+
+UPDATE inventory SET
+    pack_size = 1,
+    pack_cost_price = ROUND(cost_price::numeric, 2),
+    pack_sale_price = ROUND(sale_price::numeric, 2);
+
+ALTER TABLE inventory
+    ALTER COLUMN pack_size SET NOT NULL,
+    ALTER COLUMN pack_cost_price SET NOT NULL,
+    ALTER COLUMN pack_sale_price SET NOT NULL,
+    ADD CONSTRAINT inventory_pack_size_positive CHECK (pack_size > 0),
+    ALTER COLUMN cost_price TYPE NUMERIC(12,2) USING ROUND(cost_price::numeric, 2),
+    ALTER COLUMN sale_price TYPE NUMERIC(12,2) USING ROUND(sale_price::numeric, 2);
+
+ALTER TABLE sales
+    ALTER COLUMN total_amount TYPE NUMERIC(12,2) USING ROUND(total_amount::numeric, 2),
+    ALTER COLUMN discount TYPE NUMERIC(12,2) USING ROUND(discount::numeric, 2),
+    ALTER COLUMN paid_amount TYPE NUMERIC(12,2) USING ROUND(paid_amount::numeric, 2),
+    ALTER COLUMN due_amount TYPE NUMERIC(12,2) USING ROUND(due_amount::numeric, 2),
+    ALTER COLUMN round_off TYPE NUMERIC(12,2) USING ROUND(round_off::numeric, 2),
+    ALTER COLUMN change_returned TYPE NUMERIC(12,2) USING ROUND(change_returned::numeric, 2);
+
+ALTER TABLE sale_items
+    ALTER COLUMN unit_price TYPE NUMERIC(12,2) USING ROUND(unit_price::numeric, 2),
+    ALTER COLUMN subtotal TYPE NUMERIC(12,2) USING ROUND(subtotal::numeric, 2);
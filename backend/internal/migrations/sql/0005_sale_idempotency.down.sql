@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS sales_idempotency_key_idx;
+
+ALTER TABLE sales
+    DROP COLUMN received_at,
+    DROP COLUMN request_hash,
+    DROP COLUMN idempotency_key;
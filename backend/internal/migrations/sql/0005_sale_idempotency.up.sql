@@ -0,0 +1,17 @@
+-- Offline clients replay queued sales; an idempotency key per pharmacy makes
+-- the replay return the original sale instead of recording it twice.
+-- created_at becomes the time of sale as reported by the client, and
+-- received_at keeps when the server actually recorded it.
+
+ALTER TABLE sales
+    ADD COLUMN idempotency_key TEXT,
+    ADD COLUMN request_hash TEXT,
+    ADD COLUMN received_at TIMESTAMPTZ;
+
+UPDATE sales SET received_at = created_at;
+
+ALTER TABLE sales
+    ALTER COLUMN received_at SET DEFAULT NOW(),
+    ALTER COLUMN received_at SET NOT NULL;
+
+CREATE UNIQUE INDEX sales_idempotency_key_idx ON sales (pharmacy_id, idempotency_key) WHERE idempotency_key IS NOT NULL;
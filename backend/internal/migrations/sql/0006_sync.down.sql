@@ -0,0 +1,18 @@
+DROP TRIGGER IF EXISTS sales_sync_tombstone ON sales;
+DROP TRIGGER IF EXISTS inventory_sync_tombstone ON inventory;
+DROP TRIGGER IF EXISTS medicines_sync_tombstone ON medicines;
+DROP TRIGGER IF EXISTS sales_sync_touch ON sales;
+DROP TRIGGER IF EXISTS inventory_sync_touch ON inventory;
+DROP TRIGGER IF EXISTS medicines_sync_touch ON medicines;
+
+DROP INDEX IF EXISTS sales_sync_idx;
+DROP INDEX IF EXISTS inventory_sync_idx;
+DROP INDEX IF EXISTS medicines_sync_idx;
+
+ALTER TABLE sales DROP COLUMN sync_txid;
+ALTER TABLE inventory DROP COLUMN sync_txid;
+ALTER TABLE medicines DROP COLUMN sync_txid;
+
+DROP FUNCTION IF EXISTS sync_tombstone();
+DROP TABLE IF EXISTS sync_tombstones;
+DROP FUNCTION IF EXISTS sync_touch();
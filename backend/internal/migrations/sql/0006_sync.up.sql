@@ -0,0 +1,50 @@
+-- Change tracking for offline clients.
+--
+-- Every write stamps the row with the id of the writing transaction, and
+-- deletes leave a tombstone stamped the same way. Clients page through
+-- changes in (sync_txid, id) order and only see transactions older than the
+-- oldest one still running, so a slow transaction can never commit behind a
+-- cursor a client has already passed. Requires PostgreSQL 13 or later.
+
+CREATE FUNCTION sync_touch() RETURNS trigger AS $$
+BEGIN
+    NEW.sync_txid := pg_current_xact_id();
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TABLE sync_tombstones (
+    id BIGSERIAL PRIMARY KEY,
+    entity TEXT NOT NULL,
+    pharmacy_id INTEGER,
+    row_id INTEGER NOT NULL,
+    sync_txid xid8 NOT NULL DEFAULT pg_current_xact_id(),
+    deleted_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX sync_tombstones_cursor_idx ON sync_tombstones (entity, COALESCE(pharmacy_id, 0), sync_txid, id);
+
+-- entity is the table name; catalog rows have no pharmacy.
+CREATE FUNCTION sync_tombstone() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO sync_tombstones (entity, pharmacy_id, row_id)
+    VALUES (TG_TABLE_NAME, (to_jsonb(OLD) ->> 'pharmacy_id')::INTEGER, OLD.id);
+    RETURN OLD;
+END
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE medicines ADD COLUMN sync_txid xid8 NOT NULL DEFAULT pg_current_xact_id();
+ALTER TABLE inventory ADD COLUMN sync_txid xid8 NOT NULL DEFAULT pg_current_xact_id();
+ALTER TABLE sales ADD COLUMN sync_txid xid8 NOT NULL DEFAULT pg_current_xact_id();
+
+CREATE INDEX medicines_sync_idx ON medicines (sync_txid, id);
+CREATE INDEX inventory_sync_idx ON inventory (pharmacy_id, sync_txid, id);
+CREATE INDEX sales_sync_idx ON sales (pharmacy_id, sync_txid, id);
+
+CREATE TRIGGER medicines_sync_touch BEFORE INSERT OR UPDATE ON medicines FOR EACH ROW EXECUTE FUNCTION sync_touch();
+CREATE TRIGGER inventory_sync_touch BEFORE INSERT OR UPDATE ON inventory FOR EACH ROW EXECUTE FUNCTION sync_touch();
+CREATE TRIGGER sales_sync_touch BEFORE INSERT OR UPDATE ON sales FOR EACH ROW EXECUTE FUNCTION sync_touch();
+
+CREATE TRIGGER medicines_sync_tombstone AFTER DELETE ON medicines FOR EACH ROW EXECUTE FUNCTION sync_tombstone();
+CREATE TRIGGER inventory_sync_tombstone AFTER DELETE ON inventory FOR EACH ROW EXECUTE FUNCTION sync_tombstone();
+CREATE TRIGGER sales_sync_tombstone AFTER DELETE ON sales FOR EACH ROW EXECUTE FUNCTION sync_tombstone();
@@ -0,0 +1,20 @@
+-- Sales replayed from offline devices are accepted even when another counter
+-- sold the stock first. Stock stops at zero and each line that sold more than
+-- was available is queued here for the owner to reconcile.
+
+CREATE TABLE sale_conflicts (
+    id SERIAL PRIMARY KEY,
+    pharmacy_id INTEGER NOT NULL REFERENCES pharmacies(id),
+    sale_id INTEGER NOT NULL REFERENCES sales(id),
+    sale_item_id INTEGER NOT NULL REFERENCES sale_items(id),
+    inventory_id INTEGER NOT NULL REFERENCES inventory(id),
+    requested INTEGER NOT NULL,
+    available INTEGER NOT NULL,
+    resolution TEXT,
+    resolved_by INTEGER REFERENCES users(id),
+    resolved_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (requested > available)
+);
+
+CREATE INDEX sale_conflicts_open_idx ON sale_conflicts (pharmacy_id, created_at) WHERE resolved_at IS NULL;
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS inventory_bump_version ON inventory;
+DROP FUNCTION IF EXISTS inventory_bump_version();
+
+ALTER TABLE inventory DROP COLUMN version;
@@ -0,0 +1,14 @@
+-- Every change to an inventory row, including stock taken by sales, bumps
+-- its version. The API exposes it as an ETag so edits made from a stale copy
+-- are rejected instead of silently restoring sold units.
+
+ALTER TABLE inventory ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+CREATE FUNCTION inventory_bump_version() RETURNS trigger AS $$
+BEGIN
+    NEW.version := OLD.version + 1;
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER inventory_bump_version BEFORE UPDATE ON inventory FOR EACH ROW EXECUTE FUNCTION inventory_bump_version();
@@ -0,0 +1,2 @@
+ALTER TABLE inventory DROP CONSTRAINT IF EXISTS inventory_quantity_check;
+DROP TABLE IF EXISTS inventory_quantity_resets;
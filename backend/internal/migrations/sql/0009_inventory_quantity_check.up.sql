@@ -0,0 +1,30 @@
+-- Sales now lock the inventory rows they sell from, so stock can no longer be
+-- oversold by concurrent counters. Rows already driven negative by the old
+-- unlocked check are reset to zero before the constraint is added; the
+-- quantities they held are kept in inventory_quantity_resets so the oversold
+-- stock can still be counted and written off.
+
+CREATE TABLE inventory_quantity_resets (
+    inventory_id INTEGER PRIMARY KEY REFERENCES inventory(id),
+    pharmacy_id INTEGER NOT NULL REFERENCES pharmacies(id),
+    quantity INTEGER NOT NULL CHECK (quantity < 0),
+    reset_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO inventory_quantity_resets (inventory_id, pharmacy_id, quantity)
+SELECT id, pharmacy_id, quantity FROM inventory WHERE quantity < 0;
+
+DO $$
+DECLARE
+    reset RECORD;
+BEGIN
+    FOR reset IN SELECT inventory_id, pharmacy_id, quantity FROM inventory_quantity_resets ORDER BY inventory_id LOOP
+        RAISE NOTICE 'resetting oversold inventory % of pharmacy % from % to 0',
+            reset.inventory_id, reset.pharmacy_id, reset.quantity;
+    END LOOP;
+END
+$$;
+
+UPDATE inventory SET quantity = 0 WHERE quantity < 0;
+
+ALTER TABLE inventory ADD CONSTRAINT inventory_quantity_check CHECK (quantity >= 0);
@@ -0,0 +1,6 @@
+ALTER TABLE medicines
+    DROP COLUMN package_size,
+    DROP COLUMN package_container,
+    DROP COLUMN strength,
+    DROP COLUMN dosage_form,
+    DROP COLUMN slug;
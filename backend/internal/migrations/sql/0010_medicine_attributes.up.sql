@@ -0,0 +1,11 @@
+-- Catalog attributes that tell brands apart: "Napa" the 500 mg tablet and
+-- "Napa" the syrup share a brand name. The columns stay NULL until the
+-- catalog CSV is reloaded with `medeasy seed medicines`; the server only
+-- seeds an empty catalog.
+
+ALTER TABLE medicines
+    ADD COLUMN slug TEXT,
+    ADD COLUMN dosage_form TEXT,
+    ADD COLUMN strength TEXT,
+    ADD COLUMN package_container TEXT,
+    ADD COLUMN package_size TEXT;
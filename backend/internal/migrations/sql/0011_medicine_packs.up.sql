@@ -0,0 +1,13 @@
+-- Priced packs parsed from the catalog's package text, e.g. "10's pack:
+-- ৳ 60.00". mrp is the regulated retail price of the whole pack. The table is
+-- filled when the catalog CSV is reloaded with `medeasy seed medicines`; the
+-- server only seeds an empty catalog.
+
+CREATE TABLE medicine_packs (
+    id SERIAL PRIMARY KEY,
+    medicine_id INTEGER NOT NULL REFERENCES medicines(id) ON DELETE CASCADE,
+    description TEXT NOT NULL,
+    units INTEGER NOT NULL CHECK (units > 0),
+    mrp NUMERIC(12,2) NOT NULL CHECK (mrp >= 0),
+    UNIQUE (medicine_id, description, units)
+);
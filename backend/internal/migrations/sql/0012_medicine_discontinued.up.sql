@@ -0,0 +1,4 @@
+-- Brands dropped from the catalog are flagged rather than deleted, so the
+-- inventory and sales that reference them keep resolving.
+
+ALTER TABLE medicines ADD COLUMN discontinued_at TIMESTAMPTZ;
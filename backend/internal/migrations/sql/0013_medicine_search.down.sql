@@ -0,0 +1,2 @@
+DROP INDEX medicines_brand_prefix_idx;
+ALTER TABLE medicines DROP COLUMN search_text;
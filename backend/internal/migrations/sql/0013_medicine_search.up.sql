@@ -0,0 +1,13 @@
+-- Typo-tolerant catalog search. search_text gathers the searchable columns
+-- in lower case and is indexed by trigrams, which serves both substring
+-- (LIKE) and similarity (<%) matches. pg_trgm ships with PostgreSQL; creating
+-- it needs a role that may create extensions in this database.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE medicines ADD COLUMN search_text TEXT GENERATED ALWAYS AS (
+    lower(brand_name || ' ' || COALESCE(generic_name, '') || ' ' || COALESCE(manufacturer, '') || ' ' || COALESCE(strength, ''))
+) STORED;
+
+CREATE INDEX medicines_search_trgm_idx ON medicines USING gin (search_text gin_trgm_ops);
+CREATE INDEX medicines_brand_prefix_idx ON medicines (lower(brand_name) text_pattern_ops);
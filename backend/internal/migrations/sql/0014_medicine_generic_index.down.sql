@@ -0,0 +1 @@
+DROP INDEX medicines_generic_idx;
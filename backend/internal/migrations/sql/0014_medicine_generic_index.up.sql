@@ -0,0 +1,3 @@
+-- Substitute lookups match brands on generic name, strength and dosage form.
+
+CREATE INDEX medicines_generic_idx ON medicines (lower(generic_name));
@@ -0,0 +1,3 @@
+DROP INDEX inventory_lot_idx;
+ALTER TABLE inventory DROP COLUMN batch_number;
+DROP TABLE medicine_barcodes;
@@ -0,0 +1,19 @@
+-- Barcodes of catalog medicines, keyed by GTIN padded to 14 digits so EAN-13,
+-- UPC-A and GS1 DataMatrix scans of the same pack match. pack_size is set
+-- when the code identifies a pack of that many units rather than the
+-- medicine in general. Inventory lots remember the batch they were received
+-- from so a DataMatrix scan can find them.
+
+CREATE TABLE medicine_barcodes (
+    id SERIAL PRIMARY KEY,
+    medicine_id INTEGER NOT NULL REFERENCES medicines(id) ON DELETE CASCADE,
+    gtin CHAR(14) NOT NULL UNIQUE,
+    pack_size INTEGER CHECK (pack_size > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX medicine_barcodes_medicine_idx ON medicine_barcodes (medicine_id);
+
+ALTER TABLE inventory ADD COLUMN batch_number TEXT;
+
+CREATE INDEX inventory_lot_idx ON inventory (pharmacy_id, medicine_id, batch_number);
@@ -0,0 +1 @@
+DROP TABLE catalog_submissions;
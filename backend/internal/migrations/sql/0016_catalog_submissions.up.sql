@@ -0,0 +1,26 @@
+-- Owners propose custom inventory medicines for the shared catalog. A
+-- submission is merged into an existing catalog medicine by the owner, or
+-- added to the catalog as a new medicine by the server operator; either way
+-- the pharmacy's custom items and the sale items that sold them are relinked
+-- to the catalog medicine.
+
+CREATE TABLE catalog_submissions (
+    id SERIAL PRIMARY KEY,
+    pharmacy_id INTEGER NOT NULL REFERENCES pharmacies(id),
+    inventory_id INTEGER NOT NULL REFERENCES inventory(id),
+    brand_name TEXT NOT NULL,
+    generic_name TEXT NOT NULL DEFAULT '',
+    manufacturer TEXT NOT NULL DEFAULT '',
+    type TEXT NOT NULL DEFAULT '',
+    strength TEXT NOT NULL DEFAULT '',
+    dosage_form TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'merged', 'added', 'rejected')),
+    medicine_id INTEGER REFERENCES medicines(id),
+    note TEXT,
+    submitted_by INTEGER REFERENCES users(id),
+    reviewed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX catalog_submissions_pending_idx ON catalog_submissions (inventory_id) WHERE status = 'pending';
+CREATE INDEX catalog_submissions_pharmacy_idx ON catalog_submissions (pharmacy_id, created_at);
@@ -0,0 +1,2 @@
+DROP TABLE sale_interactions;
+DROP TABLE drug_interactions;
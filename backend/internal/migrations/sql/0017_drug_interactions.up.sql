@@ -0,0 +1,31 @@
+-- Interaction knowledge base, loaded from INTERACTIONS_CSV. Ingredients are
+-- stored lower case with ingredient_a < ingredient_b, so each pair is listed
+-- once.
+
+CREATE TABLE drug_interactions (
+    id SERIAL PRIMARY KEY,
+    ingredient_a TEXT NOT NULL,
+    ingredient_b TEXT NOT NULL,
+    severity TEXT NOT NULL CHECK (severity IN ('minor', 'moderate', 'severe')),
+    description TEXT NOT NULL DEFAULT '',
+    CHECK (ingredient_a < ingredient_b),
+    UNIQUE (ingredient_a, ingredient_b)
+);
+
+CREATE INDEX drug_interactions_b_idx ON drug_interactions (ingredient_b);
+
+-- Severe interactions dispensed in a sale, with the pharmacist who approved
+-- them. Offline sales were made before the server could check, so their
+-- interactions are recorded without an override.
+CREATE TABLE sale_interactions (
+    id SERIAL PRIMARY KEY,
+    sale_id INTEGER NOT NULL REFERENCES sales(id) ON DELETE CASCADE,
+    ingredient_a TEXT NOT NULL,
+    ingredient_b TEXT NOT NULL,
+    severity TEXT NOT NULL,
+    pharmacist TEXT,
+    reason TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX sale_interactions_sale_idx ON sale_interactions (sale_id);
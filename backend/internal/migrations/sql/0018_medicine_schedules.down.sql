@@ -0,0 +1,2 @@
+DROP TABLE sale_prescriptions;
+ALTER TABLE medicines DROP COLUMN schedule;
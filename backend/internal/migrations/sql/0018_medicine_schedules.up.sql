@@ -0,0 +1,17 @@
+-- Dispensing schedule of each medicine, classified by ingredient from
+-- SCHEDULES_CSV. Prescription-only and controlled medicines are only sold
+-- against a prescription, whose details are kept with the sale; sales of
+-- controlled medicines make up the dispensing register.
+
+ALTER TABLE medicines ADD COLUMN schedule TEXT NOT NULL DEFAULT 'otc'
+    CHECK (schedule IN ('otc', 'prescription', 'controlled'));
+
+CREATE TABLE sale_prescriptions (
+    sale_id INTEGER PRIMARY KEY REFERENCES sales(id) ON DELETE CASCADE,
+    prescriber TEXT NOT NULL,
+    registration_number TEXT NOT NULL,
+    patient_name TEXT NOT NULL,
+    patient_address TEXT,
+    prescribed_on DATE NOT NULL,
+    image_ref TEXT
+);
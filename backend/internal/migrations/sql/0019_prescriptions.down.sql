@@ -0,0 +1,4 @@
+ALTER TABLE sale_prescriptions DROP COLUMN prescription_id;
+DROP TABLE prescription_fills;
+DROP TABLE prescription_items;
+DROP TABLE prescriptions;
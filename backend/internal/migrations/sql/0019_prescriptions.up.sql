@@ -0,0 +1,51 @@
+-- Prescriptions kept as records so refills are dispensed against the stored
+-- prescription instead of re-entering it. Each item authorises quantity
+-- units per fill and refills further fills; dispensed counts the units sold
+-- against it so far, one prescription_fills row per sale line.
+
+CREATE TABLE prescriptions (
+    id SERIAL PRIMARY KEY,
+    pharmacy_id INTEGER NOT NULL REFERENCES pharmacies(id),
+    patient_name TEXT NOT NULL,
+    -- patient_phone holds digits only so lookups ignore formatting.
+    patient_phone TEXT NOT NULL DEFAULT '',
+    patient_address TEXT NOT NULL DEFAULT '',
+    prescriber TEXT NOT NULL,
+    registration_number TEXT NOT NULL,
+    prescribed_on DATE NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    -- scan_key names the uploaded scan in the attachment store.
+    scan_key TEXT,
+    created_by INTEGER REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX prescriptions_patient_phone_idx ON prescriptions (pharmacy_id, patient_phone) WHERE patient_phone <> '';
+CREATE INDEX prescriptions_patient_name_trgm_idx ON prescriptions USING gin (lower(patient_name) gin_trgm_ops);
+
+CREATE TABLE prescription_items (
+    id SERIAL PRIMARY KEY,
+    prescription_id INTEGER NOT NULL REFERENCES prescriptions(id) ON DELETE CASCADE,
+    medicine_id INTEGER REFERENCES medicines(id),
+    -- description is the medicine as written on the prescription.
+    description TEXT NOT NULL,
+    dosage TEXT NOT NULL DEFAULT '',
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    refills INTEGER NOT NULL DEFAULT 0 CHECK (refills >= 0),
+    dispensed INTEGER NOT NULL DEFAULT 0 CHECK (dispensed >= 0 AND dispensed <= quantity * (refills + 1))
+);
+
+CREATE INDEX prescription_items_prescription_id_idx ON prescription_items (prescription_id);
+
+CREATE TABLE prescription_fills (
+    id SERIAL PRIMARY KEY,
+    prescription_item_id INTEGER NOT NULL REFERENCES prescription_items(id) ON DELETE CASCADE,
+    sale_id INTEGER NOT NULL REFERENCES sales(id) ON DELETE CASCADE,
+    sale_item_id INTEGER NOT NULL REFERENCES sale_items(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX prescription_fills_item_idx ON prescription_fills (prescription_item_id);
+
+ALTER TABLE sale_prescriptions ADD COLUMN prescription_id INTEGER REFERENCES prescriptions(id);
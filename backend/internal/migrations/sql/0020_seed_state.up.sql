@@ -0,0 +1,11 @@
+-- Seeds loaded into the database, so the server loads each one on first
+-- boot only instead of inferring from the data whether it is needed.
+-- Backfills after upgrades are run explicitly with `medeasy seed`.
+
+CREATE TABLE seed_state (
+    name TEXT PRIMARY KEY,
+    source TEXT NOT NULL DEFAULT '',
+    loaded_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO seed_state (name) SELECT 'medicines' WHERE EXISTS (SELECT 1 FROM medicines WHERE brand_id IS NOT NULL);
@@ -0,0 +1 @@
+DELETE FROM seed_state WHERE name = 'schedules';
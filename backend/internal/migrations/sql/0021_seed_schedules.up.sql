@@ -0,0 +1,5 @@
+-- Records the schedule classification of catalogs classified before seed
+-- state was kept, so they are not reclassified on boot.
+
+INSERT INTO seed_state (name) SELECT 'schedules' WHERE EXISTS (SELECT 1 FROM medicines WHERE schedule <> 'otc')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,4 @@
+DELETE FROM sale_conflicts WHERE kind = 'prescription';
+ALTER TABLE sale_conflicts DROP COLUMN kind;
+ALTER TABLE sale_items DROP COLUMN schedule;
+DROP TABLE ingredient_schedules;
@@ -0,0 +1,19 @@
+-- Schedules by ingredient from SCHEDULES_CSV, kept so custom medicines are
+-- classified by their generic name when they are sold.
+CREATE TABLE ingredient_schedules (
+    ingredient TEXT PRIMARY KEY,
+    schedule TEXT NOT NULL CHECK (schedule IN ('otc', 'prescription', 'controlled'))
+);
+
+-- The schedules are applied again on the next start to fill the table.
+DELETE FROM seed_state WHERE name = 'schedules';
+
+-- The schedule each line was sold under, which decides whether it belongs
+-- in the controlled drug register. NULL for lines sold before it was kept.
+ALTER TABLE sale_items ADD COLUMN schedule TEXT CHECK (schedule IN ('otc', 'prescription', 'controlled'));
+
+-- Offline sales of prescription-only and controlled medicines that no
+-- prescription covers are accepted, since they were already made, and the
+-- uncovered units are queued with the stock conflicts for the owner. For
+-- these, available is the units the prescription did cover.
+ALTER TABLE sale_conflicts ADD COLUMN kind TEXT NOT NULL DEFAULT 'stock' CHECK (kind IN ('stock', 'prescription'));
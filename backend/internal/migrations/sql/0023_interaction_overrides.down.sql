@@ -0,0 +1 @@
+ALTER TABLE sale_interactions DROP COLUMN overridden_by;
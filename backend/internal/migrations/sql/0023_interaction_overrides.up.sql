@@ -0,0 +1,5 @@
+-- Severe interactions are overridden by the signed-in owner or pharmacist,
+-- recorded by user id. pharmacist keeps the names typed in for earlier
+-- overrides.
+
+ALTER TABLE sale_interactions ADD COLUMN overridden_by INTEGER REFERENCES users(id);
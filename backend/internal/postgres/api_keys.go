@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"medeasy/m/domain"
+)
+
+const apiKeyColumns = `id, pharmacy_id, name, prefix, secret_hash, scopes, created_by, created_at, last_used_at, expires_at, revoked_at`
+
+type apiKeyRepository struct {
+	db *sqlx.DB
+}
+
+func (r *apiKeyRepository) ByPrefix(ctx context.Context, prefix string) (domain.APIKey, error) {
+	var key domain.APIKey
+	err := r.db.GetContext(ctx, &key, `SELECT `+apiKeyColumns+` FROM api_keys WHERE prefix = $1`, prefix)
+	return key, translate(err)
+}
+
+// TouchLastUsed only writes once a minute so hot keys do not write on every request.
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = NOW() WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`, id)
+	return err
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	return r.db.QueryRowxContext(ctx, `INSERT INTO api_keys (pharmacy_id, name, prefix, secret_hash, scopes, created_by, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`,
+		key.PharmacyID, key.Name, key.Prefix, key.SecretHash, key.Scopes, key.CreatedBy, key.ExpiresAt).Scan(&key.ID, &key.CreatedAt)
+}
+
+func (r *apiKeyRepository) List(ctx context.Context, pharmacyID int64) ([]domain.APIKey, error) {
+	keys := []domain.APIKey{}
+	err := r.db.SelectContext(ctx, &keys, `SELECT `+apiKeyColumns+` FROM api_keys WHERE pharmacy_id = $1 ORDER BY created_at DESC`, pharmacyID)
+	return keys, err
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id, pharmacyID int64) (time.Time, error) {
+	var revokedAt time.Time
+	err := r.db.QueryRowxContext(ctx, `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = $1 AND pharmacy_id = $2 RETURNING revoked_at`, id, pharmacyID).Scan(&revokedAt)
+	return revokedAt, translate(err)
+}
@@ -0,0 +1,29 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"medeasy/m/domain"
+)
+
+type interactionRepository struct {
+	db *sqlx.DB
+}
+
+func (r *interactionRepository) Among(ctx context.Context, ingredients []string) ([]domain.DrugInteraction, error) {
+	interactions := []domain.DrugInteraction{}
+	if len(ingredients) < 2 {
+		return interactions, nil
+	}
+	query, args, err := sqlx.In(`SELECT ingredient_a, ingredient_b, severity, description
+		FROM drug_interactions
+		WHERE ingredient_a IN (?) AND ingredient_b IN (?)
+		ORDER BY ingredient_a, ingredient_b`, ingredients, ingredients)
+	if err != nil {
+		return nil, err
+	}
+	err = r.db.SelectContext(ctx, &interactions, r.db.Rebind(query), args...)
+	return interactions, err
+}
@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"medeasy/m/domain"
+)
+
+// inventoryColumns matches domain.InventoryItem.
+const inventoryColumns = `id, pharmacy_id, medicine_id, brand_name, generic_name, manufacturer, type, quantity, cost_price, sale_price, pack_size, pack_cost_price, pack_sale_price, expiry_date, batch_number, created_at, updated_at, version`
+
+type inventoryRepository struct {
+	db *sqlx.DB
+}
+
+func (r *inventoryRepository) Search(ctx context.Context, pharmacyID int64, query string, limit int) ([]domain.InventorySearchResult, error) {
+	args := []any{pharmacyID, limit}
+	sqlQuery := `SELECT i.id AS inventory_id, i.medicine_id, i.quantity, i.pack_size, i.cost_price, i.sale_price, i.expiry_date, i.batch_number,
+	             COALESCE(i.brand_name, m.brand_name, 'Unknown') as brand_name,
+	             COALESCE(i.generic_name, m.generic_name, '') as generic_name,
+	             COALESCE(i.manufacturer, m.manufacturer, '') as manufacturer,
+	             COALESCE(i.type, m.type, '') as type,
+	             COALESCE(m.slug, '') AS slug,
+	             COALESCE(m.dosage_form, '') AS dosage_form,
+	             COALESCE(m.strength, '') AS strength,
+	             COALESCE(m.package_container, '') AS package_container,
+	             COALESCE(m.package_size, '') AS package_size,
+	             COALESCE(m.schedule, 'otc') AS schedule,
+	             ROUND(i.pack_cost_price * i.quantity / i.pack_size, 2) AS total_cost
+                FROM inventory i
+                LEFT JOIN medicines m ON m.id = i.medicine_id
+                WHERE i.pharmacy_id = $1 AND i.quantity > 0`
+	if query != "" {
+		args = append(args, "%"+query+"%")
+		sqlQuery += " AND (COALESCE(i.brand_name, m.brand_name) ILIKE $3 OR COALESCE(i.generic_name, m.generic_name) ILIKE $3)"
+	}
+	sqlQuery += " ORDER BY brand_name, dosage_form, strength LIMIT $2"
+
+	var results []domain.InventorySearchResult
+	err := r.db.SelectContext(ctx, &results, sqlQuery, args...)
+	return results, err
+}
+
+func (r *inventoryRepository) ByID(ctx context.Context, id int64) (domain.InventoryItem, error) {
+	var item domain.InventoryItem
+	err := r.db.GetContext(ctx, &item, `SELECT `+inventoryColumns+` FROM inventory WHERE id = $1`, id)
+	return item, translate(err)
+}
+
+func (r *inventoryRepository) Create(ctx context.Context, item *domain.InventoryItem) error {
+	return r.db.QueryRowxContext(ctx, `INSERT INTO inventory (pharmacy_id, medicine_id, brand_name, generic_name, manufacturer, type, quantity, cost_price, sale_price, pack_size, pack_cost_price, pack_sale_price, expiry_date, batch_number)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id, created_at, updated_at, version`,
+		item.PharmacyID, item.MedicineID, item.BrandName, item.GenericName, item.Manufacturer, item.Type,
+		item.Quantity, item.CostPrice, item.SalePrice, item.PackSize, item.PackCostPrice, item.PackSalePrice, item.ExpiryDate, item.BatchNumber).Scan(&item.ID, &item.CreatedAt, &item.UpdatedAt, &item.Version)
+}
+
+// versionMatches is true when the version parameter is AnyVersion (-1) or the
+// current version.
+func versionMatches(param string) string {
+	return `(` + param + `::bigint < 0 OR version = ` + param + `)`
+}
+
+func (r *inventoryRepository) UpdatePricing(ctx context.Context, item domain.InventoryItem, version int64) (domain.InventoryItem, error) {
+	var updated domain.InventoryItem
+	err := r.db.GetContext(ctx, &updated, `UPDATE inventory SET quantity = $1, cost_price = $2, sale_price = $3, pack_size = $4, pack_cost_price = $5, pack_sale_price = $6, expiry_date = $7, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $8 AND `+versionMatches("$9")+` RETURNING `+inventoryColumns,
+		item.Quantity, item.CostPrice, item.SalePrice, item.PackSize, item.PackCostPrice, item.PackSalePrice, item.ExpiryDate, item.ID, version)
+	return updated, translate(err)
+}
+
+func (r *inventoryRepository) SetQuantity(ctx context.Context, id, quantity, version int64) (domain.InventoryItem, error) {
+	var updated domain.InventoryItem
+	err := r.db.GetContext(ctx, &updated, `UPDATE inventory SET quantity = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND `+versionMatches("$3")+` RETURNING `+inventoryColumns, quantity, id, version)
+	return updated, translate(err)
+}
+
+func (r *inventoryRepository) AdjustQuantity(ctx context.Context, id, delta, version int64) (domain.InventoryItem, error) {
+	var updated domain.InventoryItem
+	err := r.db.GetContext(ctx, &updated, `UPDATE inventory SET quantity = quantity + $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND quantity + $1 >= 0 AND `+versionMatches("$3")+` RETURNING `+inventoryColumns, delta, id, version)
+	return updated, translate(err)
+}
+
+func (r *inventoryRepository) ExpiringWithin(ctx context.Context, pharmacyID int64, days int) ([]domain.ExpiryAlert, error) {
+	var items []domain.ExpiryAlert
+	err := r.db.SelectContext(ctx, &items, `SELECT i.id, COALESCE(i.brand_name, m.brand_name, 'Unknown') AS brand_name, i.quantity, i.expiry_date
+              FROM inventory i
+              LEFT JOIN medicines m ON m.id = i.medicine_id
+              WHERE i.pharmacy_id = $1
+              AND i.quantity > 0
+              AND i.expiry_date IS NOT NULL
+              AND i.expiry_date <= (CURRENT_DATE + ($2 * INTERVAL '1 day'))
+              ORDER BY i.expiry_date ASC`, pharmacyID, days)
+	return items, err
+}
+
+func (r *inventoryRepository) Equivalents(ctx context.Context, pharmacyID, medicineID int64) ([]domain.Substitute, error) {
+	var substitutes []domain.Substitute
+	err := r.db.SelectContext(ctx, &substitutes, `WITH target AS (
+	            SELECT lower(generic_name) AS t_generic, lower(COALESCE(strength, '')) AS t_strength,
+	                   lower(COALESCE(dosage_form, '')) AS t_form
+	              FROM medicines WHERE id = $2 AND COALESCE(generic_name, '') <> '')
+	          SELECT `+medicineColumns+`,
+	                 COALESCE(s.in_stock, 0) AS in_stock, s.unit_price,
+	                 (SELECT ROUND(MIN(p.mrp / p.units), 2) FROM medicine_packs p WHERE p.medicine_id = m.id) AS unit_mrp
+	            FROM medicines m
+	            JOIN target t ON lower(m.generic_name) = t.t_generic
+	                         AND lower(COALESCE(m.strength, '')) = t.t_strength
+	                         AND lower(COALESCE(m.dosage_form, '')) = t.t_form
+	            LEFT JOIN (SELECT medicine_id, SUM(quantity) AS in_stock, MIN(sale_price) AS unit_price
+	                         FROM inventory
+	                        WHERE pharmacy_id = $1 AND quantity > 0
+	                        GROUP BY medicine_id) s ON s.medicine_id = m.id
+	           WHERE m.id = $2 OR m.discontinued_at IS NULL`, pharmacyID, medicineID)
+	return substitutes, err
+}
+
+func (r *inventoryRepository) Lots(ctx context.Context, pharmacyID, medicineID int64) ([]domain.InventoryItem, error) {
+	lots := []domain.InventoryItem{}
+	err := r.db.SelectContext(ctx, &lots, `SELECT `+inventoryColumns+` FROM inventory
+		WHERE pharmacy_id = $1 AND medicine_id = $2 AND quantity > 0
+		ORDER BY expiry_date NULLS LAST, id`, pharmacyID, medicineID)
+	return lots, err
+}
@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"medeasy/m/domain"
+	"medeasy/m/internal/service"
+)
+
+func TestAdjustStock(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	item := f.stock(t, "Alatrol", 10, domain.Taka(30), 10)
+
+	updated, err := f.services.Inventory.AdjustStock(ctx, f.pharmacyID, item.ID, -3, item.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Quantity != 7 || updated.Version <= item.Version {
+		t.Errorf("updated = %+v, want 7 units at a new version", updated)
+	}
+	if _, err := f.services.Inventory.AdjustStock(ctx, f.pharmacyID, item.ID, -1, item.Version); kindOf(err) != service.KindPreconditionFailed {
+		t.Errorf("stale version: got %v, want precondition failed", err)
+	}
+	if _, err := f.services.Inventory.AdjustStock(ctx, f.pharmacyID, item.ID, -8, updated.Version); kindOf(err) != service.KindInvalid {
+		t.Errorf("below zero: got %v, want invalid", err)
+	}
+	if got := f.quantity(t, item.ID); got != 7 {
+		t.Errorf("quantity = %d, want 7", got)
+	}
+}
+
+func TestInventoryIsPerPharmacy(t *testing.T) {
+	f, other := newFixture(t), newFixture(t)
+	ctx := context.Background()
+	item := f.stock(t, "Tusca", 4, domain.Taka(60), 1)
+
+	if _, err := other.services.Inventory.Get(ctx, other.pharmacyID, item.ID); kindOf(err) != service.KindForbidden {
+		t.Errorf("another pharmacy's item: got %v, want forbidden", err)
+	}
+	if _, err := other.services.Inventory.SetStock(ctx, other.pharmacyID, item.ID, 0, service.AnyVersion); kindOf(err) != service.KindForbidden {
+		t.Errorf("setting another pharmacy's stock: got %v, want forbidden", err)
+	}
+	if got := f.quantity(t, item.ID); got != 4 {
+		t.Errorf("quantity = %d, want 4", got)
+	}
+}
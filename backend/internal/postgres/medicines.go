@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"medeasy/m/domain"
+)
+
+// medicineColumns matches domain.Medicine. Attributes are NULL until the
+// catalog CSV has been reloaded after the upgrade that added them.
+const medicineColumns = `id, brand_id, brand_name, type, generic_name, manufacturer,
+	COALESCE(slug, '') AS slug, COALESCE(dosage_form, '') AS dosage_form, COALESCE(strength, '') AS strength,
+	COALESCE(package_container, '') AS package_container, COALESCE(package_size, '') AS package_size, discontinued_at, schedule`
+
+type medicineRepository struct {
+	db *sqlx.DB
+}
+
+func (r *medicineRepository) ByID(ctx context.Context, id int64) (domain.Medicine, error) {
+	var medicine domain.Medicine
+	err := r.db.GetContext(ctx, &medicine, `SELECT `+medicineColumns+` FROM medicines WHERE id = $1`, id)
+	return medicine, translate(err)
+}
+
+func (r *medicineRepository) Search(ctx context.Context, query string, afterScore float64, afterID int64, limit int) ([]domain.RankedMedicine, error) {
+	medicines := []domain.RankedMedicine{}
+	sql, args := searchQuery(query, afterScore, afterID, limit)
+	err := r.db.SelectContext(ctx, &medicines, sql, args...)
+	return medicines, err
+}
+
+// searchQuery builds the catalog search. Every word must appear in
+// search_text, or resemble one of its words closely enough to be a typo.
+// Brands, then generics, starting with the query score highest; the rest
+// by similarity. Without a query every medicine scores zero, so the catalog
+// is listed by id. Pages continue after the result with afterScore and
+// afterID.
+func searchQuery(query string, afterScore float64, afterID int64, limit int) (string, []any) {
+	args := []any{afterScore, afterID, limit}
+	score := "0"
+	var where strings.Builder
+	if words := strings.Fields(strings.ToLower(query)); len(words) > 0 {
+		phrase := strings.Join(words, " ")
+		args = append(args, phrase, likeEscape(phrase)+"%")
+		// Starting with the query outweighs any similarity, which is at most 1.
+		score = `4 * COALESCE(lower(brand_name) LIKE $5, false)::int + 2 * COALESCE(lower(generic_name) LIKE $5, false)::int
+			+ word_similarity($4, search_text)`
+		for _, word := range words {
+			args = append(args, "%"+likeEscape(word)+"%", word)
+			fmt.Fprintf(&where, " AND (search_text LIKE $%d OR $%d <%% search_text)", len(args)-1, len(args))
+		}
+	}
+	return `SELECT * FROM (
+			SELECT ` + medicineColumns + `, (` + score + `)::float8 AS score FROM medicines
+			WHERE discontinued_at IS NULL` + where.String() + `
+		) ranked
+		WHERE $2::bigint = 0 OR score < $1::float8 OR (score = $1::float8 AND id > $2::bigint)
+		ORDER BY score DESC, id LIMIT $3`, args
+}
+
+// likeEscape quotes LIKE wildcards so they match literally.
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func (r *medicineRepository) Packs(ctx context.Context, medicineID int64) ([]domain.MedicinePack, error) {
+	packs := []domain.MedicinePack{}
+	err := r.db.SelectContext(ctx, &packs, `SELECT id, medicine_id, description, units, mrp FROM medicine_packs
+		WHERE medicine_id = $1 ORDER BY units, mrp, id`, medicineID)
+	return packs, err
+}
+
+func (r *medicineRepository) ByBarcode(ctx context.Context, gtin string) (domain.MedicineBarcode, error) {
+	var barcode domain.MedicineBarcode
+	err := r.db.GetContext(ctx, &barcode, `SELECT id, medicine_id, gtin, pack_size, created_at FROM medicine_barcodes WHERE gtin = $1`, gtin)
+	return barcode, translate(err)
+}
+
+func (r *medicineRepository) AddBarcode(ctx context.Context, barcode *domain.MedicineBarcode) error {
+	err := r.db.QueryRowxContext(ctx, `INSERT INTO medicine_barcodes (medicine_id, gtin, pack_size) VALUES ($1, $2, $3)
+		RETURNING id, created_at`, barcode.MedicineID, barcode.GTIN, barcode.PackSize).Scan(&barcode.ID, &barcode.CreatedAt)
+	return translate(err)
+}
@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchPagesThroughEveryMatch(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	// A made-up generic keeps other tests' medicines out of the results.
+	generic := fmt.Sprintf("zeqtramol%d", time.Now().UnixNano())
+	want := make(map[int64]bool)
+	for i := range 7 {
+		var id int64
+		err := f.db.GetContext(ctx, &id, `INSERT INTO medicines (brand_name, type, generic_name, manufacturer, strength)
+			VALUES ($1, 'allopathic', $2, 'Test Labs', '500 mg') RETURNING id`, fmt.Sprintf("Brand %d", i), generic)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[id] = true
+	}
+
+	seen := make(map[int64]bool)
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > len(want) {
+			t.Fatal("search did not finish paging")
+		}
+		page, err := f.services.Catalog.Search(ctx, generic, cursor, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, medicine := range page.Medicines {
+			if !want[medicine.ID] || seen[medicine.ID] {
+				t.Errorf("unexpected or repeated result %d", medicine.ID)
+			}
+			seen[medicine.ID] = true
+		}
+		if page.Next == "" {
+			break
+		}
+		cursor = page.Next
+	}
+	if len(seen) != len(want) {
+		t.Errorf("found %d of %d medicines", len(seen), len(want))
+	}
+}
+
+// Both ways a search word can match must be served by the trigram index of
+// migration 0013, or the search degrades to scanning the catalog.
+func TestSearchUsesTrigramIndex(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	search, searchArgs := searchQuery("napa extra", 0, 0, 25)
+	tests := []struct {
+		name  string
+		query string
+		args  []any
+	}{
+		{"substring", `SELECT id FROM medicines WHERE search_text LIKE $1`, []any{"%napa%"}},
+		{"similarity", `SELECT id FROM medicines WHERE $1 <% search_text`, []any{"paracitamol"}},
+		{"search", search, searchArgs},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := db.BeginTxx(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tx.Rollback()
+			// A test catalog is small enough for the planner to prefer a
+			// sequential scan, which would hide whether the index applies.
+			if _, err := tx.ExecContext(ctx, `SET LOCAL enable_seqscan = off`); err != nil {
+				t.Fatal(err)
+			}
+			var plan []string
+			if err := tx.SelectContext(ctx, &plan, `EXPLAIN `+tt.query, tt.args...); err != nil {
+				t.Fatal(err)
+			}
+			if text := strings.Join(plan, "\n"); !strings.Contains(text, "medicines_search_trgm_idx") {
+				t.Errorf("plan does not use the trigram index:\n%s", text)
+			}
+		})
+	}
+}
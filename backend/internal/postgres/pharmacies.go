@@ -0,0 +1,30 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"medeasy/m/domain"
+)
+
+type pharmacyRepository struct {
+	db *sqlx.DB
+}
+
+func (r *pharmacyRepository) Create(ctx context.Context, pharmacy *domain.Pharmacy) error {
+	return r.db.QueryRowxContext(ctx, `INSERT INTO pharmacies (name, address, location, owner_id) VALUES ($1, $2, $3, $4) RETURNING id, created_at`,
+		pharmacy.Name, pharmacy.Address, pharmacy.Location, pharmacy.OwnerID).Scan(&pharmacy.ID, &pharmacy.CreatedAt)
+}
+
+func (r *pharmacyRepository) Update(ctx context.Context, pharmacy domain.Pharmacy) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE pharmacies SET name = $1, address = $2, location = $3 WHERE id = $4`,
+		pharmacy.Name, pharmacy.Address, pharmacy.Location, pharmacy.ID)
+	return err
+}
+
+func (r *pharmacyRepository) List(ctx context.Context) ([]domain.Pharmacy, error) {
+	var pharmacies []domain.Pharmacy
+	err := r.db.SelectContext(ctx, &pharmacies, `SELECT id, name, address, location, owner_id, created_at FROM pharmacies`)
+	return pharmacies, err
+}
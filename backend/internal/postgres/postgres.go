@@ -0,0 +1,55 @@
+// Package postgres implements the service repositories on PostgreSQL.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+
+	"medeasy/m/internal/service"
+)
+
+// New returns every repository backed by db.
+func New(db *sqlx.DB) service.Repositories {
+	return service.Repositories{
+		Users:         &userRepository{db: db},
+		Pharmacies:    &pharmacyRepository{db: db},
+		Medicines:     &medicineRepository{db: db},
+		Inventory:     &inventoryRepository{db: db},
+		Sales:         &saleRepository{db: db},
+		Reports:       &reportRepository{db: db},
+		APIKeys:       &apiKeyRepository{db: db},
+		Sync:          &syncRepository{db: db},
+		Submissions:   &submissionRepository{db: db},
+		Interactions:  &interactionRepository{db: db},
+		Prescriptions: &prescriptionRepository{db: db},
+	}
+}
+
+// translate maps driver errors onto the service sentinels.
+func translate(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return service.ErrNotFound
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return service.ErrDuplicate
+	}
+	return err
+}
+
+// inTx runs fn in a transaction that is committed only when fn succeeds.
+func inTx(ctx context.Context, db *sqlx.DB, fn func(tx *sqlx.Tx) error) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
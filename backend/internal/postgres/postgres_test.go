@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"medeasy/m/domain"
+	"medeasy/m/internal/config"
+	"medeasy/m/internal/database"
+	"medeasy/m/internal/migrations"
+	"medeasy/m/internal/service"
+)
+
+// testDSNEnv names a disposable PostgreSQL database for the integration
+// tests, which are skipped when it is unset. The tests migrate it to the
+// latest version and leave their rows behind, each under its own pharmacy.
+const testDSNEnv = "MEDEASY_TEST_DSN"
+
+var (
+	testDBOnce sync.Once
+	testDBConn *sqlx.DB
+	testDBErr  error
+	// fixtures numbers the owners the tests register, keeping emails unique.
+	fixtures atomic.Int64
+)
+
+// testDB returns the migrated test database, skipping the test without one.
+func testDB(t testing.TB) *sqlx.DB {
+	t.Helper()
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+	testDBOnce.Do(func() {
+		cfg := config.Config{DatabaseDSN: dsn, Timezone: "Asia/Dhaka", DB: config.DBConfig{MaxOpenConns: 20, MaxIdleConns: 20}}
+		testDBConn, testDBErr = database.Connect(cfg)
+		if testDBErr == nil {
+			_, testDBErr = migrations.Up(context.Background(), testDBConn)
+		}
+	})
+	if testDBErr != nil {
+		t.Fatal(testDBErr)
+	}
+	return testDBConn
+}
+
+// fixture is a freshly registered owner and their pharmacy, with the
+// services running on the test database.
+type fixture struct {
+	db         *sqlx.DB
+	services   service.Services
+	owner      domain.User
+	pharmacyID int64
+}
+
+func newFixture(t testing.TB) fixture {
+	t.Helper()
+	db := testDB(t)
+	services := service.New(New(db), service.Config{Secret: "integration-test-secret", TokenTTL: time.Hour})
+	email := fmt.Sprintf("owner-%d-%d@example.test", time.Now().UnixNano(), fixtures.Add(1))
+	result, err := services.Auth.Register(context.Background(), service.RegisterInput{
+		Username:     "owner",
+		Email:        email,
+		Password:     "correct horse",
+		Role:         service.RoleOwner,
+		PharmacyName: t.Name(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fixture{db: db, services: services, owner: result.User, pharmacyID: *result.User.PharmacyID}
+}
+
+// stock adds quantity units of a custom medicine sold in packs of packSize
+// at packPrice.
+func (f fixture) stock(t testing.TB, brand string, quantity int64, packPrice domain.Money, packSize int64) domain.InventoryItem {
+	t.Helper()
+	item, _, err := f.services.Inventory.Add(context.Background(), f.pharmacyID, service.InventoryInput{
+		BrandName: brand,
+		Quantity:  quantity,
+		CostPrice: packPrice,
+		SalePrice: packPrice,
+		PackSize:  packSize,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return item
+}
+
+// quantity reads an item's stock level back from the database.
+func (f fixture) quantity(t testing.TB, inventoryID int64) int64 {
+	t.Helper()
+	item, err := f.services.Inventory.Get(context.Background(), f.pharmacyID, inventoryID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return item.Quantity
+}
+
+// kindOf returns the Kind of a service error, or zero for any other error.
+func kindOf(err error) service.Kind {
+	var svcErr *service.Error
+	if errors.As(err, &svcErr) {
+		return svcErr.Kind
+	}
+	return 0
+}
+
+func TestRegisterOwner(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	users := &userRepository{db: f.db}
+	user, err := users.ByEmail(ctx, f.owner.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Role != service.RoleOwner || user.PharmacyID == nil || *user.PharmacyID != f.pharmacyID {
+		t.Errorf("owner = %+v, want an owner of pharmacy %d", user, f.pharmacyID)
+	}
+
+	_, err = f.services.Auth.Register(ctx, service.RegisterInput{
+		Username: "again", Email: f.owner.Email, Password: "correct horse", Role: service.RoleOwner, PharmacyName: "Other",
+	})
+	if kindOf(err) != service.KindConflict {
+		t.Errorf("registering the email twice: got %v, want a conflict", err)
+	}
+	if _, err := users.ByID(ctx, -1); !errors.Is(err, service.ErrNotFound) {
+		t.Errorf("missing user: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	result, err := f.services.Auth.Login(ctx, f.owner.Email, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := f.services.Auth.ParseToken(ctx, result.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.PharmacyID != f.pharmacyID || claims.Role != service.RoleOwner {
+		t.Errorf("claims = %+v", claims)
+	}
+	if _, err := f.services.Auth.Login(ctx, f.owner.Email, "wrong"); kindOf(err) != service.KindUnauthorized {
+		t.Errorf("wrong password: got %v, want unauthorized", err)
+	}
+}
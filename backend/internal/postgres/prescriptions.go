@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"medeasy/m/domain"
+)
+
+const prescriptionColumns = `id, pharmacy_id, patient_name, patient_phone, patient_address, prescriber, registration_number,
+	to_char(prescribed_on, 'YYYY-MM-DD') AS prescribed_on, note, scan_key, scan_key IS NOT NULL AS has_scan, created_by, created_at`
+
+// prescriptionItemColumns derive what is left of each item: the units not
+// yet dispensed, and the fills after the current one they make up.
+const prescriptionItemColumns = `id, prescription_id, medicine_id, description, dosage, quantity, refills, dispensed,
+	quantity * (refills + 1) - dispensed AS remaining,
+	GREATEST(CEIL((quantity * (refills + 1) - dispensed)::numeric / quantity) - 1, 0)::int AS refills_remaining`
+
+type prescriptionRepository struct {
+	db *sqlx.DB
+}
+
+func (r *prescriptionRepository) Create(ctx context.Context, p *domain.Prescription) error {
+	return inTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		err := tx.QueryRowxContext(ctx, `INSERT INTO prescriptions (pharmacy_id, patient_name, patient_phone, patient_address, prescriber, registration_number, prescribed_on, note, created_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at`,
+			p.PharmacyID, p.PatientName, p.PatientPhone, p.PatientAddress, p.Prescriber, p.RegistrationNumber, p.PrescribedOn, p.Note, p.CreatedBy).
+			Scan(&p.ID, &p.CreatedAt)
+		if err != nil {
+			return err
+		}
+		for i := range p.Items {
+			item := &p.Items[i]
+			item.PrescriptionID = p.ID
+			err := tx.QueryRowxContext(ctx, `INSERT INTO prescription_items (prescription_id, medicine_id, description, dosage, quantity, refills)
+				VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+				item.PrescriptionID, item.MedicineID, item.Description, item.Dosage, item.Quantity, item.Refills).Scan(&item.ID)
+			if err != nil {
+				return err
+			}
+			item.Remaining = item.Quantity * (item.Refills + 1)
+			item.RefillsRemaining = item.Refills
+		}
+		return nil
+	})
+}
+
+func (r *prescriptionRepository) ByID(ctx context.Context, pharmacyID, id int64) (domain.Prescription, error) {
+	var p domain.Prescription
+	err := r.db.GetContext(ctx, &p, `SELECT `+prescriptionColumns+` FROM prescriptions WHERE id = $1 AND pharmacy_id = $2`, id, pharmacyID)
+	if err != nil {
+		return p, translate(err)
+	}
+	prescriptions := []domain.Prescription{p}
+	err = withItems(ctx, r.db, prescriptions, "")
+	return prescriptions[0], err
+}
+
+func (r *prescriptionRepository) ByPatient(ctx context.Context, pharmacyID int64, phone, name string, limit int) ([]domain.Prescription, error) {
+	prescriptions := []domain.Prescription{}
+	var err error
+	if phone != "" {
+		err = r.db.SelectContext(ctx, &prescriptions, `SELECT `+prescriptionColumns+` FROM prescriptions
+			WHERE pharmacy_id = $1 AND patient_phone = $2
+			ORDER BY prescribed_on DESC, id DESC LIMIT $3`, pharmacyID, phone, limit)
+	} else {
+		// Names match as a substring, or closely enough to be a misspelling.
+		err = r.db.SelectContext(ctx, &prescriptions, `SELECT `+prescriptionColumns+` FROM prescriptions
+			WHERE pharmacy_id = $1 AND (lower(patient_name) LIKE $2 OR $3 <% lower(patient_name))
+			ORDER BY word_similarity($3, lower(patient_name)) DESC, prescribed_on DESC, id DESC LIMIT $4`,
+			pharmacyID, "%"+likeEscape(name)+"%", name, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return prescriptions, withItems(ctx, r.db, prescriptions, "")
+}
+
+func (r *prescriptionRepository) SetScan(ctx context.Context, pharmacyID, id int64, key string) error {
+	var updated int64
+	err := r.db.GetContext(ctx, &updated, `UPDATE prescriptions SET scan_key = $3 WHERE id = $1 AND pharmacy_id = $2 RETURNING id`, id, pharmacyID, key)
+	return translate(err)
+}
+
+func (t *saleTx) LockPrescription(ctx context.Context, pharmacyID, id int64) (domain.Prescription, error) {
+	var p domain.Prescription
+	err := t.tx.GetContext(ctx, &p, `SELECT `+prescriptionColumns+` FROM prescriptions WHERE id = $1 AND pharmacy_id = $2 FOR UPDATE`, id, pharmacyID)
+	if err != nil {
+		return p, translate(err)
+	}
+	prescriptions := []domain.Prescription{p}
+	err = withItems(ctx, t.tx, prescriptions, " FOR UPDATE")
+	return prescriptions[0], err
+}
+
+func (t *saleTx) RecordFill(ctx context.Context, fill *domain.PrescriptionFill) error {
+	if _, err := t.tx.ExecContext(ctx, `UPDATE prescription_items SET dispensed = dispensed + $1 WHERE id = $2`,
+		fill.Quantity, fill.PrescriptionItemID); err != nil {
+		return err
+	}
+	return t.tx.QueryRowxContext(ctx, `INSERT INTO prescription_fills (prescription_item_id, sale_id, sale_item_id, quantity)
+		VALUES ($1, $2, $3, $4) RETURNING id, created_at`,
+		fill.PrescriptionItemID, fill.SaleID, fill.SaleItemID, fill.Quantity).Scan(&fill.ID, &fill.CreatedAt)
+}
+
+func (t *saleTx) Fills(ctx context.Context, saleID int64) ([]domain.PrescriptionFill, error) {
+	var fills []domain.PrescriptionFill
+	err := t.tx.SelectContext(ctx, &fills, `SELECT id, prescription_item_id, sale_id, sale_item_id, quantity, created_at
+		FROM prescription_fills WHERE sale_id = $1 ORDER BY id`, saleID)
+	return fills, err
+}
+
+// withItems loads the items of prescriptions in the order they were
+// prescribed, appending lock to the query.
+func withItems(ctx context.Context, q sqlx.ExtContext, prescriptions []domain.Prescription, lock string) error {
+	if len(prescriptions) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(prescriptions))
+	byID := make(map[int64]*domain.Prescription, len(prescriptions))
+	for i := range prescriptions {
+		ids[i] = prescriptions[i].ID
+		prescriptions[i].Items = []domain.PrescriptionItem{}
+		byID[prescriptions[i].ID] = &prescriptions[i]
+	}
+	query, args, err := sqlx.In(`SELECT `+prescriptionItemColumns+` FROM prescription_items
+		WHERE prescription_id IN (?) ORDER BY id`+lock, ids)
+	if err != nil {
+		return err
+	}
+	var items []domain.PrescriptionItem
+	if err := sqlx.SelectContext(ctx, q, &items, q.Rebind(query), args...); err != nil {
+		return err
+	}
+	for _, item := range items {
+		p := byID[item.PrescriptionID]
+		p.Items = append(p.Items, item)
+	}
+	return nil
+}
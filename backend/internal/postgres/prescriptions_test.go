@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"medeasy/m/internal/service"
+)
+
+func TestFindPrescriptions(t *testing.T) {
+	f, other := newFixture(t), newFixture(t)
+	ctx := context.Background()
+
+	created, err := f.services.Prescriptions.Create(ctx, f.pharmacyID, int64(f.owner.ID), service.PrescriptionInput{
+		PatientName:        "Rahima Khatun",
+		PatientPhone:       "+880 1711-000000",
+		Prescriber:         "Dr. Anwar Hossain",
+		RegistrationNumber: "A-12345",
+		PrescribedOn:       "2024-01-15",
+		Items:              []service.PrescriptionItemInput{{Description: "Amoxicillin 500 mg", Quantity: 21, Refills: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created.Items) != 1 || created.Items[0].Remaining != 42 || created.Items[0].RefillsRemaining != 1 {
+		t.Errorf("created items = %+v", created.Items)
+	}
+
+	for _, patient := range []string{"8801711000000", "rahima", "KHATUN"} {
+		found, err := f.services.Prescriptions.Find(ctx, f.pharmacyID, patient)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(found) != 1 || found[0].ID != created.ID || len(found[0].Items) != 1 {
+			t.Errorf("Find(%q) = %+v, want prescription %d", patient, found, created.ID)
+		}
+	}
+
+	found, err := other.services.Prescriptions.Find(ctx, other.pharmacyID, "rahima")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 0 {
+		t.Errorf("another pharmacy found %+v", found)
+	}
+	if _, err := other.services.Prescriptions.Get(ctx, other.pharmacyID, created.ID); kindOf(err) != service.KindNotFound {
+		t.Errorf("another pharmacy's prescription: got %v, want not found", err)
+	}
+}
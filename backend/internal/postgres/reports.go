@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"medeasy/m/domain"
+)
+
+type reportRepository struct {
+	db *sqlx.DB
+}
+
+func (r *reportRepository) DailySummary(ctx context.Context, pharmacyID int64) (domain.SalesSummary, error) {
+	var summary domain.SalesSummary
+	err := r.db.GetContext(ctx, &summary, `SELECT COALESCE(SUM(total_amount - discount),0) AS revenue, COUNT(*) AS sales_count FROM sales WHERE DATE(created_at) = CURRENT_DATE AND pharmacy_id = $1`, pharmacyID)
+	return summary, err
+}
+
+func (r *reportRepository) MonthlySummary(ctx context.Context, pharmacyID int64) (domain.SalesSummary, error) {
+	var summary domain.SalesSummary
+	err := r.db.GetContext(ctx, &summary, `SELECT COALESCE(SUM(total_amount - discount),0) AS revenue, COUNT(*) AS sales_count FROM sales WHERE DATE(created_at) >= date_trunc('month', CURRENT_DATE) AND pharmacy_id = $1`, pharmacyID)
+	return summary, err
+}
+
+func (r *reportRepository) Sales(ctx context.Context, pharmacyID int64, startDate, endDate string) ([]domain.Sale, error) {
+	args := []any{pharmacyID}
+	clauses := []string{"pharmacy_id = $1"}
+	if startDate != "" {
+		args = append(args, startDate)
+		clauses = append(clauses, fmt.Sprintf("DATE(created_at) >= $%d", len(args)))
+	}
+	if endDate != "" {
+		args = append(args, endDate)
+		clauses = append(clauses, fmt.Sprintf("DATE(created_at) <= $%d", len(args)))
+	}
+	query := `SELECT ` + saleColumns + ` FROM sales
+		WHERE ` + strings.Join(clauses, " AND ") + ` ORDER BY created_at DESC`
+
+	var sales []domain.Sale
+	err := r.db.SelectContext(ctx, &sales, query, args...)
+	return sales, err
+}
+
+func (r *reportRepository) SaleItems(ctx context.Context, saleIDs []int64) ([]domain.SaleItemDetail, error) {
+	query, args, err := sqlx.In(`SELECT si.sale_id, si.medicine_id, si.inventory_id, si.quantity, si.unit_price, si.subtotal,
+	            COALESCE(m.brand_name, i.brand_name, 'Custom Medicine') as brand_name
+                FROM sale_items si
+                LEFT JOIN medicines m ON m.id = si.medicine_id
+                LEFT JOIN inventory i ON i.id = si.inventory_id
+                WHERE si.sale_id IN (?)`, saleIDs)
+	if err != nil {
+		return nil, err
+	}
+	var rows []domain.SaleItemDetail
+	err = r.db.SelectContext(ctx, &rows, r.db.Rebind(query), args...)
+	return rows, err
+}
+
+func (r *reportRepository) ControlledRegister(ctx context.Context, pharmacyID int64, startDate, endDate string) ([]domain.ControlledDispense, error) {
+	args := []any{pharmacyID}
+	// Lines sold before their schedule was kept fall back to the medicine's.
+	clauses := []string{"s.pharmacy_id = $1", "COALESCE(si.schedule, m.schedule) = 'controlled'"}
+	if startDate != "" {
+		args = append(args, startDate)
+		clauses = append(clauses, fmt.Sprintf("DATE(s.created_at) >= $%d", len(args)))
+	}
+	if endDate != "" {
+		args = append(args, endDate)
+		clauses = append(clauses, fmt.Sprintf("DATE(s.created_at) <= $%d", len(args)))
+	}
+	query := `SELECT s.id AS sale_id, s.created_at AS dispensed_at, si.medicine_id,
+	            COALESCE(m.brand_name, i.brand_name, 'Custom Medicine') AS brand_name,
+	            COALESCE(m.generic_name, i.generic_name, '') AS generic_name, COALESCE(m.strength, '') AS strength,
+	            COALESCE(m.dosage_form, '') AS dosage_form, COALESCE(m.manufacturer, i.manufacturer, '') AS manufacturer,
+	            i.batch_number, si.quantity, p.patient_name, p.patient_address, p.prescriber, p.registration_number,
+	            to_char(p.prescribed_on, 'YYYY-MM-DD') AS prescribed_on,
+	            COALESCE(u.username, k.name, '') AS dispensed_by,
+	            EXISTS (SELECT 1 FROM sale_conflicts c WHERE c.sale_item_id = si.id AND c.kind = 'prescription') AS unverified
+                FROM sale_items si
+                JOIN sales s ON s.id = si.sale_id
+                LEFT JOIN medicines m ON m.id = si.medicine_id
+                LEFT JOIN inventory i ON i.id = si.inventory_id
+                LEFT JOIN sale_prescriptions p ON p.sale_id = s.id
+                LEFT JOIN users u ON u.id = s.user_id
+                LEFT JOIN api_keys k ON k.id = s.api_key_id
+                WHERE ` + strings.Join(clauses, " AND ") + ` ORDER BY s.created_at, si.id`
+
+	rows := []domain.ControlledDispense{}
+	err := r.db.SelectContext(ctx, &rows, query, args...)
+	return rows, err
+}
@@ -0,0 +1,198 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"medeasy/m/domain"
+	"medeasy/m/internal/service"
+)
+
+// saleColumns matches domain.Sale.
+const saleColumns = `id, pharmacy_id, user_id, api_key_id, total_amount, discount, paid_amount, due_amount, COALESCE(round_off, 0) AS round_off, COALESCE(change_returned, 0) AS change_returned, idempotency_key, request_hash, created_at`
+
+type saleRepository struct {
+	db *sqlx.DB
+}
+
+func (r *saleRepository) InTx(ctx context.Context, fn func(tx service.SaleTx) error) error {
+	return inTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		return fn(&saleTx{tx: tx})
+	})
+}
+
+type saleTx struct {
+	tx *sqlx.Tx
+}
+
+func (t *saleTx) LockInventory(ctx context.Context, pharmacyID int64, ids []int64) ([]domain.InventoryItem, error) {
+	query, args, err := sqlx.In(`SELECT `+inventoryColumns+` FROM inventory WHERE pharmacy_id = ? AND id IN (?) ORDER BY id FOR UPDATE`, pharmacyID, ids)
+	if err != nil {
+		return nil, err
+	}
+	var items []domain.InventoryItem
+	err = t.tx.SelectContext(ctx, &items, t.tx.Rebind(query), args...)
+	return items, err
+}
+
+func (t *saleTx) ClaimIdempotencyKey(ctx context.Context, pharmacyID int64, key string) (domain.Sale, error) {
+	if _, err := t.tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, $2))`, key, pharmacyID); err != nil {
+		return domain.Sale{}, err
+	}
+	var sale domain.Sale
+	err := t.tx.GetContext(ctx, &sale, `SELECT `+saleColumns+` FROM sales WHERE pharmacy_id = $1 AND idempotency_key = $2`, pharmacyID, key)
+	return sale, translate(err)
+}
+
+func (t *saleTx) CreateSale(ctx context.Context, sale *domain.Sale, soldAt time.Time) error {
+	return t.tx.QueryRowxContext(ctx, `
+		INSERT INTO sales (pharmacy_id, user_id, api_key_id, total_amount, discount, paid_amount, due_amount, round_off, change_returned, idempotency_key, request_hash, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id, created_at`,
+		sale.PharmacyID, sale.UserID, sale.APIKeyID, sale.TotalAmount, sale.Discount, sale.PaidAmount, sale.DueAmount, sale.RoundOff, sale.ChangeReturned,
+		sale.IdempotencyKey, sale.RequestHash, soldAt).
+		Scan(&sale.ID, &sale.CreatedAt)
+}
+
+func (t *saleTx) AddItem(ctx context.Context, item *domain.SaleItem) error {
+	return t.tx.QueryRowxContext(ctx, `
+		INSERT INTO sale_items (sale_id, medicine_id, inventory_id, quantity, unit_price, subtotal, schedule)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		item.SaleID, item.MedicineID, item.InventoryID, item.Quantity, item.UnitPrice, item.Subtotal, item.Schedule).Scan(&item.ID)
+}
+
+func (t *saleTx) DecrementStock(ctx context.Context, inventoryID, quantity int64) error {
+	var id int64
+	err := t.tx.GetContext(ctx, &id, `UPDATE inventory SET quantity = quantity - $1 WHERE id = $2 AND quantity >= $1 RETURNING id`, quantity, inventoryID)
+	return translate(err)
+}
+
+// conflictSelect reads sale_conflicts as c with the item's name resolved.
+const conflictSelect = `SELECT c.id, c.kind, c.pharmacy_id, c.sale_id, c.sale_item_id, c.inventory_id,
+	COALESCE(i.brand_name, m.brand_name, 'Unknown') AS brand_name,
+	c.requested, c.available, c.requested - c.available AS shortfall,
+	c.resolution, c.resolved_by, c.resolved_at, c.created_at
+	FROM sale_conflicts c
+	JOIN inventory i ON i.id = c.inventory_id
+	LEFT JOIN medicines m ON m.id = i.medicine_id`
+
+func (t *saleTx) RecordConflict(ctx context.Context, conflict *domain.SaleConflict) error {
+	return t.tx.QueryRowxContext(ctx, `
+		INSERT INTO sale_conflicts (kind, pharmacy_id, sale_id, sale_item_id, inventory_id, requested, available)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`,
+		conflict.Kind, conflict.PharmacyID, conflict.SaleID, conflict.SaleItemID, conflict.InventoryID, conflict.Requested, conflict.Available).
+		Scan(&conflict.ID, &conflict.CreatedAt)
+}
+
+func (t *saleTx) RecordInteraction(ctx context.Context, interaction *domain.SaleInteraction) error {
+	return t.tx.QueryRowxContext(ctx, `
+		INSERT INTO sale_interactions (sale_id, ingredient_a, ingredient_b, severity, overridden_by, reason)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+		interaction.SaleID, interaction.IngredientA, interaction.IngredientB, interaction.Severity, interaction.OverriddenBy, interaction.Reason).
+		Scan(&interaction.ID, &interaction.CreatedAt)
+}
+
+func (t *saleTx) RecordPrescription(ctx context.Context, prescription *domain.SalePrescription) error {
+	_, err := t.tx.ExecContext(ctx, `
+		INSERT INTO sale_prescriptions (sale_id, prescriber, registration_number, patient_name, patient_address, prescribed_on, image_ref, prescription_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		prescription.SaleID, prescription.Prescriber, prescription.RegistrationNumber, prescription.PatientName,
+		prescription.PatientAddress, prescription.PrescribedOn, prescription.ImageRef, prescription.PrescriptionID)
+	return err
+}
+
+func (t *saleTx) Schedules(ctx context.Context, ids []int64) (map[int64]string, error) {
+	schedules := make(map[int64]string)
+	if len(ids) == 0 {
+		return schedules, nil
+	}
+	query, args, err := sqlx.In(`SELECT id, schedule FROM medicines WHERE id IN (?)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		ID       int64  `db:"id"`
+		Schedule string `db:"schedule"`
+	}
+	if err := t.tx.SelectContext(ctx, &rows, t.tx.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		schedules[row.ID] = row.Schedule
+	}
+	return schedules, nil
+}
+
+func (t *saleTx) IngredientSchedules(ctx context.Context, ingredients []string) (map[string]string, error) {
+	return ingredientSchedules(ctx, t.tx, ingredients)
+}
+
+// ingredientSchedules returns the schedule listed for each of ingredients
+// that has one.
+func ingredientSchedules(ctx context.Context, q sqlx.QueryerContext, ingredients []string) (map[string]string, error) {
+	schedules := make(map[string]string)
+	if len(ingredients) == 0 {
+		return schedules, nil
+	}
+	query, args, err := sqlx.In(`SELECT ingredient, schedule FROM ingredient_schedules WHERE ingredient IN (?)`, ingredients)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		Ingredient string `db:"ingredient"`
+		Schedule   string `db:"schedule"`
+	}
+	if err := sqlx.SelectContext(ctx, q, &rows, sqlx.Rebind(sqlx.DOLLAR, query), args...); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		schedules[row.Ingredient] = row.Schedule
+	}
+	return schedules, nil
+}
+
+func (t *saleTx) SoldGenerics(ctx context.Context, saleID int64) ([]string, error) {
+	var generics []string
+	err := t.tx.SelectContext(ctx, &generics, `SELECT COALESCE(i.generic_name, m.generic_name, '')
+		FROM sale_items si
+		LEFT JOIN medicines m ON m.id = si.medicine_id
+		LEFT JOIN inventory i ON i.id = si.inventory_id
+		WHERE si.sale_id = $1 ORDER BY si.id`, saleID)
+	return generics, err
+}
+
+func (t *saleTx) Conflicts(ctx context.Context, saleID int64) ([]domain.SaleConflict, error) {
+	var conflicts []domain.SaleConflict
+	err := t.tx.SelectContext(ctx, &conflicts, conflictSelect+` WHERE c.sale_id = $1 ORDER BY c.id`, saleID)
+	return conflicts, err
+}
+
+func (r *saleRepository) Conflicts(ctx context.Context, pharmacyID int64, status string) ([]domain.SaleConflict, error) {
+	query := conflictSelect + ` WHERE c.pharmacy_id = $1`
+	switch status {
+	case service.ConflictsOpen:
+		query += ` AND c.resolved_at IS NULL`
+	case service.ConflictsResolved:
+		query += ` AND c.resolved_at IS NOT NULL`
+	}
+	conflicts := []domain.SaleConflict{}
+	err := r.db.SelectContext(ctx, &conflicts, query+` ORDER BY c.created_at, c.id`, pharmacyID)
+	return conflicts, err
+}
+
+func (r *saleRepository) Conflict(ctx context.Context, pharmacyID, id int64) (domain.SaleConflict, error) {
+	var conflict domain.SaleConflict
+	err := r.db.GetContext(ctx, &conflict, conflictSelect+` WHERE c.id = $1 AND c.pharmacy_id = $2`, id, pharmacyID)
+	return conflict, translate(err)
+}
+
+func (r *saleRepository) ResolveConflict(ctx context.Context, pharmacyID, id, userID int64, resolution string) (domain.SaleConflict, error) {
+	var updated int64
+	err := r.db.GetContext(ctx, &updated, `UPDATE sale_conflicts SET resolution = $1, resolved_by = $2, resolved_at = NOW()
+		WHERE id = $3 AND pharmacy_id = $4 AND resolved_at IS NULL RETURNING id`, resolution, userID, id, pharmacyID)
+	if err != nil {
+		return domain.SaleConflict{}, translate(err)
+	}
+	return r.Conflict(ctx, pharmacyID, id)
+}
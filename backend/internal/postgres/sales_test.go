@@ -0,0 +1,233 @@
+package postgres
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"medeasy/m/domain"
+	"medeasy/m/internal/service"
+)
+
+// sale sells quantity units of one item as the fixture's owner.
+func (f fixture) sale(inventoryID, quantity int64, paid domain.Money) service.NewSale {
+	return service.NewSale{
+		PharmacyID: f.pharmacyID,
+		UserID:     int64(f.owner.ID),
+		Role:       f.owner.Role,
+		Items:      []service.SaleLine{{InventoryID: inventoryID, Quantity: quantity}},
+		PaidAmount: paid,
+	}
+}
+
+func TestSaleDecrementsStock(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	item := f.stock(t, "Napa", 30, domain.Taka(10), 10)
+
+	receipt, err := f.services.Sales.Create(ctx, f.sale(item.ID, 5, domain.Taka(10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Total != domain.Taka(5) || receipt.ChangeReturned != domain.Taka(5) || receipt.Lines != 1 {
+		t.Errorf("receipt = %+v, want a total of 5 with 5 in change", receipt)
+	}
+	if got := f.quantity(t, item.ID); got != 25 {
+		t.Errorf("quantity = %d, want 25", got)
+	}
+
+	summary, err := f.services.Reports.Daily(ctx, f.pharmacyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary != (domain.SalesSummary{Revenue: domain.Taka(5), SalesCount: 1}) {
+		t.Errorf("daily summary = %+v", summary)
+	}
+	sales, err := f.services.Reports.Sales(ctx, f.pharmacyID, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sales) != 1 || sales[0].ID != receipt.SaleID || len(sales[0].Items) != 1 {
+		t.Errorf("sales report = %+v", sales)
+	}
+}
+
+func TestSaleRejectsInsufficientStock(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	item := f.stock(t, "Ace", 2, domain.Taka(2), 1)
+
+	_, err := f.services.Sales.Create(ctx, f.sale(item.ID, 3, 0))
+	if service.ReasonOf(err) != "insufficient_stock" {
+		t.Fatalf("got %v, want insufficient stock", err)
+	}
+	if got := f.quantity(t, item.ID); got != 2 {
+		t.Errorf("quantity = %d, want 2", got)
+	}
+	summary, err := f.services.Reports.Daily(ctx, f.pharmacyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.SalesCount != 0 {
+		t.Errorf("%d sales recorded, want none", summary.SalesCount)
+	}
+}
+
+func TestSaleOfAnotherPharmacysStock(t *testing.T) {
+	f, other := newFixture(t), newFixture(t)
+	item := other.stock(t, "Seclo", 10, domain.Taka(5), 1)
+
+	_, err := f.services.Sales.Create(context.Background(), f.sale(item.ID, 1, 0))
+	if kindOf(err) != service.KindInvalid {
+		t.Fatalf("got %v, want the item not to be found", err)
+	}
+	if got := other.quantity(t, item.ID); got != 10 {
+		t.Errorf("quantity = %d, want 10", got)
+	}
+}
+
+func TestSaleReplaysIdempotencyKey(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	item := f.stock(t, "Fexo", 10, domain.Taka(8), 1)
+
+	in := f.sale(item.ID, 2, domain.Taka(20))
+	in.IdempotencyKey = "counter-1"
+	first, err := f.services.Sales.Create(ctx, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := f.services.Sales.Create(ctx, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !again.Replayed || again.SaleID != first.SaleID || again.SaleTotals != first.SaleTotals || again.Lines != first.Lines {
+		t.Errorf("replay = %+v, want the receipt of %+v", again, first)
+	}
+	if got := f.quantity(t, item.ID); got != 8 {
+		t.Errorf("quantity = %d, want 8", got)
+	}
+
+	in.Items[0].Quantity = 3
+	if _, err := f.services.Sales.Create(ctx, in); kindOf(err) != service.KindConflict {
+		t.Errorf("reusing the key for another sale: got %v, want a conflict", err)
+	}
+}
+
+func TestOfflineSaleRecordsStockConflict(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	item := f.stock(t, "Monas", 1, domain.Taka(15), 1)
+
+	in := f.sale(item.ID, 3, domain.Taka(45))
+	in.IdempotencyKey = "offline-1"
+	in.Offline = true
+	receipt, err := f.services.Sales.Create(ctx, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(receipt.Conflicts) != 1 {
+		t.Fatalf("conflicts = %+v, want one", receipt.Conflicts)
+	}
+	c := receipt.Conflicts[0]
+	if c.Kind != domain.ConflictStock || c.Requested != 3 || c.Available != 1 || c.Shortfall != 2 {
+		t.Errorf("conflict = %+v", c)
+	}
+	if got := f.quantity(t, item.ID); got != 0 {
+		t.Errorf("quantity = %d, want 0", got)
+	}
+
+	open, err := f.services.Sales.Conflicts(ctx, f.pharmacyID, service.ConflictsOpen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(open) != 1 || open[0].ID != c.ID {
+		t.Fatalf("open conflicts = %+v", open)
+	}
+	resolved, err := f.services.Sales.ResolveConflict(ctx, f.pharmacyID, c.ID, int64(f.owner.ID), "counted again")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved.Resolution == nil || *resolved.Resolution != "counted again" {
+		t.Errorf("resolved = %+v", resolved)
+	}
+	if _, err := f.services.Sales.ResolveConflict(ctx, f.pharmacyID, c.ID, int64(f.owner.ID), "twice"); err == nil {
+		t.Error("resolving a closed conflict did not fail")
+	}
+}
+
+func TestConcurrentSalesNeverOversell(t *testing.T) {
+	const stock, buyers = 5, 12
+	f := newFixture(t)
+	ctx := context.Background()
+	item := f.stock(t, "Sergel", stock, domain.Taka(7), 1)
+
+	errs := make(chan error, buyers)
+	var start sync.WaitGroup
+	start.Add(1)
+	for range buyers {
+		go func() {
+			start.Wait()
+			_, err := f.services.Sales.Create(ctx, f.sale(item.ID, 1, domain.Taka(7)))
+			errs <- err
+		}()
+	}
+	start.Done()
+
+	var sold int
+	for range buyers {
+		err := <-errs
+		switch {
+		case err == nil:
+			sold++
+		case service.ReasonOf(err) != "insufficient_stock":
+			t.Errorf("got %v, want a sale or insufficient stock", err)
+		}
+	}
+	if sold != stock {
+		t.Errorf("%d sales succeeded, want %d", sold, stock)
+	}
+	if got := f.quantity(t, item.ID); got != 0 {
+		t.Errorf("quantity = %d, want 0", got)
+	}
+	summary, err := f.services.Reports.Daily(ctx, f.pharmacyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.SalesCount != stock {
+		t.Errorf("%d sales recorded, want %d", summary.SalesCount, stock)
+	}
+}
+
+// Carts listing the same items in opposite orders must not deadlock.
+func TestConcurrentSalesLockInOrder(t *testing.T) {
+	const rounds = 10
+	f := newFixture(t)
+	ctx := context.Background()
+	a := f.stock(t, "Maxpro", 2*rounds, domain.Taka(6), 1)
+	b := f.stock(t, "Rupa", 2*rounds, domain.Taka(4), 1)
+
+	errs := make(chan error, 2*rounds)
+	for i := range 2 * rounds {
+		lines := []service.SaleLine{{InventoryID: a.ID, Quantity: 1}, {InventoryID: b.ID, Quantity: 1}}
+		if i%2 == 1 {
+			lines[0], lines[1] = lines[1], lines[0]
+		}
+		go func() {
+			in := f.sale(0, 0, 0)
+			in.Items = lines
+			_, err := f.services.Sales.Create(ctx, in)
+			errs <- err
+		}()
+	}
+	for range 2 * rounds {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+	for _, item := range []domain.InventoryItem{a, b} {
+		if got := f.quantity(t, item.ID); got != 0 {
+			t.Errorf("quantity of %d = %d, want 0", item.ID, got)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"medeasy/m/domain"
+)
+
+const submissionColumns = `id, pharmacy_id, inventory_id, brand_name, generic_name, manufacturer, type, strength, dosage_form,
+	status, medicine_id, note, submitted_by, reviewed_at, created_at`
+
+type submissionRepository struct {
+	db *sqlx.DB
+}
+
+func (r *submissionRepository) Create(ctx context.Context, s *domain.CatalogSubmission) error {
+	err := r.db.QueryRowxContext(ctx, `INSERT INTO catalog_submissions (pharmacy_id, inventory_id, brand_name, generic_name, manufacturer, type, strength, dosage_form, submitted_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, status, created_at`,
+		s.PharmacyID, s.InventoryID, s.BrandName, s.GenericName, s.Manufacturer, s.Type, s.Strength, s.DosageForm, s.SubmittedBy).
+		Scan(&s.ID, &s.Status, &s.CreatedAt)
+	return translate(err)
+}
+
+func (r *submissionRepository) ByID(ctx context.Context, id int64) (domain.CatalogSubmission, error) {
+	var s domain.CatalogSubmission
+	err := r.db.GetContext(ctx, &s, `SELECT `+submissionColumns+` FROM catalog_submissions WHERE id = $1`, id)
+	return s, translate(err)
+}
+
+func (r *submissionRepository) List(ctx context.Context, pharmacyID int64, status string) ([]domain.CatalogSubmission, error) {
+	submissions := []domain.CatalogSubmission{}
+	err := r.db.SelectContext(ctx, &submissions, `SELECT `+submissionColumns+` FROM catalog_submissions
+		WHERE ($1 = 0 OR pharmacy_id = $1) AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC, id DESC`, pharmacyID, status)
+	return submissions, err
+}
+
+func (r *submissionRepository) Candidates(ctx context.Context, brandName, genericName string, limit int) ([]domain.CatalogCandidate, error) {
+	candidates := []domain.CatalogCandidate{}
+	err := r.db.SelectContext(ctx, &candidates, `SELECT `+medicineColumns+`,
+		(2 * similarity(lower(brand_name), lower($1)) + similarity(lower(COALESCE(generic_name, '')), lower($2))) / 3 AS similarity
+		FROM medicines
+		WHERE discontinued_at IS NULL AND lower(brand_name) % lower($1)
+		ORDER BY similarity DESC, brand_name, id
+		LIMIT $3`, brandName, genericName, limit)
+	return candidates, err
+}
+
+func (r *submissionRepository) Merge(ctx context.Context, id, medicineID int64) (domain.CatalogSubmission, domain.Relinked, error) {
+	var merged domain.CatalogSubmission
+	var relinked domain.Relinked
+	err := inTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var err error
+		merged, relinked, err = relink(ctx, tx, id, func(domain.CatalogSubmission) (int64, error) {
+			return medicineID, nil
+		}, domain.SubmissionMerged)
+		return err
+	})
+	return merged, relinked, err
+}
+
+func (r *submissionRepository) AddToCatalog(ctx context.Context, id int64) (domain.CatalogSubmission, domain.Relinked, error) {
+	var added domain.CatalogSubmission
+	var relinked domain.Relinked
+	err := inTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var err error
+		added, relinked, err = relink(ctx, tx, id, func(s domain.CatalogSubmission) (int64, error) {
+			// Added medicines have no brand id, which keeps them out of
+			// catalog CSV imports. They are classified like the catalog.
+			schedules, err := ingredientSchedules(ctx, tx, domain.GenericKeys(s.GenericName))
+			if err != nil {
+				return 0, err
+			}
+			var medicineID int64
+			err = tx.GetContext(ctx, &medicineID, `INSERT INTO medicines (brand_name, type, generic_name, manufacturer, dosage_form, strength, schedule)
+				VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+				s.BrandName, s.Type, s.GenericName, s.Manufacturer, s.DosageForm, s.Strength, domain.ScheduleOf(s.GenericName, schedules))
+			return medicineID, err
+		}, domain.SubmissionAdded)
+		return err
+	})
+	return added, relinked, err
+}
+
+// relink closes the pending submission id with status and links its custom
+// items to the medicine returned by target.
+func relink(ctx context.Context, tx *sqlx.Tx, id int64, target func(domain.CatalogSubmission) (int64, error), status string) (domain.CatalogSubmission, domain.Relinked, error) {
+	var s domain.CatalogSubmission
+	err := tx.GetContext(ctx, &s, `SELECT `+submissionColumns+` FROM catalog_submissions WHERE id = $1 AND status = 'pending' FOR UPDATE`, id)
+	if err != nil {
+		return s, domain.Relinked{}, translate(err)
+	}
+	medicineID, err := target(s)
+	if err != nil {
+		return s, domain.Relinked{}, err
+	}
+
+	// The submitted item and every other custom item of the pharmacy with
+	// the same names take the catalog medicine's names. Sales of them are
+	// touched so offline clients sync the relinked items.
+	var relinked domain.Relinked
+	err = tx.QueryRowxContext(ctx, `WITH items AS (
+	        UPDATE inventory i
+	           SET medicine_id = m.id, brand_name = m.brand_name, generic_name = m.generic_name,
+	               manufacturer = m.manufacturer, type = m.type, updated_at = CURRENT_TIMESTAMP
+	          FROM medicines m
+	         WHERE m.id = $1 AND i.pharmacy_id = $2 AND i.medicine_id IS NULL
+	           AND (i.id = $3 OR (lower(trim(i.brand_name)) = lower(trim($4))
+	                AND lower(trim(COALESCE(i.generic_name, ''))) = lower(trim($5))))
+	     RETURNING i.id),
+	     sold AS (
+	        UPDATE sale_items si SET medicine_id = $1
+	          FROM items
+	         WHERE si.inventory_id = items.id AND si.medicine_id IS NULL
+	     RETURNING si.sale_id),
+	     touched AS (
+	        UPDATE sales SET sync_txid = pg_current_xact_id() WHERE id IN (SELECT sale_id FROM sold)),
+	     closed AS (
+	        UPDATE catalog_submissions SET status = $6, medicine_id = $1, reviewed_at = NOW()
+	         WHERE status = 'pending' AND id <> $7 AND inventory_id IN (SELECT id FROM items))
+	SELECT (SELECT COUNT(*) FROM items), (SELECT COUNT(*) FROM sold)`,
+		medicineID, s.PharmacyID, s.InventoryID, s.BrandName, s.GenericName, status, s.ID).
+		Scan(&relinked.Inventory, &relinked.SaleItems)
+	if err != nil {
+		return s, relinked, err
+	}
+
+	err = tx.GetContext(ctx, &s, `UPDATE catalog_submissions SET status = $2, medicine_id = $3, reviewed_at = NOW()
+		WHERE id = $1 RETURNING `+submissionColumns, s.ID, status, medicineID)
+	return s, relinked, err
+}
+
+func (r *submissionRepository) Reject(ctx context.Context, id int64, note string) (domain.CatalogSubmission, error) {
+	var s domain.CatalogSubmission
+	err := r.db.GetContext(ctx, &s, `UPDATE catalog_submissions SET status = 'rejected', note = NULLIF($2, ''), reviewed_at = NOW()
+		WHERE id = $1 AND status = 'pending' RETURNING `+submissionColumns, id, note)
+	return s, translate(err)
+}
@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"medeasy/m/domain"
+	"medeasy/m/internal/service"
+)
+
+// custom stocks an item that is not in the catalog.
+func (f fixture) custom(t testing.TB, brand, generic, expiry string) domain.InventoryItem {
+	t.Helper()
+	item, _, err := f.services.Inventory.Add(context.Background(), f.pharmacyID, service.InventoryInput{
+		BrandName:   brand,
+		GenericName: generic,
+		Quantity:    20,
+		CostPrice:   domain.Taka(40),
+		SalePrice:   domain.Taka(40),
+		PackSize:    10,
+		ExpiryDate:  expiry,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.MedicineID != nil {
+		t.Fatalf("%s was linked to catalog medicine %d", brand, *item.MedicineID)
+	}
+	return item
+}
+
+func TestMergeRelinksCustomItems(t *testing.T) {
+	f, other := newFixture(t), newFixture(t)
+	ctx := context.Background()
+	brand := fmt.Sprintf("Relink %d", time.Now().UnixNano())
+	var medicineID int64
+	if err := f.db.Get(&medicineID, `INSERT INTO medicines (brand_name, generic_name, type, manufacturer)
+		VALUES ($1, 'Paracetamol', 'allopathic', 'Square') RETURNING id`, brand+" Catalog"); err != nil {
+		t.Fatal(err)
+	}
+
+	expiry := time.Now().AddDate(0, 0, 10).Format("2006-01-02")
+	submitted := f.custom(t, brand, "Paracetamol", expiry)
+	sibling := f.custom(t, "  "+brand+" ", "PARACETAMOL", "")
+	unrelated := f.custom(t, brand+" Extra", "Paracetamol", "")
+	elsewhere := other.custom(t, brand, "Paracetamol", "")
+
+	for _, id := range []int64{submitted.ID, sibling.ID} {
+		if _, err := f.services.Sales.Create(ctx, f.sale(id, 2, domain.Taka(10))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	submission, err := f.services.Submissions.Submit(ctx, f.pharmacyID, int64(f.owner.ID), submitted.ID, service.SubmissionInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	siblingSubmission, err := f.services.Submissions.Submit(ctx, f.pharmacyID, int64(f.owner.ID), sibling.ID, service.SubmissionInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	merged, relinked, err := f.services.Submissions.Merge(ctx, f.pharmacyID, submission.ID, medicineID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.Status != domain.SubmissionMerged || merged.MedicineID == nil || *merged.MedicineID != medicineID {
+		t.Errorf("merged submission = %+v, want merged into %d", merged, medicineID)
+	}
+	if relinked != (domain.Relinked{Inventory: 2, SaleItems: 2}) {
+		t.Errorf("relinked = %+v, want 2 items and 2 sale items", relinked)
+	}
+
+	linked := func(id int64) (medicine *int64, brandName *string) {
+		t.Helper()
+		row := struct {
+			MedicineID *int64  `db:"medicine_id"`
+			BrandName  *string `db:"brand_name"`
+		}{}
+		if err := f.db.Get(&row, `SELECT medicine_id, brand_name FROM inventory WHERE id = $1`, id); err != nil {
+			t.Fatal(err)
+		}
+		return row.MedicineID, row.BrandName
+	}
+	for _, id := range []int64{submitted.ID, sibling.ID} {
+		if medicine, name := linked(id); medicine == nil || *medicine != medicineID || name == nil || *name != brand+" Catalog" {
+			t.Errorf("item %d is linked to %v named %v, want %d named %q", id, medicine, name, medicineID, brand+" Catalog")
+		}
+	}
+	for _, id := range []int64{unrelated.ID, elsewhere.ID} {
+		if medicine, _ := linked(id); medicine != nil {
+			t.Errorf("item %d was relinked to %d", id, *medicine)
+		}
+	}
+
+	var unlinkedSales int
+	if err := f.db.Get(&unlinkedSales, `SELECT COUNT(*) FROM sale_items
+		WHERE inventory_id IN ($1, $2) AND medicine_id IS DISTINCT FROM $3`, submitted.ID, sibling.ID, medicineID); err != nil {
+		t.Fatal(err)
+	}
+	if unlinkedSales != 0 {
+		t.Errorf("%d sale items were not relinked", unlinkedSales)
+	}
+
+	closed, err := f.services.Submissions.List(ctx, f.pharmacyID, domain.SubmissionMerged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var siblingClosed bool
+	for _, s := range closed {
+		if s.ID == siblingSubmission.ID {
+			siblingClosed = s.MedicineID != nil && *s.MedicineID == medicineID && s.ReviewedAt != nil
+		}
+	}
+	if !siblingClosed {
+		t.Errorf("sibling submission %d was not closed as merged into %d", siblingSubmission.ID, medicineID)
+	}
+	if _, _, err := f.services.Submissions.Merge(ctx, f.pharmacyID, siblingSubmission.ID, medicineID); kindOf(err) != service.KindConflict {
+		t.Errorf("merging the closed sibling: got %v, want conflict", err)
+	}
+
+	alerts, err := f.services.Inventory.ExpiryAlerts(ctx, f.pharmacyID, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listed bool
+	for _, alert := range alerts {
+		if alert.InventoryID == submitted.ID {
+			listed = alert.BrandName == brand+" Catalog" && alert.Quantity == submitted.Quantity-2
+		}
+	}
+	if !listed {
+		t.Errorf("expiry alerts %+v do not list item %d under its catalog name", alerts, submitted.ID)
+	}
+}
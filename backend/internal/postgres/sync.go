@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"medeasy/m/domain"
+	"medeasy/m/internal/service"
+)
+
+// Transaction ids are xid8 in the database and travel as BIGINT, which holds
+// any id a cluster will reach.
+const (
+	syncTxID  = `sync_txid::text::bigint AS sync_txid`
+	syncAfter = `(sync_txid, id) > ($1::bigint::text::xid8, $2) AND sync_txid < $3::bigint::text::xid8`
+	syncOrder = ` ORDER BY sync_txid, id LIMIT $4`
+)
+
+type syncRepository struct {
+	db *sqlx.DB
+}
+
+type syncedInventory struct {
+	TxID int64 `db:"sync_txid"`
+	domain.InventoryItem
+}
+
+type syncedMedicine struct {
+	TxID int64 `db:"sync_txid"`
+	domain.Medicine
+}
+
+type syncedSale struct {
+	TxID int64 `db:"sync_txid"`
+	domain.Sale
+}
+
+func (r *syncRepository) Horizon(ctx context.Context) (int64, error) {
+	var horizon int64
+	err := r.db.GetContext(ctx, &horizon, `SELECT pg_snapshot_xmin(pg_current_snapshot())::text::bigint`)
+	return horizon, err
+}
+
+func (r *syncRepository) Inventory(ctx context.Context, q service.SyncQuery) ([]service.Synced[domain.InventoryItem], error) {
+	var rows []syncedInventory
+	err := r.db.SelectContext(ctx, &rows, `SELECT `+syncTxID+`, `+inventoryColumns+` FROM inventory
+		WHERE pharmacy_id = $5 AND `+syncAfter+syncOrder,
+		q.After.TxID, q.After.ID, q.Horizon, q.Limit, q.PharmacyID)
+	synced := make([]service.Synced[domain.InventoryItem], len(rows))
+	for i, row := range rows {
+		synced[i] = service.Synced[domain.InventoryItem]{SyncPosition: service.SyncPosition{TxID: row.TxID, ID: row.ID}, Row: row.InventoryItem}
+	}
+	return synced, err
+}
+
+func (r *syncRepository) Medicines(ctx context.Context, q service.SyncQuery) ([]service.Synced[domain.Medicine], error) {
+	var rows []syncedMedicine
+	err := r.db.SelectContext(ctx, &rows, `SELECT `+syncTxID+`, `+medicineColumns+` FROM medicines
+		WHERE `+syncAfter+syncOrder,
+		q.After.TxID, q.After.ID, q.Horizon, q.Limit)
+	synced := make([]service.Synced[domain.Medicine], len(rows))
+	for i, row := range rows {
+		synced[i] = service.Synced[domain.Medicine]{SyncPosition: service.SyncPosition{TxID: row.TxID, ID: row.ID}, Row: row.Medicine}
+	}
+	return synced, err
+}
+
+func (r *syncRepository) Sales(ctx context.Context, q service.SyncQuery) ([]service.Synced[domain.Sale], error) {
+	var rows []syncedSale
+	err := r.db.SelectContext(ctx, &rows, `SELECT `+syncTxID+`, `+saleColumns+` FROM sales
+		WHERE pharmacy_id = $5 AND `+syncAfter+syncOrder,
+		q.After.TxID, q.After.ID, q.Horizon, q.Limit, q.PharmacyID)
+	synced := make([]service.Synced[domain.Sale], len(rows))
+	for i, row := range rows {
+		synced[i] = service.Synced[domain.Sale]{SyncPosition: service.SyncPosition{TxID: row.TxID, ID: row.ID}, Row: row.Sale}
+	}
+	return synced, err
+}
+
+func (r *syncRepository) Tombstones(ctx context.Context, table string, q service.SyncQuery) ([]service.Tombstone, error) {
+	var rows []struct {
+		TxID  int64 `db:"sync_txid"`
+		ID    int64 `db:"id"`
+		RowID int64 `db:"row_id"`
+	}
+	err := r.db.SelectContext(ctx, &rows, `SELECT `+syncTxID+`, id, row_id FROM sync_tombstones
+		WHERE COALESCE(pharmacy_id, 0) = $5 AND entity = $6 AND `+syncAfter+syncOrder,
+		q.After.TxID, q.After.ID, q.Horizon, q.Limit, q.PharmacyID, table)
+	tombstones := make([]service.Tombstone, len(rows))
+	for i, row := range rows {
+		tombstones[i] = service.Tombstone{SyncPosition: service.SyncPosition{TxID: row.TxID, ID: row.ID}, RowID: row.RowID}
+	}
+	return tombstones, err
+}
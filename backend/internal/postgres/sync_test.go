@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"medeasy/m/domain"
+	"medeasy/m/internal/service"
+)
+
+// syncInventory pages through the pharmacy's inventory changes after cursor,
+// limit at a time, and returns the upserted and deleted ids in order along
+// with the final cursor.
+func (f fixture) syncInventory(t testing.TB, cursor string, limit int) (upserts, deleted []int64, next string) {
+	t.Helper()
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			t.Fatal("sync never reported the last page")
+		}
+		changes, err := f.services.Sync.Inventory(context.Background(), f.pharmacyID, cursor, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes.Upserts)+len(changes.Deleted) > limit {
+			t.Fatalf("page of %d upserts and %d deletions exceeds the limit %d", len(changes.Upserts), len(changes.Deleted), limit)
+		}
+		for _, item := range changes.Upserts {
+			upserts = append(upserts, item.ID)
+		}
+		deleted = append(deleted, changes.Deleted...)
+		cursor = changes.Cursor
+		if !changes.HasMore {
+			return upserts, deleted, cursor
+		}
+	}
+}
+
+func TestSyncPagesEveryChangeOnce(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	var items []domain.InventoryItem
+	for i := range 7 {
+		items = append(items, f.stock(t, fmt.Sprintf("Sync Paging %d", i), 10, domain.Taka(20), 10))
+	}
+	// Changing an item moves it to the end of the stream.
+	if _, err := f.services.Inventory.SetStock(ctx, f.pharmacyID, items[2].ID, 4, service.AnyVersion); err != nil {
+		t.Fatal(err)
+	}
+
+	upserts, deleted, cursor := f.syncInventory(t, "", 2)
+	seen := map[int64]int{}
+	for _, id := range upserts {
+		seen[id]++
+	}
+	for _, item := range items {
+		if seen[item.ID] != 1 {
+			t.Errorf("item %d synced %d times, want once", item.ID, seen[item.ID])
+		}
+	}
+	if len(upserts) != len(items) || len(deleted) != 0 {
+		t.Errorf("synced %v upserts and %v deletions, want the %d items once each", upserts, deleted, len(items))
+	}
+	if upserts[len(upserts)-1] != items[2].ID {
+		t.Errorf("last change = %d, want the updated item %d", upserts[len(upserts)-1], items[2].ID)
+	}
+
+	if upserts, deleted, _ := f.syncInventory(t, cursor, 2); len(upserts)+len(deleted) != 0 {
+		t.Errorf("caught-up cursor returned %v and %v", upserts, deleted)
+	}
+	if _, err := f.services.Inventory.SetStock(ctx, f.pharmacyID, items[5].ID, 1, service.AnyVersion); err != nil {
+		t.Fatal(err)
+	}
+	if upserts, _, _ := f.syncInventory(t, cursor, 2); len(upserts) != 1 || upserts[0] != items[5].ID {
+		t.Errorf("after one more change got %v, want [%d]", upserts, items[5].ID)
+	}
+}
+
+func TestSyncReportsDeletesAsTombstones(t *testing.T) {
+	f := newFixture(t)
+	kept := f.stock(t, "Sync Kept", 3, domain.Taka(15), 1)
+	gone := f.stock(t, "Sync Deleted", 3, domain.Taka(15), 1)
+	_, _, cursor := f.syncInventory(t, "", 10)
+
+	if _, err := f.db.Exec(`DELETE FROM inventory WHERE id = $1`, gone.ID); err != nil {
+		t.Fatal(err)
+	}
+	upserts, deleted, cursor := f.syncInventory(t, cursor, 10)
+	if len(upserts) != 0 || len(deleted) != 1 || deleted[0] != gone.ID {
+		t.Errorf("after the delete got upserts %v and deletions %v, want only %d deleted", upserts, deleted, gone.ID)
+	}
+
+	// A client starting from scratch sees the surviving row and the tombstone.
+	upserts, deleted, _ = f.syncInventory(t, "", 1)
+	if len(upserts) != 1 || upserts[0] != kept.ID || len(deleted) != 1 || deleted[0] != gone.ID {
+		t.Errorf("full sync got upserts %v and deletions %v, want [%d] and [%d]", upserts, deleted, kept.ID, gone.ID)
+	}
+	if upserts, deleted, _ := f.syncInventory(t, cursor, 10); len(upserts)+len(deleted) != 0 {
+		t.Errorf("caught-up cursor returned %v and %v", upserts, deleted)
+	}
+}
+
+func TestSyncDoesNotSkipTransactionsInFlight(t *testing.T) {
+	f := newFixture(t)
+	slow := f.stock(t, "Sync Slow Writer", 5, domain.Taka(10), 1)
+	_, _, cursor := f.syncInventory(t, "", 10)
+
+	// The slow transaction takes its id first, then a later one commits.
+	tx, err := f.db.Beginx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`UPDATE inventory SET quantity = 2 WHERE id = $1`, slow.ID); err != nil {
+		t.Fatal(err)
+	}
+	fast := f.stock(t, "Sync Fast Writer", 5, domain.Taka(10), 1)
+
+	// Handing out the committed change now would move the cursor past the
+	// slow transaction, so nothing is returned until it ends.
+	upserts, _, held := f.syncInventory(t, cursor, 10)
+	if len(upserts) != 0 {
+		t.Errorf("with a transaction in flight got %v, want nothing yet", upserts)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	upserts, _, _ = f.syncInventory(t, held, 10)
+	got := map[int64]bool{}
+	for _, id := range upserts {
+		got[id] = true
+	}
+	if len(upserts) != 2 || !got[slow.ID] || !got[fast.ID] {
+		t.Errorf("after commit got %v, want %d and %d", upserts, slow.ID, fast.ID)
+	}
+}
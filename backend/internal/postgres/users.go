@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"medeasy/m/domain"
+	"medeasy/m/internal/service"
+)
+
+type userRepository struct {
+	db *sqlx.DB
+}
+
+func (r *userRepository) ByEmail(ctx context.Context, email string) (domain.User, error) {
+	var user domain.User
+	err := r.db.GetContext(ctx, &user, `SELECT id, username, email, password, role, pharmacy_id, disabled_at FROM users WHERE email = $1`, email)
+	return user, translate(err)
+}
+
+func (r *userRepository) ByID(ctx context.Context, id int64) (domain.User, error) {
+	var user domain.User
+	err := r.db.GetContext(ctx, &user, `SELECT id, username, email, password, role, pharmacy_id, disabled_at FROM users WHERE id = $1`, id)
+	return user, translate(err)
+}
+
+func (r *userRepository) CreateOwner(ctx context.Context, user *domain.User, pharmacy *domain.Pharmacy) error {
+	return inTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		if err := tx.QueryRowxContext(ctx, `INSERT INTO users (username, email, password, role) VALUES ($1, $2, $3, $4) RETURNING id`,
+			user.Username, user.Email, user.Password, user.Role).Scan(&user.ID); err != nil {
+			return translate(err)
+		}
+		ownerID := int64(user.ID)
+		pharmacy.OwnerID = &ownerID
+		if err := tx.QueryRowxContext(ctx, `INSERT INTO pharmacies (name, address, location, owner_id) VALUES ($1, $2, $3, $4) RETURNING id, created_at`,
+			pharmacy.Name, pharmacy.Address, pharmacy.Location, ownerID).Scan(&pharmacy.ID, &pharmacy.CreatedAt); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE users SET pharmacy_id = $1 WHERE id = $2`, pharmacy.ID, user.ID); err != nil {
+			return err
+		}
+		user.PharmacyID = &pharmacy.ID
+		return nil
+	})
+}
+
+func (r *userRepository) CreateEmployee(ctx context.Context, user *domain.User) error {
+	return inTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var exists bool
+		if err := tx.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM pharmacies WHERE id = $1)`, user.PharmacyID); err != nil {
+			return err
+		}
+		if !exists {
+			return service.ErrNotFound
+		}
+		err := tx.QueryRowxContext(ctx, `INSERT INTO users (username, email, password, role, pharmacy_id) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+			user.Username, user.Email, user.Password, user.Role, user.PharmacyID).Scan(&user.ID)
+		return translate(err)
+	})
+}
+
+func (r *userRepository) UpdatePassword(ctx context.Context, userID int64, hash string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE users SET password = $1 WHERE id = $2`, hash, userID)
+	return err
+}
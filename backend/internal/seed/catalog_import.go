@@ -0,0 +1,275 @@
+package seed
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"medeasy/m/domain"
+)
+
+// CatalogRow is one brand of a catalog CSV and the line it was read from.
+type CatalogRow struct {
+	Line int
+	domain.Medicine
+}
+
+// CatalogDiff is what importing a catalog CSV would change, by brand id.
+type CatalogDiff struct {
+	Added   []CatalogRow
+	Changed []CatalogChange
+	// Removed lists brands missing from the CSV. They are flagged as
+	// discontinued rather than deleted.
+	Removed []domain.Medicine
+}
+
+// CatalogChange is a brand whose attributes differ from the CSV. A
+// discontinued brand that reappears is changed with the field "discontinued".
+type CatalogChange struct {
+	Before domain.Medicine
+	After  CatalogRow
+	Fields []string
+}
+
+// Empty reports whether the import would change nothing.
+func (d CatalogDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Removed) == 0
+}
+
+// fingerprint identifies the changes a diff makes and the rows it overwrites.
+func (d CatalogDiff) fingerprint() string {
+	var b strings.Builder
+	for _, row := range d.Added {
+		fmt.Fprintf(&b, "+%d\n", row.BrandID)
+	}
+	for _, change := range d.Changed {
+		before := change.Before
+		fmt.Fprintf(&b, "~%d %q %q %q %q %q %q %q %q %q %q\n", before.ID, change.Fields, before.BrandName, before.Type,
+			before.GenericName, before.Manufacturer, before.Slug, before.DosageForm, before.Strength, before.PackageContainer, before.PackageSize)
+	}
+	for _, medicine := range d.Removed {
+		fmt.Fprintf(&b, "-%d\n", medicine.ID)
+	}
+	return b.String()
+}
+
+// ErrCatalogChanged means the catalog was modified between computing a diff
+// and applying it.
+var ErrCatalogChanged = errors.New("the catalog changed since the diff was computed; run the import again")
+
+// ReadCatalog reads a whole catalog CSV for import. Unlike LoadMedicines it
+// rejects malformed rows, since every brand left out would be discontinued.
+func ReadCatalog(csvPath string) ([]CatalogRow, error) {
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open medicine catalog %s: %w", csvPath, err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	if _, err := reader.Read(); err != nil {
+		return nil, fmt.Errorf("unable to read medicine header: %w", err)
+	}
+	var rows []CatalogRow
+	lines := make(map[int64]int)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read medicine catalog: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) < 10 {
+			return nil, fmt.Errorf("line %d: expected 10 columns, got %d", line, len(record))
+		}
+		for i := range record {
+			record[i] = strings.TrimSpace(record[i])
+		}
+		brandID, err := strconv.ParseInt(record[0], 10, 64)
+		if err != nil || brandID <= 0 {
+			return nil, fmt.Errorf("line %d: invalid brand id %q", line, record[0])
+		}
+		if record[1] == "" {
+			return nil, fmt.Errorf("line %d: brand %d has no name", line, brandID)
+		}
+		if first, ok := lines[brandID]; ok {
+			return nil, fmt.Errorf("line %d: brand %d is already listed on line %d", line, brandID, first)
+		}
+		lines[brandID] = line
+		rows = append(rows, CatalogRow{Line: line, Medicine: domain.Medicine{
+			BrandID:          brandID,
+			BrandName:        record[1],
+			Type:             record[2],
+			Slug:             record[3],
+			DosageForm:       record[4],
+			GenericName:      record[5],
+			Strength:         record[6],
+			Manufacturer:     record[7],
+			PackageContainer: record[8],
+			PackageSize:      record[9],
+		}})
+	}
+	return rows, nil
+}
+
+// DiffCatalog compares rows, a full catalog, with the medicines table.
+// Medicines without a brand id are not part of the catalog and are ignored.
+func DiffCatalog(ctx context.Context, db sqlx.QueryerContext, rows []CatalogRow) (CatalogDiff, error) {
+	var existing []domain.Medicine
+	err := sqlx.SelectContext(ctx, db, &existing, `SELECT id, brand_id, brand_name, COALESCE(type, '') AS type,
+		COALESCE(generic_name, '') AS generic_name, COALESCE(manufacturer, '') AS manufacturer,
+		COALESCE(slug, '') AS slug, COALESCE(dosage_form, '') AS dosage_form, COALESCE(strength, '') AS strength,
+		COALESCE(package_container, '') AS package_container, COALESCE(package_size, '') AS package_size, discontinued_at
+		FROM medicines WHERE brand_id IS NOT NULL ORDER BY brand_id`)
+	if err != nil {
+		return CatalogDiff{}, fmt.Errorf("unable to read medicines: %w", err)
+	}
+	byBrand := make(map[int64]domain.Medicine, len(existing))
+	for _, medicine := range existing {
+		byBrand[medicine.BrandID] = medicine
+	}
+
+	var diff CatalogDiff
+	listed := make(map[int64]bool, len(rows))
+	for _, row := range rows {
+		listed[row.BrandID] = true
+		before, ok := byBrand[row.BrandID]
+		if !ok {
+			diff.Added = append(diff.Added, row)
+			continue
+		}
+		if fields := changedFields(before, row.Medicine); len(fields) > 0 {
+			diff.Changed = append(diff.Changed, CatalogChange{Before: before, After: row, Fields: fields})
+		}
+	}
+	for _, medicine := range existing {
+		if !listed[medicine.BrandID] && medicine.DiscontinuedAt == nil {
+			diff.Removed = append(diff.Removed, medicine)
+		}
+	}
+	return diff, nil
+}
+
+func changedFields(before, after domain.Medicine) []string {
+	var fields []string
+	compare := func(name, a, b string) {
+		if a != b {
+			fields = append(fields, name)
+		}
+	}
+	compare("brand_name", before.BrandName, after.BrandName)
+	compare("type", before.Type, after.Type)
+	compare("generic_name", before.GenericName, after.GenericName)
+	compare("manufacturer", before.Manufacturer, after.Manufacturer)
+	compare("slug", before.Slug, after.Slug)
+	compare("dosage_form", before.DosageForm, after.DosageForm)
+	compare("strength", before.Strength, after.Strength)
+	compare("package_container", before.PackageContainer, after.PackageContainer)
+	compare("package_size", before.PackageSize, after.PackageSize)
+	if before.DiscontinuedAt != nil {
+		fields = append(fields, "discontinued")
+	}
+	return fields
+}
+
+// ImportResult reports an applied catalog import.
+type ImportResult struct {
+	Packs int
+	// Classified counts medicines whose schedule changed.
+	Classified int
+	Failures   []PackFailure
+}
+
+// ApplyCatalog applies the import of rows in one transaction, provided it
+// would still make exactly the confirmed changes; otherwise it returns
+// ErrCatalogChanged. Packs are reloaded for added brands and for brands
+// whose package text changed, and medicines are classified from the stored
+// ingredient schedules.
+func ApplyCatalog(ctx context.Context, db *sqlx.DB, rows []CatalogRow, confirmed CatalogDiff) (ImportResult, error) {
+	var result ImportResult
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return result, fmt.Errorf("unable to start catalog import: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Keeps other catalog writers, such as a concurrent seed, out until commit.
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE medicines IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return result, fmt.Errorf("unable to lock medicines: %w", err)
+	}
+	diff, err := DiffCatalog(ctx, tx, rows)
+	if err != nil {
+		return result, err
+	}
+	if diff.fingerprint() != confirmed.fingerprint() {
+		return result, ErrCatalogChanged
+	}
+
+	var repack []CatalogRow
+	for _, row := range diff.Added {
+		m := row.Medicine
+		if _, err := tx.ExecContext(ctx, `INSERT INTO medicines (brand_id, brand_name, type, generic_name, manufacturer, slug, dosage_form, strength, package_container, package_size)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			m.BrandID, m.BrandName, m.Type, m.GenericName, m.Manufacturer, m.Slug, m.DosageForm, m.Strength, m.PackageContainer, m.PackageSize); err != nil {
+			return result, fmt.Errorf("unable to add brand %d: %w", m.BrandID, err)
+		}
+		repack = append(repack, row)
+	}
+	for _, change := range diff.Changed {
+		m := change.After.Medicine
+		if _, err := tx.ExecContext(ctx, `UPDATE medicines SET brand_name = $2, type = $3, generic_name = $4, manufacturer = $5, slug = $6,
+			dosage_form = $7, strength = $8, package_container = $9, package_size = $10, discontinued_at = NULL
+			WHERE brand_id = $1`,
+			m.BrandID, m.BrandName, m.Type, m.GenericName, m.Manufacturer, m.Slug, m.DosageForm, m.Strength, m.PackageContainer, m.PackageSize); err != nil {
+			return result, fmt.Errorf("unable to update brand %d: %w", m.BrandID, err)
+		}
+		if change.Before.PackageContainer != m.PackageContainer {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM medicine_packs WHERE medicine_id = $1`, change.Before.ID); err != nil {
+				return result, fmt.Errorf("unable to clear packs of brand %d: %w", m.BrandID, err)
+			}
+			repack = append(repack, change.After)
+		}
+	}
+	for _, medicine := range diff.Removed {
+		if _, err := tx.ExecContext(ctx, `UPDATE medicines SET discontinued_at = NOW() WHERE id = $1`, medicine.ID); err != nil {
+			return result, fmt.Errorf("unable to discontinue brand %d: %w", medicine.BrandID, err)
+		}
+	}
+
+	for _, row := range repack {
+		packs, err := ParsePackage(row.PackageContainer)
+		if err != nil {
+			result.Failures = append(result.Failures, PackFailure{Line: row.Line, BrandID: strconv.FormatInt(row.BrandID, 10), Text: row.PackageContainer, Err: err})
+			continue
+		}
+		for _, pack := range packs {
+			if _, err := tx.ExecContext(ctx, `INSERT INTO medicine_packs (medicine_id, description, units, mrp)
+				SELECT id, $2, $3, $4 FROM medicines WHERE brand_id = $1`,
+				row.BrandID, pack.Description, pack.Units, pack.MRP); err != nil {
+				return result, fmt.Errorf("unable to add packs of brand %d: %w", row.BrandID, err)
+			}
+			result.Packs++
+		}
+	}
+
+	// Added brands and changed generics are classified before commit.
+	if result.Classified, err = classifyMedicines(ctx, tx); err != nil {
+		return result, err
+	}
+	if err := markLoaded(ctx, tx, SeedMedicines, ""); err != nil {
+		return result, fmt.Errorf("unable to record catalog import: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return ImportResult{}, fmt.Errorf("unable to commit catalog import: %w", err)
+	}
+	return result, nil
+}
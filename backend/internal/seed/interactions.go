@@ -0,0 +1,96 @@
+package seed
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"medeasy/m/domain"
+)
+
+// LoadInteractions replaces the drug interaction knowledge base with the
+// CSV at csvPath, whose columns are ingredient_a, ingredient_b, severity and
+// description. Ingredients are single generic ingredients, matched without
+// case against catalog generics and the leading words of their ingredients,
+// so "warfarin" covers "Warfarin Sodium". Any malformed row fails the load.
+func LoadInteractions(db *sqlx.DB, csvPath string) (int, error) {
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return 0, fmt.Errorf("unable to load interactions %s: %w", csvPath, err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	if _, err := reader.Read(); err != nil {
+		return 0, fmt.Errorf("unable to read interaction header: %w", err)
+	}
+	var interactions []domain.DrugInteraction
+	lines := make(map[[2]string]int)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("unable to read interactions: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) < 3 {
+			return 0, fmt.Errorf("line %d: expected ingredient_a, ingredient_b, severity and description", line)
+		}
+		a, b := ingredientName(record[0]), ingredientName(record[1])
+		if a == "" || b == "" || a == b || strings.Contains(a+b, "+") {
+			return 0, fmt.Errorf("line %d: expected two different single ingredients", line)
+		}
+		if b < a {
+			a, b = b, a
+		}
+		severity := strings.ToLower(strings.TrimSpace(record[2]))
+		if severity != domain.SeverityMinor && severity != domain.SeverityModerate && severity != domain.SeveritySevere {
+			return 0, fmt.Errorf("line %d: severity must be minor, moderate or severe", line)
+		}
+		if first, ok := lines[[2]string{a, b}]; ok {
+			return 0, fmt.Errorf("line %d: %s and %s are already listed on line %d", line, a, b, first)
+		}
+		lines[[2]string{a, b}] = line
+		interaction := domain.DrugInteraction{IngredientA: a, IngredientB: b, Severity: severity}
+		if len(record) > 3 {
+			interaction.Description = strings.TrimSpace(record[3])
+		}
+		interactions = append(interactions, interaction)
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("unable to start interaction transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.Exec(`DELETE FROM drug_interactions`); err != nil {
+		return 0, fmt.Errorf("unable to clear interactions: %w", err)
+	}
+	for _, interaction := range interactions {
+		if _, err := tx.Exec(`INSERT INTO drug_interactions (ingredient_a, ingredient_b, severity, description) VALUES ($1, $2, $3, $4)`,
+			interaction.IngredientA, interaction.IngredientB, interaction.Severity, interaction.Description); err != nil {
+			return 0, fmt.Errorf("unable to add interaction %s + %s: %w", interaction.IngredientA, interaction.IngredientB, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("unable to commit interactions: %w", err)
+	}
+	return len(interactions), nil
+}
+
+// InteractionsNeedLoad reports whether the knowledge base is empty.
+func InteractionsNeedLoad(db *sqlx.DB) (bool, error) {
+	var needed bool
+	err := db.Get(&needed, `SELECT NOT EXISTS (SELECT 1 FROM drug_interactions)`)
+	return needed, err
+}
+
+func ingredientName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
@@ -1,77 +1,169 @@
 package seed
 
 import (
+	"context"
 	"encoding/csv"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"strings"
 
 	"github.com/jmoiron/sqlx"
 )
 
-// LoadMedicines ingests the CSV into the medicines table, ignoring duplicates.
-func LoadMedicines(db *sqlx.DB, csvPath string) {
+// LoadResult summarises a catalog load.
+type LoadResult struct {
+	// Medicines counts rows inserted or backfilled.
+	Medicines int
+	// Packs counts packs inserted or repriced.
+	Packs int
+	// Classified counts medicines whose schedule changed.
+	Classified int
+	// Failures lists the rows whose package text could not be parsed. Those
+	// medicines are still loaded, without packs.
+	Failures []PackFailure
+}
+
+// PackFailure is a catalog row whose package prices could not be parsed.
+type PackFailure struct {
+	Line    int
+	BrandID string
+	Text    string
+	Err     error
+}
+
+func (f PackFailure) Error() string {
+	return fmt.Sprintf("line %d (brand %s): %v: %q", f.Line, f.BrandID, f.Err, f.Text)
+}
+
+// LoadMedicines ingests the CSV into the medicines table. Known brands keep
+// their names and only have missing attributes filled in, which backfills
+// catalogs loaded by older versions. Each row's package prices are parsed
+// into medicine_packs.
+func LoadMedicines(db *sqlx.DB, csvPath string) (LoadResult, error) {
+	var result LoadResult
 	file, err := os.Open(csvPath)
 	if err != nil {
-		log.Printf("unable to load medicine catalog %s: %v", csvPath, err)
-		return
+		return result, fmt.Errorf("unable to load medicine catalog %s: %w", csvPath, err)
 	}
 	defer file.Close()
 
 	reader := csv.NewReader(file)
 	// Skip header
 	if _, err := reader.Read(); err != nil {
-		log.Printf("unable to read medicine header: %v", err)
-		return
+		return result, fmt.Errorf("unable to read medicine header: %w", err)
 	}
 
 	tx, err := db.Beginx()
 	if err != nil {
-		log.Printf("unable to start medicine transaction: %v", err)
-		return
+		return result, fmt.Errorf("unable to start medicine transaction: %w", err)
 	}
-	stmt, err := tx.Preparex(`INSERT INTO medicines (brand_id, brand_name, type, generic_name, manufacturer) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (brand_id) DO NOTHING`)
+	stmt, err := tx.Preparex(`INSERT INTO medicines (brand_id, brand_name, type, generic_name, manufacturer, slug, dosage_form, strength, package_container, package_size)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (brand_id) DO UPDATE SET slug = EXCLUDED.slug, dosage_form = EXCLUDED.dosage_form, strength = EXCLUDED.strength,
+			package_container = EXCLUDED.package_container, package_size = EXCLUDED.package_size
+		WHERE medicines.slug IS NULL`)
 	if err != nil {
-		log.Printf("unable to prepare medicine insert: %v", err)
 		_ = tx.Rollback()
-		return
+		return result, fmt.Errorf("unable to prepare medicine insert: %w", err)
 	}
 	defer stmt.Close()
+	packStmt, err := tx.Preparex(`INSERT INTO medicine_packs (medicine_id, description, units, mrp)
+		SELECT id, $2, $3, $4 FROM medicines WHERE brand_id = $1
+		ON CONFLICT (medicine_id, description, units) DO UPDATE SET mrp = EXCLUDED.mrp
+		WHERE medicine_packs.mrp <> EXCLUDED.mrp`)
+	if err != nil {
+		_ = tx.Rollback()
+		return result, fmt.Errorf("unable to prepare medicine pack insert: %w", err)
+	}
+	defer packStmt.Close()
 
-	rows := 0
 	for {
 		record, err := reader.Read()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			log.Printf("unable to read medicine row: %v", err)
+			slog.Warn("unable to read medicine row", slog.String("error", err.Error()))
 			continue
 		}
-		if len(record) < 9 {
+		if len(record) < 10 {
 			continue
 		}
-		brandID := strings.TrimSpace(record[0])
-		brandName := strings.TrimSpace(record[1])
-		medType := strings.TrimSpace(record[2])
-		generic := strings.TrimSpace(record[5])
-		manufacturer := strings.TrimSpace(record[7])
-
+		for i := range record {
+			record[i] = strings.TrimSpace(record[i])
+		}
+		brandID, brandName, container := record[0], record[1], record[8]
 		if brandName == "" {
 			continue
 		}
 
-		if _, err := stmt.Exec(brandID, brandName, medType, generic, manufacturer); err != nil {
-			log.Printf("unable to insert medicine %s: %v", brandName, err)
+		packs, packErr := ParsePackage(container)
+		if packErr != nil {
+			line, _ := reader.FieldPos(8)
+			result.Failures = append(result.Failures, PackFailure{Line: line, BrandID: brandID, Text: container, Err: packErr})
+		}
+
+		// A failed statement aborts the whole transaction in Postgres, so
+		// each row runs under a savepoint that a bad row is rolled back to.
+		if _, err := tx.Exec(`SAVEPOINT medicine_row`); err != nil {
+			_ = tx.Rollback()
+			return LoadResult{}, fmt.Errorf("unable to load medicine catalog: %w", err)
+		}
+		medicines, rowPacks, err := loadRow(stmt, packStmt, record, packs)
+		if err != nil {
+			slog.Warn("unable to insert medicine", slog.String("brand_name", brandName), slog.String("error", err.Error()))
+			_, err = tx.Exec(`ROLLBACK TO SAVEPOINT medicine_row`)
 		} else {
-			rows++
+			result.Medicines += medicines
+			result.Packs += rowPacks
+			_, err = tx.Exec(`RELEASE SAVEPOINT medicine_row`)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return LoadResult{}, fmt.Errorf("unable to load medicine catalog: %w", err)
 		}
 	}
 
+	// Inserted brands start as otc until classified from the stored
+	// ingredient schedules.
+	if result.Classified, err = classifyMedicines(context.Background(), tx); err != nil {
+		_ = tx.Rollback()
+		return LoadResult{}, err
+	}
+	if err := markLoaded(context.Background(), tx, SeedMedicines, csvPath); err != nil {
+		_ = tx.Rollback()
+		return LoadResult{}, fmt.Errorf("unable to record medicine seed: %w", err)
+	}
 	if err := tx.Commit(); err != nil {
-		log.Printf("unable to commit medicine seed: %v", err)
-	} else {
-		log.Printf("seeded medicine catalog with %d rows", rows)
+		return LoadResult{}, fmt.Errorf("unable to commit medicine seed: %w", err)
+	}
+	return result, nil
+}
+
+// loadRow upserts one catalog row and its parsed packs, returning how many
+// medicines and packs it changed.
+func loadRow(stmt, packStmt *sqlx.Stmt, record []string, packs []Pack) (int, int, error) {
+	brandID, brandName, medType, slug, dosageForm := record[0], record[1], record[2], record[3], record[4]
+	generic, strength, manufacturer, container, packageSize := record[5], record[6], record[7], record[8], record[9]
+
+	res, err := stmt.Exec(brandID, brandName, medType, generic, manufacturer, slug, dosageForm, strength, container, packageSize)
+	if err != nil {
+		return 0, 0, err
+	}
+	var medicines, changed int
+	if n, err := res.RowsAffected(); err == nil {
+		medicines = int(n)
+	}
+	for _, pack := range packs {
+		res, err := packStmt.Exec(brandID, pack.Description, pack.Units, pack.MRP)
+		if err != nil {
+			return 0, 0, fmt.Errorf("pack %q: %w", pack.Description, err)
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			changed += int(n)
+		}
 	}
+	return medicines, changed, nil
 }
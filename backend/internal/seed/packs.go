@@ -0,0 +1,109 @@
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"medeasy/m/domain"
+)
+
+// Pack is one priced pack parsed from the catalog's package text.
+type Pack struct {
+	Description string
+	// Units is how many dispensing units (tablets, capsules) the pack holds;
+	// bottles, tubes, vials and other single containers count as one.
+	Units int64
+	MRP   domain.Money
+}
+
+var (
+	// packPrice matches an MRP such as "৳ 40.12" or "৳ 9,900.00".
+	packPrice = regexp.MustCompile(`৳\s*(\d{1,3}(?:,\d{3})+\.\d{2}|\d+\.\d{2})`)
+	// countedPack matches packs of N units: "10's pack", "30's tin",
+	// "28 tablet pack", "4 capsule strip".
+	countedPack = regexp.MustCompile(`^(\d+)(?:'s| (?:tablet|capsule)s?) \w+$`)
+	spaces      = regexp.MustCompile(`\s+`)
+)
+
+// unpriced are package texts that carry no MRP on purpose.
+var unpriced = map[string]bool{
+	"":                  true,
+	"price unavailable": true,
+	"not for sale":      true,
+}
+
+// ParsePackage extracts the priced packs from a catalog package text such as
+// "Unit Price: ৳ 6.50,(15's pack: ৳ 97.50)". Texts that state no price, like
+// "Not for sale", yield no packs and no error.
+func ParsePackage(text string) ([]Pack, error) {
+	text = strings.TrimSpace(text)
+	matches := packPrice.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		for _, part := range strings.Split(text, ",") {
+			if !unpriced[strings.ToLower(strings.TrimSpace(part))] {
+				return nil, errors.New("no price found")
+			}
+		}
+		return nil, nil
+	}
+
+	var packs []Pack
+	seen := make(map[string]bool)
+	pos := 0
+	for _, m := range matches {
+		description, err := packDescription(text[pos:m[0]])
+		if err != nil {
+			return nil, err
+		}
+		mrp, err := domain.ParseMoney(strings.ReplaceAll(text[m[2]:m[3]], ",", ""))
+		if err != nil {
+			return nil, err
+		}
+		pos = m[1]
+
+		pack := Pack{Description: description, Units: packUnits(description), MRP: mrp}
+		key := fmt.Sprintf("%s\x00%d", pack.Description, pack.Units)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		packs = append(packs, pack)
+	}
+	if rest := strings.Trim(text[pos:], " ,)"); rest != "" {
+		return nil, fmt.Errorf("unexpected text after last price: %q", rest)
+	}
+	return packs, nil
+}
+
+// packDescription cleans the text before a price: the separators left over
+// from the previous entry, the parenthesis some entries are wrapped in and
+// the colon before the price.
+func packDescription(segment string) (string, error) {
+	description := strings.TrimLeft(segment, " ,)")
+	description = strings.TrimSpace(description)
+	if !strings.HasSuffix(description, ":") {
+		return "", fmt.Errorf("expected \"description:\" before price, got %q", segment)
+	}
+	description = strings.TrimSpace(strings.TrimSuffix(description, ":"))
+	// "(15's pack" is wrapped; "(1 & 60) tablet kit" is not.
+	if strings.HasPrefix(description, "(") && !strings.Contains(description, ")") {
+		description = strings.TrimSpace(description[1:])
+	}
+	description = spaces.ReplaceAllString(description, " ")
+	if description == "" {
+		return "", fmt.Errorf("missing pack description in %q", segment)
+	}
+	return description, nil
+}
+
+func packUnits(description string) int64 {
+	if m := countedPack.FindStringSubmatch(description); m != nil {
+		if units, err := strconv.ParseInt(m[1], 10, 64); err == nil && units > 0 {
+			return units
+		}
+	}
+	return 1
+}
@@ -0,0 +1,119 @@
+package seed
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"medeasy/m/domain"
+)
+
+// ApplySchedules classifies every medicine from the CSV at csvPath, whose
+// columns are ingredient and schedule. A medicine takes the most restricted
+// schedule of its ingredients, matched like interaction ingredients, and is
+// otc when none is listed. The ingredients are kept to classify custom
+// medicines when they are sold. It returns how many medicines changed
+// schedule.
+func ApplySchedules(db *sqlx.DB, csvPath string) (int, error) {
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return 0, fmt.Errorf("unable to load schedules %s: %w", csvPath, err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	if _, err := reader.Read(); err != nil {
+		return 0, fmt.Errorf("unable to read schedule header: %w", err)
+	}
+	schedules := make(map[string]string)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("unable to read schedules: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) < 2 {
+			return 0, fmt.Errorf("line %d: expected ingredient and schedule", line)
+		}
+		ingredient := ingredientName(record[0])
+		if ingredient == "" || strings.Contains(ingredient, "+") {
+			return 0, fmt.Errorf("line %d: expected a single ingredient", line)
+		}
+		schedule := strings.ToLower(strings.TrimSpace(record[1]))
+		if !domain.IsSchedule(schedule) {
+			return 0, fmt.Errorf("line %d: schedule must be otc, prescription or controlled", line)
+		}
+		if _, ok := schedules[ingredient]; ok {
+			return 0, fmt.Errorf("line %d: %s is listed twice", line, ingredient)
+		}
+		schedules[ingredient] = schedule
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("unable to start schedule transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.Exec(`DELETE FROM ingredient_schedules`); err != nil {
+		return 0, fmt.Errorf("unable to clear ingredient schedules: %w", err)
+	}
+	for ingredient, schedule := range schedules {
+		if _, err := tx.Exec(`INSERT INTO ingredient_schedules (ingredient, schedule) VALUES ($1, $2)`, ingredient, schedule); err != nil {
+			return 0, fmt.Errorf("unable to store schedule of %s: %w", ingredient, err)
+		}
+	}
+	changed, err := classifyMedicines(context.Background(), tx)
+	if err != nil {
+		return 0, err
+	}
+	if err := markLoaded(context.Background(), tx, SeedSchedules, csvPath); err != nil {
+		return 0, fmt.Errorf("unable to record schedules: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("unable to commit schedules: %w", err)
+	}
+	return changed, nil
+}
+
+// classifyMedicines gives every medicine the most restricted schedule that
+// ingredient_schedules lists for its ingredients, or otc, and returns how
+// many changed. Catalog writes call it in their own transaction, so no
+// medicine is committed unclassified.
+func classifyMedicines(ctx context.Context, tx *sqlx.Tx) (int, error) {
+	var listed []struct {
+		Ingredient string `db:"ingredient"`
+		Schedule   string `db:"schedule"`
+	}
+	if err := tx.SelectContext(ctx, &listed, `SELECT ingredient, schedule FROM ingredient_schedules`); err != nil {
+		return 0, fmt.Errorf("unable to read ingredient schedules: %w", err)
+	}
+	schedules := make(map[string]string, len(listed))
+	for _, row := range listed {
+		schedules[row.Ingredient] = row.Schedule
+	}
+
+	var medicines []domain.Medicine
+	if err := tx.SelectContext(ctx, &medicines, `SELECT id, COALESCE(generic_name, '') AS generic_name, schedule FROM medicines FOR UPDATE`); err != nil {
+		return 0, fmt.Errorf("unable to read medicines: %w", err)
+	}
+	changed := 0
+	for _, medicine := range medicines {
+		schedule := domain.ScheduleOf(medicine.GenericName, schedules)
+		if schedule == medicine.Schedule {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE medicines SET schedule = $2 WHERE id = $1`, medicine.ID, schedule); err != nil {
+			return 0, fmt.Errorf("unable to classify medicine %d: %w", medicine.ID, err)
+		}
+		changed++
+	}
+	return changed, nil
+}
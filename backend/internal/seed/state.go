@@ -0,0 +1,29 @@
+package seed
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Seeds recorded in seed_state once loaded.
+const (
+	SeedMedicines = "medicines"
+	SeedSchedules = "schedules"
+)
+
+// Loaded reports whether the named seed has been loaded, by the server on
+// first boot or with a CLI command.
+func Loaded(db *sqlx.DB, name string) (bool, error) {
+	var loaded bool
+	err := db.Get(&loaded, `SELECT EXISTS (SELECT 1 FROM seed_state WHERE name = $1)`, name)
+	return loaded, err
+}
+
+// markLoaded records that the named seed was loaded from source, as part of
+// the transaction that loaded it.
+func markLoaded(ctx context.Context, tx sqlx.ExecerContext, name, source string) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO seed_state (name, source) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET source = EXCLUDED.source, loaded_at = NOW()`, name, source)
+	return err
+}
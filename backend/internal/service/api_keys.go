@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"medeasy/m/domain"
+)
+
+// APIKeys manages a pharmacy's integration credentials.
+type APIKeys interface {
+	// Create returns the stored key together with its plaintext, which is
+	// never retrievable again.
+	Create(ctx context.Context, in NewAPIKey) (domain.APIKey, string, error)
+	List(ctx context.Context, pharmacyID int64) ([]domain.APIKey, error)
+	Revoke(ctx context.Context, pharmacyID, id int64) (time.Time, error)
+}
+
+// NewAPIKey describes a key to issue.
+type NewAPIKey struct {
+	PharmacyID int64
+	CreatedBy  int64
+	Name       string
+	Scopes     []string
+	// ExpiresAt is an optional RFC 3339 timestamp.
+	ExpiresAt string
+}
+
+type apiKeyService struct {
+	apiKeys APIKeyRepository
+	now     func() time.Time
+}
+
+func (s *apiKeyService) Create(ctx context.Context, in NewAPIKey) (domain.APIKey, string, error) {
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		return domain.APIKey{}, "", invalid("name is required")
+	}
+	if len(in.Scopes) == 0 {
+		return domain.APIKey{}, "", invalid("at least one scope is required")
+	}
+	scopes := domain.ScopeList{}
+	for _, scope := range in.Scopes {
+		if !validScope(scope) {
+			return domain.APIKey{}, "", invalid("unknown scope " + scope)
+		}
+		if !scopes.Contains(scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	var expiresAt *time.Time
+	if in.ExpiresAt != "" {
+		t, err := time.Parse(time.RFC3339, in.ExpiresAt)
+		if err != nil {
+			return domain.APIKey{}, "", invalid("expires_at must be an RFC 3339 timestamp")
+		}
+		if !t.After(s.now()) {
+			return domain.APIKey{}, "", invalid("expires_at must be in the future")
+		}
+		expiresAt = &t
+	}
+
+	key, prefix, err := generateAPIKey()
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+	apiKey := domain.APIKey{
+		PharmacyID: in.PharmacyID,
+		Name:       name,
+		Prefix:     prefix,
+		SecretHash: hashAPIKey(key),
+		Scopes:     scopes,
+		CreatedBy:  &in.CreatedBy,
+		ExpiresAt:  expiresAt,
+	}
+	if err := s.apiKeys.Create(ctx, &apiKey); err != nil {
+		return domain.APIKey{}, "", err
+	}
+	return apiKey, key, nil
+}
+
+func (s *apiKeyService) List(ctx context.Context, pharmacyID int64) ([]domain.APIKey, error) {
+	return s.apiKeys.List(ctx, pharmacyID)
+}
+
+func (s *apiKeyService) Revoke(ctx context.Context, pharmacyID, id int64) (time.Time, error) {
+	revokedAt, err := s.apiKeys.Revoke(ctx, id, pharmacyID)
+	if errors.Is(err, ErrNotFound) {
+		return time.Time{}, notFound("api key not found")
+	}
+	return revokedAt, err
+}
+
+// generateAPIKey returns a new plaintext key and its lookup prefix.
+func generateAPIKey() (key, prefix string, err error) {
+	idBytes := make([]byte, 6)
+	if _, err = rand.Read(idBytes); err != nil {
+		return "", "", err
+	}
+	secretBytes := make([]byte, 32)
+	if _, err = rand.Read(secretBytes); err != nil {
+		return "", "", err
+	}
+	prefix = hex.EncodeToString(idBytes)
+	key = APIKeyPrefix + prefix + "_" + base64.RawURLEncoding.EncodeToString(secretBytes)
+	return key, prefix, nil
+}
+
+func validScope(scope string) bool {
+	for _, s := range domain.APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+
+	"medeasy/m/domain"
+)
+
+// User roles. Pharmacists work like employees and may also override severe
+// drug interactions; the role is assigned with the CLI rather than taken at
+// registration.
+const (
+	RoleOwner      = "owner"
+	RoleEmployee   = "employee"
+	RolePharmacist = "pharmacist"
+)
+
+// APIKeyPrefix marks a credential as an API key rather than a JWT.
+const APIKeyPrefix = "mek_"
+
+// Auth registers and authenticates users and API keys.
+type Auth interface {
+	Register(ctx context.Context, in RegisterInput) (AuthResult, error)
+	Login(ctx context.Context, email, password string) (AuthResult, error)
+	ResetPassword(ctx context.Context, userID int64, newPassword string) error
+	// ParseToken validates a user JWT and returns the caller's current role
+	// and pharmacy, refusing accounts disabled since the token was issued.
+	ParseToken(ctx context.Context, token string) (Claims, error)
+	AuthenticateAPIKey(ctx context.Context, key string) (domain.APIKey, error)
+}
+
+// RegisterInput describes a new owner (with their pharmacy) or employee.
+type RegisterInput struct {
+	Username         string
+	Email            string
+	Password         string
+	Role             string
+	PharmacyID       int64
+	PharmacyName     string
+	PharmacyAddress  string
+	PharmacyLocation string
+}
+
+// AuthResult is a signed-in user with their token.
+type AuthResult struct {
+	Token    string
+	User     domain.User
+	Pharmacy *domain.Pharmacy
+}
+
+// Claims identify the caller behind a user JWT.
+type Claims struct {
+	UserID     int64
+	Role       string
+	PharmacyID int64
+}
+
+type authClaims struct {
+	UserID     int64  `json:"user_id"`
+	Role       string `json:"role"`
+	PharmacyID int64  `json:"pharmacy_id"`
+	jwt.RegisteredClaims
+}
+
+var (
+	errInvalidAPIKey = unauthorized("invalid api key", "invalid_api_key")
+	errDisabled      = &Error{Kind: KindForbidden, Message: "account is disabled", Reason: "disabled"}
+)
+
+type authService struct {
+	users    UserRepository
+	apiKeys  APIKeyRepository
+	secret   []byte
+	tokenTTL time.Duration
+	now      func() time.Time
+}
+
+func (s *authService) Register(ctx context.Context, in RegisterInput) (AuthResult, error) {
+	if in.Username == "" || in.Email == "" || in.Password == "" || in.Role == "" {
+		return AuthResult{}, invalid("username, email, password and role are required")
+	}
+	if in.Role != RoleOwner && in.Role != RoleEmployee {
+		return AuthResult{}, invalid("role must be owner or employee")
+	}
+	if in.Role == RoleOwner && strings.TrimSpace(in.PharmacyName) == "" {
+		return AuthResult{}, invalid("pharmacy_name is required for owners")
+	}
+	if in.Role == RoleEmployee && in.PharmacyID <= 0 {
+		return AuthResult{}, invalid("pharmacy_id is required for employees")
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(in.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return AuthResult{}, err
+	}
+	user := domain.User{
+		Username: in.Username,
+		Email:    strings.ToLower(in.Email),
+		Password: string(hashed),
+		Role:     in.Role,
+	}
+
+	var pharmacy *domain.Pharmacy
+	if in.Role == RoleOwner {
+		pharmacy = &domain.Pharmacy{Name: in.PharmacyName, Address: in.PharmacyAddress, Location: in.PharmacyLocation}
+		err = s.users.CreateOwner(ctx, &user, pharmacy)
+	} else {
+		user.PharmacyID = &in.PharmacyID
+		err = s.users.CreateEmployee(ctx, &user)
+	}
+	switch {
+	case errors.Is(err, ErrDuplicate):
+		return AuthResult{}, conflict("email already exists")
+	case errors.Is(err, ErrNotFound):
+		return AuthResult{}, invalid("invalid pharmacy_id for employee")
+	case err != nil:
+		return AuthResult{}, err
+	}
+
+	token, err := s.issueToken(int64(user.ID), user.Role, *user.PharmacyID)
+	if err != nil {
+		return AuthResult{}, err
+	}
+	user.Password = ""
+	return AuthResult{Token: token, User: user, Pharmacy: pharmacy}, nil
+}
+
+func (s *authService) Login(ctx context.Context, email, password string) (AuthResult, error) {
+	user, err := s.users.ByEmail(ctx, strings.ToLower(email))
+	if errors.Is(err, ErrNotFound) {
+		return AuthResult{}, unauthorized("invalid credentials", "unknown_user")
+	}
+	if err != nil {
+		return AuthResult{}, err
+	}
+	if user.DisabledAt != nil {
+		return AuthResult{}, errDisabled
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		return AuthResult{}, unauthorized("invalid credentials", "bad_password")
+	}
+	if user.PharmacyID == nil || *user.PharmacyID == 0 {
+		return AuthResult{}, &Error{Kind: KindForbidden, Message: "user is not linked to a pharmacy", Reason: "no_pharmacy"}
+	}
+
+	token, err := s.issueToken(int64(user.ID), user.Role, *user.PharmacyID)
+	if err != nil {
+		return AuthResult{}, err
+	}
+	user.Password = ""
+	return AuthResult{Token: token, User: user}, nil
+}
+
+func (s *authService) ResetPassword(ctx context.Context, userID int64, newPassword string) error {
+	if newPassword == "" {
+		return invalid("new_password is required")
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return s.users.UpdatePassword(ctx, userID, string(hashed))
+}
+
+func (s *authService) issueToken(userID int64, role string, pharmacyID int64) (string, error) {
+	now := s.now()
+	claims := authClaims{
+		UserID:     userID,
+		Role:       role,
+		PharmacyID: pharmacyID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.tokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+}
+
+func (s *authService) ParseToken(ctx context.Context, tokenString string) (Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &authClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, errors.New("unexpected signing method")
+		}
+		return s.secret, nil
+	})
+	if err != nil || !token.Valid {
+		return Claims{}, unauthorized("invalid token", "invalid_token")
+	}
+	claims, ok := token.Claims.(*authClaims)
+	if !ok {
+		return Claims{}, unauthorized("invalid token claims", "invalid_token")
+	}
+	// Tokens outlive account changes, so the account is read on every
+	// request: disabling a user takes effect immediately.
+	user, err := s.users.ByID(ctx, claims.UserID)
+	if errors.Is(err, ErrNotFound) {
+		return Claims{}, unauthorized("invalid token", "invalid_token")
+	}
+	if err != nil {
+		return Claims{}, err
+	}
+	if user.DisabledAt != nil {
+		return Claims{}, errDisabled
+	}
+	if user.PharmacyID == nil || *user.PharmacyID <= 0 {
+		return Claims{}, forbidden("user is not linked to a pharmacy")
+	}
+	return Claims{UserID: claims.UserID, Role: user.Role, PharmacyID: *user.PharmacyID}, nil
+}
+
+func (s *authService) AuthenticateAPIKey(ctx context.Context, key string) (domain.APIKey, error) {
+	rest, ok := strings.CutPrefix(key, APIKeyPrefix)
+	if !ok {
+		return domain.APIKey{}, errInvalidAPIKey
+	}
+	prefix, _, ok := strings.Cut(rest, "_")
+	if !ok || prefix == "" {
+		return domain.APIKey{}, errInvalidAPIKey
+	}
+
+	apiKey, err := s.apiKeys.ByPrefix(ctx, prefix)
+	if errors.Is(err, ErrNotFound) {
+		return domain.APIKey{}, errInvalidAPIKey
+	}
+	if err != nil {
+		return domain.APIKey{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(apiKey.SecretHash), []byte(hashAPIKey(key))) != 1 {
+		return domain.APIKey{}, errInvalidAPIKey
+	}
+	if !apiKey.Active(s.now()) {
+		return domain.APIKey{}, errInvalidAPIKey
+	}
+	_ = s.apiKeys.TouchLastUsed(ctx, apiKey.ID)
+	return apiKey, nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
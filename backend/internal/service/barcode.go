@@ -0,0 +1,235 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Barcode is what a scanned code says about a pack. Linear EAN-13, UPC-A and
+// EAN-8 codes carry only the GTIN; GS1 DataMatrix and GS1-128 codes may add
+// the batch, expiry and serial number.
+type Barcode struct {
+	// GTIN is normalised to 14 digits, so the EAN-13 and GS1 forms of the
+	// same code compare equal.
+	GTIN   string
+	Batch  string
+	Expiry *time.Time
+	Serial string
+}
+
+// groupSeparator is FNC1 as transmitted by scanners: it ends a
+// variable-length element.
+const groupSeparator = '\x1d'
+
+// gs1Fixed lists the predefined-length application identifiers, with the
+// length of their data.
+var gs1Fixed = map[string]int{
+	"00": 18, "01": 14, "02": 14,
+	"11": 6, "12": 6, "13": 6, "15": 6, "16": 6, "17": 6,
+	"20": 2,
+}
+
+// gs1Variable lists the variable-length application identifiers found on
+// medicine packs, with their maximum data length.
+var gs1Variable = map[string]int{
+	"10": 20, "21": 20, "22": 20, "30": 8, "37": 8,
+	"240": 30, "241": 30,
+	"710": 20, "711": 20, "712": 20, "713": 20, "714": 20, "715": 20,
+}
+
+// parseBarcode reads a scanned code: plain GTIN digits, a GS1 element string
+// with group separators (optionally prefixed by a symbology identifier such
+// as "]d2"), or the human-readable form "(01)…(17)…(10)…". Two-digit expiry
+// years are placed within 50 years of year.
+func parseBarcode(code string, year int) (Barcode, error) {
+	code = strings.TrimSpace(code)
+	if strings.HasPrefix(code, "]") && len(code) >= 3 {
+		code = code[3:]
+	}
+	code = strings.TrimPrefix(code, string(groupSeparator))
+	if code == "" {
+		return Barcode{}, invalid("barcode is required")
+	}
+	if isDigits(code) && (len(code) == 8 || len(code) == 12 || len(code) == 13 || len(code) == 14) {
+		gtin, err := normaliseGTIN(code)
+		return Barcode{GTIN: gtin}, err
+	}
+
+	var b Barcode
+	elements, err := gs1Elements(code)
+	if err != nil {
+		return Barcode{}, err
+	}
+	for _, element := range elements {
+		ai, data := element[0], element[1]
+		switch ai {
+		case "01":
+			if b.GTIN, err = normaliseGTIN(data); err != nil {
+				return Barcode{}, err
+			}
+		case "10":
+			b.Batch = data
+		case "17":
+			expiry, err := gs1Date(data, year)
+			if err != nil {
+				return Barcode{}, err
+			}
+			b.Expiry = &expiry
+		case "21":
+			b.Serial = data
+		}
+	}
+	if b.GTIN == "" {
+		return Barcode{}, invalid("barcode has no GTIN (01)")
+	}
+	return b, nil
+}
+
+// gs1Elements splits an element string into application identifier and data
+// pairs.
+func gs1Elements(code string) ([][2]string, error) {
+	var elements [][2]string
+	if strings.HasPrefix(code, "(") {
+		// Human-readable: every identifier is bracketed.
+		for code != "" {
+			end := strings.IndexByte(code, ')')
+			if !strings.HasPrefix(code, "(") || end < 0 {
+				return nil, invalid("malformed GS1 barcode")
+			}
+			ai := code[1:end]
+			code = code[end+1:]
+			next := strings.IndexByte(code, '(')
+			if next < 0 {
+				next = len(code)
+			}
+			if err := checkElement(ai, code[:next]); err != nil {
+				return nil, err
+			}
+			elements = append(elements, [2]string{ai, code[:next]})
+			code = code[next:]
+		}
+		return elements, nil
+	}
+
+	for code != "" {
+		ai, n := gs1Identifier(code)
+		if ai == "" {
+			return nil, invalid(fmt.Sprintf("unsupported GS1 application identifier at %q", code))
+		}
+		code = code[len(ai):]
+		var data string
+		if n > 0 {
+			if len(code) < n {
+				return nil, invalid(fmt.Sprintf("GS1 element (%s) is too short", ai))
+			}
+			data, code = code[:n], code[n:]
+		} else {
+			end := strings.IndexByte(code, groupSeparator)
+			if end < 0 {
+				end = len(code)
+			}
+			data, code = code[:end], code[end:]
+		}
+		code = strings.TrimPrefix(code, string(groupSeparator))
+		if err := checkElement(ai, data); err != nil {
+			return nil, err
+		}
+		elements = append(elements, [2]string{ai, data})
+	}
+	return elements, nil
+}
+
+// gs1Identifier returns the identifier code starts with and its fixed data
+// length, or 0 for variable-length data.
+func gs1Identifier(code string) (string, int) {
+	for _, size := range []int{2, 3} {
+		if len(code) < size {
+			break
+		}
+		ai := code[:size]
+		if n, ok := gs1Fixed[ai]; ok {
+			return ai, n
+		}
+		if _, ok := gs1Variable[ai]; ok {
+			return ai, 0
+		}
+	}
+	return "", 0
+}
+
+func checkElement(ai, data string) error {
+	if n, ok := gs1Fixed[ai]; ok {
+		if len(data) != n || !isDigits(data) {
+			return invalid(fmt.Sprintf("GS1 element (%s) must be %d digits", ai, n))
+		}
+		return nil
+	}
+	limit, ok := gs1Variable[ai]
+	if !ok {
+		return invalid(fmt.Sprintf("unsupported GS1 application identifier (%s)", ai))
+	}
+	if data == "" || len(data) > limit {
+		return invalid(fmt.Sprintf("GS1 element (%s) must be 1 to %d characters", ai, limit))
+	}
+	return nil
+}
+
+// gs1Date reads YYMMDD. Day 00 means the last day of the month.
+func gs1Date(data string, year int) (time.Time, error) {
+	yy, _ := strconv.Atoi(data[:2])
+	month, _ := strconv.Atoi(data[2:4])
+	day, _ := strconv.Atoi(data[4:6])
+	if month < 1 || month > 12 || day > 31 {
+		return time.Time{}, invalid(fmt.Sprintf("invalid GS1 expiry date %s", data))
+	}
+	full := year - year%100 + yy
+	switch {
+	case full > year+50:
+		full -= 100
+	case full <= year-50:
+		full += 100
+	}
+	if day == 0 {
+		return time.Date(full, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC), nil
+	}
+	t := time.Date(full, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Day() != day {
+		return time.Time{}, invalid(fmt.Sprintf("invalid GS1 expiry date %s", data))
+	}
+	return t, nil
+}
+
+// normaliseGTIN pads a GTIN-8, -12, -13 or -14 to 14 digits and checks its
+// check digit.
+func normaliseGTIN(code string) (string, error) {
+	if !isDigits(code) || len(code) > 14 || len(code) < 8 {
+		return "", invalid("a GTIN has 8, 12, 13 or 14 digits")
+	}
+	gtin := strings.Repeat("0", 14-len(code)) + code
+	sum := 0
+	for i := 0; i < 13; i++ {
+		digit := int(gtin[i] - '0')
+		if i%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	if int(gtin[13]-'0') != (10-sum%10)%10 {
+		return "", invalid("barcode check digit does not match")
+	}
+	return gtin, nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBarcode(t *testing.T) {
+	const gtin = "04006381333931"
+	date := func(year int, month time.Month, day int) *time.Time {
+		d := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		return &d
+	}
+
+	tests := []struct {
+		name string
+		code string
+		want Barcode
+	}{
+		{"EAN-13", "4006381333931", Barcode{GTIN: gtin}},
+		{"UPC-A", "036000291452", Barcode{GTIN: "00036000291452"}},
+		{"EAN-8", "96385074", Barcode{GTIN: "00000096385074"}},
+		{"GTIN-14 with spaces", " " + gtin + "\n", Barcode{GTIN: gtin}},
+		{
+			"DataMatrix with symbology identifier",
+			"]d2" + "0104006381333931" + "17270331" + "10ABC123\x1d" + "21SER9",
+			Barcode{GTIN: gtin, Expiry: date(2027, time.March, 31), Batch: "ABC123", Serial: "SER9"},
+		},
+		{
+			"GS1-128 with leading FNC1",
+			"]C1\x1d" + "0104006381333931" + "10LOT1",
+			Barcode{GTIN: gtin, Batch: "LOT1"},
+		},
+		{
+			"leading group separator",
+			"\x1d10B1\x1d0104006381333931",
+			Barcode{GTIN: gtin, Batch: "B1"},
+		},
+		{
+			"human-readable",
+			"(01)04006381333931(17)270300(10)LOT 7",
+			Barcode{GTIN: gtin, Expiry: date(2027, time.March, 31), Batch: "LOT 7"},
+		},
+		{
+			"three-digit identifier",
+			"0104006381333931" + "240REF-1\x1d" + "17251200",
+			Barcode{GTIN: gtin, Expiry: date(2025, time.December, 31)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBarcode(tt.code, 2026)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.GTIN != tt.want.GTIN || got.Batch != tt.want.Batch || got.Serial != tt.want.Serial {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if (got.Expiry == nil) != (tt.want.Expiry == nil) || (got.Expiry != nil && !got.Expiry.Equal(*tt.want.Expiry)) {
+				t.Errorf("expiry = %v, want %v", got.Expiry, tt.want.Expiry)
+			}
+		})
+	}
+}
+
+func TestParseBarcodeRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+	}{
+		{"empty", "  "},
+		{"only a symbology identifier", "]d2"},
+		{"bad check digit", "4006381333932"},
+		{"GTIN element too short", "(01)0400638133393"},
+		{"non-digit GTIN", "(01)0400638133393X"},
+		{"unknown identifier", "010400638133393199X"},
+		{"no GTIN", "(10)LOT"},
+		{"empty batch", "(01)04006381333931(10)"},
+		{"batch too long", "0104006381333931" + "10" + strings.Repeat("A", 21)},
+		{"unbracketed human-readable", "(01)04006381333931 10LOT"},
+		{"impossible expiry", "(01)04006381333931(17)250229"},
+		{"expiry month 13", "(01)04006381333931(17)251300"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := parseBarcode(tt.code, 2026); err == nil {
+				t.Errorf("parsed %q as %+v", tt.code, got)
+			}
+		})
+	}
+}
+
+// Two-digit expiry years fall within 50 years of the current year.
+func TestGS1DateWindow(t *testing.T) {
+	tests := []struct {
+		data string
+		year int
+		want string
+	}{
+		{"760101", 2026, "2076-01-01"},
+		{"770101", 2026, "1977-01-01"},
+		{"270315", 2026, "2027-03-15"},
+		{"991231", 2001, "1999-12-31"},
+		{"000115", 2099, "2100-01-15"},
+		{"490101", 2099, "2149-01-01"},
+		{"500101", 2099, "2050-01-01"},
+		{"240229", 2026, "2024-02-29"},
+		{"250200", 2026, "2025-02-28"},
+		{"241200", 2026, "2024-12-31"},
+	}
+	for _, tt := range tests {
+		got, err := gs1Date(tt.data, tt.year)
+		if err != nil {
+			t.Errorf("gs1Date(%s, %d): %v", tt.data, tt.year, err)
+			continue
+		}
+		if s := got.Format("2006-01-02"); s != tt.want {
+			t.Errorf("gs1Date(%s, %d) = %s, want %s", tt.data, tt.year, s, tt.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"medeasy/m/domain"
+)
+
+// searchLimit caps catalog and inventory search results.
+const searchLimit = 25
+
+// Catalog searches the shared medicine catalog.
+type Catalog interface {
+	// Search finds medicines by brand, generic name, manufacturer or
+	// strength, tolerating typos. An empty cursor starts from the best match.
+	Search(ctx context.Context, query, cursor string, limit int) (MedicinePage, error)
+	// Packs lists the priced packs of a catalog medicine.
+	Packs(ctx context.Context, medicineID int64) ([]domain.MedicinePack, error)
+	// AddBarcode registers a scanned code for a medicine. packSize is set
+	// when the code is printed on packs of that many units. Codes apply to
+	// every pharmacy, so only operators register them, through the CLI.
+	AddBarcode(ctx context.Context, medicineID int64, code string, packSize int64) (domain.MedicineBarcode, error)
+}
+
+type catalogService struct {
+	medicines MedicineRepository
+}
+
+// MedicinePage is one page of search results. Next is empty on the last page.
+type MedicinePage struct {
+	Medicines []domain.Medicine
+	Next      string
+}
+
+// maxSearchLimit caps the page size of a catalog search.
+const maxSearchLimit = 100
+
+func (s *catalogService) Search(ctx context.Context, query, cursor string, limit int) (MedicinePage, error) {
+	afterScore, afterID, err := parseSearchCursor(cursor)
+	if err != nil {
+		return MedicinePage{}, err
+	}
+	switch {
+	case limit <= 0:
+		limit = searchLimit
+	case limit > maxSearchLimit:
+		limit = maxSearchLimit
+	}
+	ranked, err := s.medicines.Search(ctx, strings.TrimSpace(query), afterScore, afterID, limit+1)
+	if err != nil {
+		return MedicinePage{}, err
+	}
+	page := MedicinePage{Medicines: make([]domain.Medicine, 0, min(len(ranked), limit))}
+	for _, result := range ranked[:min(len(ranked), limit)] {
+		page.Medicines = append(page.Medicines, result.Medicine)
+	}
+	if len(ranked) > limit {
+		last := ranked[limit-1]
+		page.Next = base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d:%s", last.ID, strconv.FormatFloat(last.Score, 'g', -1, 64)))
+	}
+	return page, nil
+}
+
+// A search cursor is the ID and score of the last result of the previous
+// page, so later pages cost no more than the first and results do not shift
+// between pages when the catalog changes.
+func parseSearchCursor(cursor string) (float64, int64, error) {
+	if cursor == "" {
+		return 0, 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, invalid("invalid search cursor")
+	}
+	id, score, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return 0, 0, invalid("invalid search cursor")
+	}
+	afterID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || afterID <= 0 {
+		return 0, 0, invalid("invalid search cursor")
+	}
+	afterScore, err := strconv.ParseFloat(score, 64)
+	if err != nil || math.IsNaN(afterScore) || math.IsInf(afterScore, 0) {
+		return 0, 0, invalid("invalid search cursor")
+	}
+	return afterScore, afterID, nil
+}
+
+func (s *catalogService) Packs(ctx context.Context, medicineID int64) ([]domain.MedicinePack, error) {
+	_, err := s.medicines.ByID(ctx, medicineID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, notFound("medicine not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.medicines.Packs(ctx, medicineID)
+}
+
+func (s *catalogService) AddBarcode(ctx context.Context, medicineID int64, code string, packSize int64) (domain.MedicineBarcode, error) {
+	if packSize < 0 {
+		return domain.MedicineBarcode{}, invalid("pack_size must be positive")
+	}
+	scanned, err := parseBarcode(code, time.Now().Year())
+	if err != nil {
+		return domain.MedicineBarcode{}, err
+	}
+	_, err = s.medicines.ByID(ctx, medicineID)
+	if errors.Is(err, ErrNotFound) {
+		return domain.MedicineBarcode{}, notFound("medicine not found")
+	}
+	if err != nil {
+		return domain.MedicineBarcode{}, err
+	}
+	barcode := domain.MedicineBarcode{MedicineID: medicineID, GTIN: scanned.GTIN}
+	if packSize > 0 {
+		barcode.PackSize = &packSize
+	}
+	err = s.medicines.AddBarcode(ctx, &barcode)
+	if errors.Is(err, ErrDuplicate) {
+		return domain.MedicineBarcode{}, conflict("barcode is already registered")
+	}
+	return barcode, err
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"slices"
+	"testing"
+
+	"medeasy/m/domain"
+)
+
+// rankedCatalog is a MedicineRepository whose search returns a fixed ranking.
+type rankedCatalog struct {
+	MedicineRepository
+	ranking []domain.RankedMedicine
+}
+
+func (c rankedCatalog) Search(ctx context.Context, query string, afterScore float64, afterID int64, limit int) ([]domain.RankedMedicine, error) {
+	var results []domain.RankedMedicine
+	for _, result := range c.ranking {
+		if afterID == 0 || result.Score < afterScore || (result.Score == afterScore && result.ID > afterID) {
+			results = append(results, result)
+		}
+	}
+	return results[:min(len(results), limit)], nil
+}
+
+func TestSearchPages(t *testing.T) {
+	scores := []float64{4.5, 0.30000000000000004, 0.30000000000000004, 0.30000000000000004, 1.0 / 3, 0}
+	var ranking []domain.RankedMedicine
+	for i, score := range scores {
+		ranking = append(ranking, domain.RankedMedicine{Medicine: domain.Medicine{ID: int64(10 - i)}, Score: score})
+	}
+	slices.SortFunc(ranking, func(a, b domain.RankedMedicine) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.ID, b.ID))
+	})
+	s := &catalogService{medicines: rankedCatalog{ranking: ranking}}
+
+	var got []int64
+	cursor := ""
+	for range len(ranking) {
+		page, err := s.Search(context.Background(), "napa", cursor, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, medicine := range page.Medicines {
+			got = append(got, medicine.ID)
+		}
+		if cursor = page.Next; cursor == "" {
+			break
+		}
+	}
+	var want []int64
+	for _, result := range ranking {
+		want = append(want, result.ID)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("paged through %v, want %v", got, want)
+	}
+}
+
+func TestParseSearchCursor(t *testing.T) {
+	if _, _, err := parseSearchCursor("not base64!"); err == nil {
+		t.Error("malformed cursor was accepted")
+	}
+	for _, raw := range []string{"o25", "12", "0:1", "-1:1", "7:", "7:rose", "7:NaN", "7:Inf"} {
+		if _, _, err := parseSearchCursor(base64.RawURLEncoding.EncodeToString([]byte(raw))); err == nil {
+			t.Errorf("cursor %q was accepted", raw)
+		}
+	}
+	score, id, err := parseSearchCursor(base64.RawURLEncoding.EncodeToString([]byte("7:0.30000000000000004")))
+	if err != nil || score != 0.30000000000000004 || id != 7 {
+		t.Errorf("got %v, %d, %v; want the exact score and id 7", score, id, err)
+	}
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"medeasy/m/domain"
+)
+
+// InteractionOverride is the approval of the signed-in owner or pharmacist
+// to sell a cart with severe interactions. It is recorded with the sale.
+type InteractionOverride struct {
+	Reason string
+}
+
+// maxCheckedGenerics bounds the generic names of one interaction check.
+const maxCheckedGenerics = 100
+
+// findInteractions returns the known interactions between different generics
+// among generics, severe first. Ingredients of one combination generic are
+// not checked against each other.
+func findInteractions(ctx context.Context, repo InteractionRepository, generics []string) ([]domain.InteractionWarning, error) {
+	var names []string
+	var keys []map[string]bool
+	seen := make(map[string]bool)
+	var all []string
+	for _, generic := range generics {
+		generic = strings.TrimSpace(generic)
+		if generic == "" || seen[strings.ToLower(generic)] {
+			continue
+		}
+		seen[strings.ToLower(generic)] = true
+		set := make(map[string]bool)
+		for _, ingredient := range domain.Ingredients(generic) {
+			for _, key := range domain.IngredientKeys(ingredient) {
+				if !set[key] {
+					set[key] = true
+					all = append(all, key)
+				}
+			}
+		}
+		names = append(names, generic)
+		keys = append(keys, set)
+	}
+	if len(names) < 2 {
+		return nil, nil
+	}
+
+	known, err := repo.Among(ctx, all)
+	if err != nil {
+		return nil, err
+	}
+	var warnings []domain.InteractionWarning
+	for _, interaction := range known {
+	pairs:
+		for i := range names {
+			for j := range names {
+				if i != j && keys[i][interaction.IngredientA] && keys[j][interaction.IngredientB] {
+					warnings = append(warnings, domain.InteractionWarning{DrugInteraction: interaction, GenericA: names[i], GenericB: names[j]})
+					break pairs
+				}
+			}
+		}
+	}
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return severityRank(warnings[i].Severity) > severityRank(warnings[j].Severity)
+	})
+	return warnings, nil
+}
+
+func severityRank(severity string) int {
+	switch severity {
+	case domain.SeveritySevere:
+		return 2
+	case domain.SeverityModerate:
+		return 1
+	}
+	return 0
+}
+
+// severeInteractions returns the warnings that need a pharmacist override.
+func severeInteractions(warnings []domain.InteractionWarning) []domain.InteractionWarning {
+	var severe []domain.InteractionWarning
+	for _, warning := range warnings {
+		if warning.Severity == domain.SeveritySevere {
+			severe = append(severe, warning)
+		}
+	}
+	return severe
+}
+
+func errInteractionOverride(severe []domain.InteractionWarning) error {
+	pairs := make([]string, len(severe))
+	for i, warning := range severe {
+		pairs[i] = warning.IngredientA + " + " + warning.IngredientB
+	}
+	return &Error{
+		Kind:    KindInvalid,
+		Message: fmt.Sprintf("severe interaction (%s) needs a pharmacist override", strings.Join(pairs, ", ")),
+		Reason:  "interaction_override_required",
+	}
+}
@@ -0,0 +1,495 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"medeasy/m/domain"
+)
+
+// Inventory manages a pharmacy's stock.
+type Inventory interface {
+	Search(ctx context.Context, pharmacyID int64, query string) ([]domain.InventorySearchResult, error)
+	Get(ctx context.Context, pharmacyID, id int64) (domain.InventoryItem, error)
+	// Add and Update check the sale price of catalog medicines against the
+	// MRP. Add prices stock at the MRP when no sale price is given.
+	Add(ctx context.Context, pharmacyID int64, in InventoryInput) (domain.InventoryItem, PriceCheck, error)
+	// Update and SetStock overwrite the stock level, so they require the
+	// version the caller last read (or AnyVersion).
+	Update(ctx context.Context, pharmacyID, id, version int64, in InventoryInput) (domain.InventoryItem, PriceCheck, error)
+	SetStock(ctx context.Context, pharmacyID, id, quantity, version int64) (domain.InventoryItem, error)
+	// Scan resolves a scanned barcode to a catalog medicine and the
+	// pharmacy's matching lot.
+	Scan(ctx context.Context, pharmacyID int64, code string) (ScanResult, error)
+	// AdjustStock adds delta to the stock level. version is optional.
+	AdjustStock(ctx context.Context, pharmacyID, id, delta, version int64) (domain.InventoryItem, error)
+	ExpiryAlerts(ctx context.Context, pharmacyID int64, days int) ([]domain.ExpiryAlert, error)
+	// Substitutes lists other brands of a catalog medicine's generic, strength
+	// and dosage form: those in stock first, then the cheapest.
+	Substitutes(ctx context.Context, pharmacyID, medicineID int64) ([]domain.Substitute, error)
+}
+
+// InventoryInput describes received stock. With PackSize set, CostPrice and
+// SalePrice are the prices of one pack of PackSize units; otherwise they are
+// totals for the whole quantity, which then counts as one pack.
+type InventoryInput struct {
+	MedicineID   *int64
+	BrandName    string
+	GenericName  string
+	Manufacturer string
+	Type         string
+	Quantity     int64
+	CostPrice    domain.Money
+	SalePrice    domain.Money
+	PackSize     int64
+	ExpiryDate   string
+	BatchNumber  string
+	// Barcode is a scan of the received pack. It identifies the medicine
+	// and fills in the pack size, batch and expiry when those are empty.
+	Barcode string
+}
+
+// pricing fills the pack and derived unit prices of item from in, whose
+// quantity and pack size have been checked to be positive and non-negative.
+// Dividing by a positive pack size cannot overflow.
+func (in InventoryInput) pricing(item *domain.InventoryItem) {
+	item.PackSize = in.PackSize
+	if item.PackSize == 0 {
+		item.PackSize = in.Quantity
+	}
+	item.PackCostPrice = in.CostPrice
+	item.PackSalePrice = in.SalePrice
+	item.CostPrice, _ = in.CostPrice.MulRatio(1, item.PackSize)
+	item.SalePrice, _ = in.SalePrice.MulRatio(1, item.PackSize)
+}
+
+// PriceCheck compares an item's pack sale price with the catalog MRP.
+type PriceCheck struct {
+	// MRP is the highest retail price the catalog lists for one pack of the
+	// item's size, or zero when the catalog has no price for it.
+	MRP domain.Money
+	// Suggested is set when the sale price was taken from the MRP.
+	Suggested bool
+	Warnings  []string
+}
+
+// ScanResult is a scanned code resolved against the catalog and the
+// pharmacy's stock.
+type ScanResult struct {
+	Barcode
+	// Medicine is nil when the GTIN is not registered.
+	Medicine *domain.Medicine
+	PackSize *int64
+	// Lot is the lot the scan identifies: the in-stock lot with the scanned
+	// batch or, when the code has no batch, the one expiring first. It is
+	// nil when no such lot is in stock.
+	Lot *domain.InventoryItem
+	// Lots lists every in-stock lot of the medicine.
+	Lots []domain.InventoryItem
+}
+
+// Versions passed to the inventory writes. NoVersion means the caller did not
+// say which version it read; AnyVersion skips the check.
+const (
+	NoVersion  int64 = 0
+	AnyVersion int64 = -1
+)
+
+// defaultExpiryWindow is used when no alert window is requested.
+const defaultExpiryWindow = 30
+
+type inventoryService struct {
+	inventory InventoryRepository
+	medicines MedicineRepository
+}
+
+func (s *inventoryService) Search(ctx context.Context, pharmacyID int64, query string) ([]domain.InventorySearchResult, error) {
+	return s.inventory.Search(ctx, pharmacyID, strings.TrimSpace(query), searchLimit)
+}
+
+func (s *inventoryService) Get(ctx context.Context, pharmacyID, id int64) (domain.InventoryItem, error) {
+	return s.authorize(ctx, pharmacyID, id)
+}
+
+func (s *inventoryService) Add(ctx context.Context, pharmacyID int64, in InventoryInput) (domain.InventoryItem, PriceCheck, error) {
+	if in.Barcode != "" {
+		if err := s.applyBarcode(ctx, &in); err != nil {
+			return domain.InventoryItem{}, PriceCheck{}, err
+		}
+	}
+	var medicine *domain.Medicine
+	if in.MedicineID != nil && *in.MedicineID != 0 {
+		found, err := s.medicines.ByID(ctx, *in.MedicineID)
+		if errors.Is(err, ErrNotFound) {
+			return domain.InventoryItem{}, PriceCheck{}, invalid("invalid medicine_id")
+		}
+		if err != nil {
+			return domain.InventoryItem{}, PriceCheck{}, err
+		}
+		medicine = &found
+	}
+	if in.Quantity <= 0 || in.CostPrice <= 0 || in.SalePrice < 0 {
+		return domain.InventoryItem{}, PriceCheck{}, invalid("quantity, cost_price and sale_price are required")
+	}
+	if in.PackSize < 0 {
+		return domain.InventoryItem{}, PriceCheck{}, invalid("pack_size must be positive")
+	}
+	expiry, err := parseExpiryDate(in.ExpiryDate)
+	if err != nil {
+		return domain.InventoryItem{}, PriceCheck{}, err
+	}
+
+	var check PriceCheck
+	if medicine != nil {
+		if check, err = s.checkPrice(ctx, medicine.ID, &in); err != nil {
+			return domain.InventoryItem{}, PriceCheck{}, err
+		}
+	}
+	if in.SalePrice == 0 {
+		return domain.InventoryItem{}, PriceCheck{}, invalid("sale_price is required when the catalog has no MRP for this pack")
+	}
+
+	item := domain.InventoryItem{
+		PharmacyID: pharmacyID,
+		Quantity:   in.Quantity,
+		ExpiryDate: expiry,
+	}
+	if batch := strings.TrimSpace(in.BatchNumber); batch != "" {
+		item.BatchNumber = &batch
+	}
+	in.pricing(&item)
+	if medicine != nil {
+		item.MedicineID = &medicine.ID
+		item.BrandName = &medicine.BrandName
+		item.GenericName = &medicine.GenericName
+		item.Manufacturer = &medicine.Manufacturer
+		item.Type = &medicine.Type
+	} else {
+		// Custom medicine that is not in the catalog.
+		if in.BrandName == "" {
+			return domain.InventoryItem{}, PriceCheck{}, invalid("brand_name is required for custom medicine")
+		}
+		item.BrandName = &in.BrandName
+		item.GenericName = &in.GenericName
+		item.Manufacturer = &in.Manufacturer
+		item.Type = &in.Type
+	}
+
+	if err := s.inventory.Create(ctx, &item); err != nil {
+		return domain.InventoryItem{}, PriceCheck{}, err
+	}
+	return item, check, nil
+}
+
+func (s *inventoryService) Update(ctx context.Context, pharmacyID, id, version int64, in InventoryInput) (domain.InventoryItem, PriceCheck, error) {
+	current, err := s.authorize(ctx, pharmacyID, id)
+	if err != nil {
+		return domain.InventoryItem{}, PriceCheck{}, err
+	}
+	if version == NoVersion {
+		return domain.InventoryItem{}, PriceCheck{}, errVersionRequired
+	}
+	if in.Quantity < 0 || in.CostPrice <= 0 || in.SalePrice <= 0 {
+		return domain.InventoryItem{}, PriceCheck{}, invalid("quantity, cost_price and sale_price are required")
+	}
+	if in.Quantity == 0 {
+		return domain.InventoryItem{}, PriceCheck{}, invalid("quantity must be greater than zero")
+	}
+	if in.PackSize < 0 {
+		return domain.InventoryItem{}, PriceCheck{}, invalid("pack_size must be positive")
+	}
+	expiry, err := parseExpiryDate(in.ExpiryDate)
+	if err != nil {
+		return domain.InventoryItem{}, PriceCheck{}, err
+	}
+	var check PriceCheck
+	if current.MedicineID != nil {
+		if check, err = s.checkPrice(ctx, *current.MedicineID, &in); err != nil {
+			return domain.InventoryItem{}, PriceCheck{}, err
+		}
+	}
+
+	// Only stock, prices and expiry are editable; names stay as received.
+	item := domain.InventoryItem{
+		ID:         id,
+		PharmacyID: pharmacyID,
+		Quantity:   in.Quantity,
+		ExpiryDate: expiry,
+	}
+	in.pricing(&item)
+	updated, err := s.inventory.UpdatePricing(ctx, item, version)
+	if errors.Is(err, ErrNotFound) {
+		return domain.InventoryItem{}, PriceCheck{}, errVersionChanged
+	}
+	return updated, check, err
+}
+
+// applyBarcode fills in the medicine, pack size, batch and expiry of received
+// stock from a scan of its pack.
+func (s *inventoryService) applyBarcode(ctx context.Context, in *InventoryInput) error {
+	scanned, err := parseBarcode(in.Barcode, time.Now().Year())
+	if err != nil {
+		return err
+	}
+	registered, err := s.medicines.ByBarcode(ctx, scanned.GTIN)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		if in.MedicineID == nil || *in.MedicineID == 0 {
+			return invalid("barcode is not registered; choose the medicine or register the barcode first")
+		}
+	case err != nil:
+		return err
+	case in.MedicineID == nil || *in.MedicineID == 0:
+		in.MedicineID = &registered.MedicineID
+	case *in.MedicineID != registered.MedicineID:
+		return invalid("barcode belongs to a different medicine")
+	}
+	if in.PackSize == 0 && registered.PackSize != nil {
+		in.PackSize = *registered.PackSize
+	}
+	if strings.TrimSpace(in.BatchNumber) == "" {
+		in.BatchNumber = scanned.Batch
+	}
+	if strings.TrimSpace(in.ExpiryDate) == "" && scanned.Expiry != nil {
+		in.ExpiryDate = scanned.Expiry.Format("2006-01-02")
+	}
+	return nil
+}
+
+func (s *inventoryService) Scan(ctx context.Context, pharmacyID int64, code string) (ScanResult, error) {
+	scanned, err := parseBarcode(code, time.Now().Year())
+	if err != nil {
+		return ScanResult{}, err
+	}
+	result := ScanResult{Barcode: scanned, Lots: []domain.InventoryItem{}}
+	registered, err := s.medicines.ByBarcode(ctx, scanned.GTIN)
+	if errors.Is(err, ErrNotFound) {
+		return result, nil
+	}
+	if err != nil {
+		return ScanResult{}, err
+	}
+	medicine, err := s.medicines.ByID(ctx, registered.MedicineID)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	result.Medicine = &medicine
+	result.PackSize = registered.PackSize
+
+	if result.Lots, err = s.inventory.Lots(ctx, pharmacyID, medicine.ID); err != nil {
+		return ScanResult{}, err
+	}
+	for i, lot := range result.Lots {
+		if scanned.Batch == "" || (lot.BatchNumber != nil && strings.EqualFold(*lot.BatchNumber, scanned.Batch)) {
+			result.Lot = &result.Lots[i]
+			break
+		}
+	}
+	return result, nil
+}
+
+// checkPrice compares in's pack sale price with the medicine's MRP, filling
+// in the sale price from the MRP when it is zero.
+func (s *inventoryService) checkPrice(ctx context.Context, medicineID int64, in *InventoryInput) (PriceCheck, error) {
+	packs, err := s.medicines.Packs(ctx, medicineID)
+	if err != nil {
+		return PriceCheck{}, err
+	}
+	units := in.PackSize
+	if units == 0 {
+		units = in.Quantity
+	}
+	low, high, ok := mrpRange(packs, units)
+	if !ok {
+		return PriceCheck{}, nil
+	}
+	check := PriceCheck{MRP: high}
+	if in.SalePrice == 0 {
+		in.SalePrice = low
+		check.Suggested = true
+	}
+	if in.SalePrice > high {
+		check.Warnings = append(check.Warnings, fmt.Sprintf("sale_price %s is above the MRP of %s for %d units", in.SalePrice, high, units))
+	}
+	return check, nil
+}
+
+// mrpRange returns the lowest and highest MRP the catalog lists for a pack
+// of units. Packs of exactly that size are preferred; otherwise every pack's
+// price is scaled to units.
+func mrpRange(packs []domain.MedicinePack, units int64) (low, high domain.Money, ok bool) {
+	var prices []domain.Money
+	for _, pack := range packs {
+		if pack.Units == units {
+			prices = append(prices, pack.MRP)
+		}
+	}
+	if len(prices) == 0 {
+		for _, pack := range packs {
+			// Packs whose price cannot be scaled to units are skipped.
+			if price, err := pack.MRP.MulRatio(units, pack.Units); err == nil {
+				prices = append(prices, price)
+			}
+		}
+	}
+	if len(prices) == 0 {
+		return 0, 0, false
+	}
+	low, high = prices[0], prices[0]
+	for _, price := range prices[1:] {
+		low, high = min(low, price), max(high, price)
+	}
+	return low, high, true
+}
+
+func (s *inventoryService) SetStock(ctx context.Context, pharmacyID, id, quantity, version int64) (domain.InventoryItem, error) {
+	if _, err := s.authorize(ctx, pharmacyID, id); err != nil {
+		return domain.InventoryItem{}, err
+	}
+	if version == NoVersion {
+		return domain.InventoryItem{}, errVersionRequired
+	}
+	if quantity < 0 {
+		return domain.InventoryItem{}, invalid("quantity must be positive")
+	}
+	updated, err := s.inventory.SetQuantity(ctx, id, quantity, version)
+	if errors.Is(err, ErrNotFound) {
+		return domain.InventoryItem{}, errVersionChanged
+	}
+	return updated, err
+}
+
+func (s *inventoryService) AdjustStock(ctx context.Context, pharmacyID, id, delta, version int64) (domain.InventoryItem, error) {
+	if _, err := s.authorize(ctx, pharmacyID, id); err != nil {
+		return domain.InventoryItem{}, err
+	}
+	if delta == 0 {
+		return domain.InventoryItem{}, invalid("delta must not be zero")
+	}
+	if version == NoVersion {
+		version = AnyVersion
+	}
+	updated, err := s.inventory.AdjustQuantity(ctx, id, delta, version)
+	if !errors.Is(err, ErrNotFound) {
+		return updated, err
+	}
+	// Tell a stale version apart from a shortage.
+	current, err := s.inventory.ByID(ctx, id)
+	if err != nil {
+		return domain.InventoryItem{}, err
+	}
+	if version != AnyVersion && current.Version != version {
+		return domain.InventoryItem{}, errVersionChanged
+	}
+	return domain.InventoryItem{}, invalid(fmt.Sprintf("only %d in stock", current.Quantity))
+}
+
+func (s *inventoryService) ExpiryAlerts(ctx context.Context, pharmacyID int64, days int) ([]domain.ExpiryAlert, error) {
+	if days <= 0 {
+		days = defaultExpiryWindow
+	}
+	return s.inventory.ExpiringWithin(ctx, pharmacyID, days)
+}
+
+func (s *inventoryService) Substitutes(ctx context.Context, pharmacyID, medicineID int64) ([]domain.Substitute, error) {
+	_, err := s.medicines.ByID(ctx, medicineID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, notFound("medicine not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	equivalents, err := s.inventory.Equivalents(ctx, pharmacyID, medicineID)
+	if err != nil {
+		return nil, err
+	}
+	var requested domain.Substitute
+	substitutes := make([]domain.Substitute, 0, len(equivalents))
+	for _, equivalent := range equivalents {
+		if equivalent.ID == medicineID {
+			requested = equivalent
+			continue
+		}
+		substitutes = append(substitutes, equivalent)
+	}
+	for i := range substitutes {
+		substitutes[i].PriceDifference = priceDifference(substitutes[i], requested)
+	}
+	sort.SliceStable(substitutes, func(i, j int) bool {
+		a, b := substitutes[i], substitutes[j]
+		if (a.InStock > 0) != (b.InStock > 0) {
+			return a.InStock > 0
+		}
+		pa, pb := comparablePrice(a), comparablePrice(b)
+		if (pa != nil) != (pb != nil) {
+			return pa != nil
+		}
+		if pa != nil && *pa != *pb {
+			return *pa < *pb
+		}
+		return a.BrandName < b.BrandName
+	})
+	return substitutes, nil
+}
+
+// comparablePrice is what a unit of the brand costs the customer here: the
+// pharmacy's price when stocked, otherwise the MRP.
+func comparablePrice(s domain.Substitute) *domain.Money {
+	if s.UnitPrice != nil {
+		return s.UnitPrice
+	}
+	return s.UnitMRP
+}
+
+// priceDifference compares like with like: stocked prices when both brands
+// are stocked, MRPs otherwise.
+func priceDifference(substitute, requested domain.Substitute) *domain.Money {
+	a, b := substitute.UnitPrice, requested.UnitPrice
+	if a == nil || b == nil {
+		a, b = substitute.UnitMRP, requested.UnitMRP
+	}
+	if a == nil || b == nil {
+		return nil
+	}
+	difference := *a - *b
+	return &difference
+}
+
+var (
+	errVersionRequired = preconditionRequired("send the item's ETag in If-Match, or change stock by delta")
+	errVersionChanged  = preconditionFailed("inventory item was changed since it was read; reload it and try again")
+)
+
+// authorize loads the inventory item and checks it belongs to pharmacyID.
+func (s *inventoryService) authorize(ctx context.Context, pharmacyID, id int64) (domain.InventoryItem, error) {
+	item, err := s.inventory.ByID(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return domain.InventoryItem{}, notFound("inventory not found")
+	}
+	if err != nil {
+		return domain.InventoryItem{}, err
+	}
+	if item.PharmacyID != pharmacyID {
+		return domain.InventoryItem{}, forbidden("inventory does not belong to your pharmacy")
+	}
+	return item, nil
+}
+
+// parseExpiryDate accepts YYYY-MM-DD, optionally followed by a time as sent
+// by clients that serialise full timestamps. Empty means no expiry.
+func parseExpiryDate(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if len(value) >= 10 {
+		if t, err := time.Parse("2006-01-02", value[:10]); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, invalid("expiry_date must be in YYYY-MM-DD format")
+}
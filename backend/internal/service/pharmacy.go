@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+
+	"medeasy/m/domain"
+)
+
+// Pharmacies manages pharmacy records.
+type Pharmacies interface {
+	Create(ctx context.Context, ownerID int64, in PharmacyInput) (domain.Pharmacy, error)
+	Update(ctx context.Context, id int64, in PharmacyInput) error
+	List(ctx context.Context) ([]domain.Pharmacy, error)
+}
+
+// PharmacyInput holds the editable pharmacy fields.
+type PharmacyInput struct {
+	Name     string
+	Address  string
+	Location string
+}
+
+type pharmacyService struct {
+	pharmacies PharmacyRepository
+}
+
+func (s *pharmacyService) Create(ctx context.Context, ownerID int64, in PharmacyInput) (domain.Pharmacy, error) {
+	if in.Name == "" {
+		return domain.Pharmacy{}, invalid("name is required")
+	}
+	pharmacy := domain.Pharmacy{Name: in.Name, Address: in.Address, Location: in.Location, OwnerID: &ownerID}
+	if err := s.pharmacies.Create(ctx, &pharmacy); err != nil {
+		return domain.Pharmacy{}, err
+	}
+	return pharmacy, nil
+}
+
+func (s *pharmacyService) Update(ctx context.Context, id int64, in PharmacyInput) error {
+	if in.Name == "" {
+		return invalid("name is required")
+	}
+	return s.pharmacies.Update(ctx, domain.Pharmacy{ID: id, Name: in.Name, Address: in.Address, Location: in.Location})
+}
+
+func (s *pharmacyService) List(ctx context.Context) ([]domain.Pharmacy, error) {
+	return s.pharmacies.List(ctx)
+}
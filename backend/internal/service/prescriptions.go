@@ -0,0 +1,249 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"medeasy/m/domain"
+)
+
+// Prescriptions keeps patients' prescriptions so refills are dispensed
+// against the stored record. Sales fill them through NewSale.PrescriptionID.
+type Prescriptions interface {
+	Create(ctx context.Context, pharmacyID, userID int64, in PrescriptionInput) (domain.Prescription, error)
+	Get(ctx context.Context, pharmacyID, id int64) (domain.Prescription, error)
+	// Find looks up a patient's prescriptions by phone number or name,
+	// newest first.
+	Find(ctx context.Context, pharmacyID int64, patient string) ([]domain.Prescription, error)
+	// AttachScan stores a JPEG, PNG or PDF scan of the prescription,
+	// replacing any earlier one.
+	AttachScan(ctx context.Context, pharmacyID, id int64, scan io.Reader) (domain.Prescription, error)
+	// Scan opens the prescription's scan and returns its content type.
+	Scan(ctx context.Context, pharmacyID, id int64) (io.ReadCloser, string, error)
+}
+
+// PrescriptionInput is a prescription as written by the prescriber.
+type PrescriptionInput struct {
+	PatientName    string
+	PatientPhone   string
+	PatientAddress string
+	Prescriber     string
+	// RegistrationNumber is the prescriber's medical council registration.
+	RegistrationNumber string
+	// PrescribedOn is the prescription's date, YYYY-MM-DD.
+	PrescribedOn string
+	Note         string
+	Items        []PrescriptionItemInput
+}
+
+// PrescriptionItemInput is one prescribed medicine. Description defaults to
+// the catalog medicine's name.
+type PrescriptionItemInput struct {
+	MedicineID  *int64
+	Description string
+	Dosage      string
+	// Quantity is the units dispensed per fill; Refills the fills allowed
+	// after the first.
+	Quantity int64
+	Refills  int64
+}
+
+const (
+	// MaxScanBytes bounds an uploaded prescription scan.
+	MaxScanBytes = 10 << 20
+	// maxPrescriptionItems bounds the medicines of one prescription.
+	maxPrescriptionItems = 50
+	// maxPatientPrescriptions caps the prescriptions a lookup returns.
+	maxPatientPrescriptions = 50
+	// minPhoneDigits tells a phone number from a name in lookups.
+	minPhoneDigits = 6
+)
+
+// scanExtensions are the accepted scan types and the extension each is
+// stored with.
+var scanExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"application/pdf": ".pdf",
+}
+
+type prescriptionService struct {
+	prescriptions PrescriptionRepository
+	medicines     MedicineRepository
+	attachments   AttachmentStore
+	location      *time.Location
+	now           func() time.Time
+}
+
+func (s *prescriptionService) Create(ctx context.Context, pharmacyID, userID int64, in PrescriptionInput) (domain.Prescription, error) {
+	p := domain.Prescription{
+		PharmacyID:         pharmacyID,
+		PatientName:        strings.TrimSpace(in.PatientName),
+		PatientPhone:       phoneDigits(in.PatientPhone),
+		PatientAddress:     strings.TrimSpace(in.PatientAddress),
+		Prescriber:         strings.TrimSpace(in.Prescriber),
+		RegistrationNumber: strings.TrimSpace(in.RegistrationNumber),
+		PrescribedOn:       strings.TrimSpace(in.PrescribedOn),
+		Note:               strings.TrimSpace(in.Note),
+	}
+	if p.Prescriber == "" || p.RegistrationNumber == "" || p.PatientName == "" {
+		return domain.Prescription{}, invalid("prescription needs the prescriber, their registration number and the patient")
+	}
+	if strings.TrimSpace(in.PatientPhone) != "" && len(p.PatientPhone) < minPhoneDigits {
+		return domain.Prescription{}, invalid(fmt.Sprintf("patient_phone must have at least %d digits", minPhoneDigits))
+	}
+	date, err := time.ParseInLocation("2006-01-02", p.PrescribedOn, s.location)
+	if err != nil {
+		return domain.Prescription{}, invalid("prescribed_on must be in YYYY-MM-DD format")
+	}
+	if date.After(s.now().In(s.location)) {
+		return domain.Prescription{}, invalid("prescribed_on is in the future")
+	}
+	if len(in.Items) == 0 {
+		return domain.Prescription{}, invalid("prescription has no items")
+	}
+	if len(in.Items) > maxPrescriptionItems {
+		return domain.Prescription{}, invalid(fmt.Sprintf("prescription has more than %d items", maxPrescriptionItems))
+	}
+	for i, item := range in.Items {
+		prescribed := domain.PrescriptionItem{
+			MedicineID:  item.MedicineID,
+			Description: strings.TrimSpace(item.Description),
+			Dosage:      strings.TrimSpace(item.Dosage),
+			Quantity:    item.Quantity,
+			Refills:     item.Refills,
+		}
+		if prescribed.Quantity <= 0 {
+			return domain.Prescription{}, invalid(fmt.Sprintf("quantity of item %d must be positive", i+1))
+		}
+		if prescribed.Refills < 0 {
+			return domain.Prescription{}, invalid(fmt.Sprintf("refills of item %d must not be negative", i+1))
+		}
+		if item.MedicineID != nil {
+			medicine, err := s.medicines.ByID(ctx, *item.MedicineID)
+			if errors.Is(err, ErrNotFound) {
+				return domain.Prescription{}, invalid(fmt.Sprintf("invalid medicine_id of item %d", i+1))
+			}
+			if err != nil {
+				return domain.Prescription{}, err
+			}
+			if prescribed.Description == "" {
+				prescribed.Description = strings.TrimSpace(medicine.BrandName + " " + medicine.Strength)
+			}
+		}
+		if prescribed.Description == "" {
+			return domain.Prescription{}, invalid(fmt.Sprintf("item %d needs a medicine_id or description", i+1))
+		}
+		p.Items = append(p.Items, prescribed)
+	}
+	if userID > 0 {
+		p.CreatedBy = &userID
+	}
+	if err := s.prescriptions.Create(ctx, &p); err != nil {
+		return domain.Prescription{}, err
+	}
+	return p, nil
+}
+
+func (s *prescriptionService) Get(ctx context.Context, pharmacyID, id int64) (domain.Prescription, error) {
+	p, err := s.prescriptions.ByID(ctx, pharmacyID, id)
+	if errors.Is(err, ErrNotFound) {
+		return domain.Prescription{}, notFound("prescription not found")
+	}
+	return p, err
+}
+
+func (s *prescriptionService) Find(ctx context.Context, pharmacyID int64, patient string) ([]domain.Prescription, error) {
+	patient = strings.TrimSpace(patient)
+	if patient == "" {
+		return nil, invalid("patient is required")
+	}
+	// A query without letters is a phone number.
+	if digits := phoneDigits(patient); len(digits) >= minPhoneDigits && strings.IndexFunc(patient, unicode.IsLetter) < 0 {
+		return s.prescriptions.ByPatient(ctx, pharmacyID, digits, "", maxPatientPrescriptions)
+	}
+	return s.prescriptions.ByPatient(ctx, pharmacyID, "", strings.ToLower(patient), maxPatientPrescriptions)
+}
+
+func (s *prescriptionService) AttachScan(ctx context.Context, pharmacyID, id int64, scan io.Reader) (domain.Prescription, error) {
+	if _, err := s.Get(ctx, pharmacyID, id); err != nil {
+		return domain.Prescription{}, err
+	}
+	data, err := io.ReadAll(io.LimitReader(scan, MaxScanBytes+1))
+	if err != nil {
+		return domain.Prescription{}, err
+	}
+	if len(data) == 0 {
+		return domain.Prescription{}, invalid("scan is empty")
+	}
+	if len(data) > MaxScanBytes {
+		return domain.Prescription{}, invalid(fmt.Sprintf("scan must be at most %d MB", MaxScanBytes>>20))
+	}
+	ext, ok := scanExtensions[http.DetectContentType(data)]
+	if !ok {
+		return domain.Prescription{}, invalid("scan must be a JPEG, PNG or PDF file")
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return domain.Prescription{}, err
+	}
+	// Each upload gets a new key, so a reader of the old scan is never
+	// served half of the new one.
+	key := fmt.Sprintf("prescriptions/%d/%d-%s%s", pharmacyID, id, hex.EncodeToString(suffix), ext)
+	if err := s.attachments.Put(ctx, key, bytes.NewReader(data)); err != nil {
+		return domain.Prescription{}, err
+	}
+	// The replaced scan is kept: sales dispensed against the prescription
+	// reference the key they were recorded with.
+	err = s.prescriptions.SetScan(ctx, pharmacyID, id, key)
+	if err != nil {
+		_ = s.attachments.Delete(ctx, key)
+	}
+	if errors.Is(err, ErrNotFound) {
+		return domain.Prescription{}, notFound("prescription not found")
+	}
+	if err != nil {
+		return domain.Prescription{}, err
+	}
+	return s.Get(ctx, pharmacyID, id)
+}
+
+func (s *prescriptionService) Scan(ctx context.Context, pharmacyID, id int64) (io.ReadCloser, string, error) {
+	p, err := s.Get(ctx, pharmacyID, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if p.ScanKey == nil {
+		return nil, "", notFound("prescription has no scan")
+	}
+	file, err := s.attachments.Open(ctx, *p.ScanKey)
+	if errors.Is(err, ErrNotFound) {
+		return nil, "", notFound("scan not found")
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return file, mime.TypeByExtension(path.Ext(*p.ScanKey)), nil
+}
+
+// phoneDigits strips a phone number to its digits.
+func phoneDigits(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+}
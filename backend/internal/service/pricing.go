@@ -0,0 +1,109 @@
+package service
+
+import (
+	"fmt"
+	"math"
+
+	"medeasy/m/domain"
+)
+
+// PricedLine is a sale line priced from the pack it is sold out of.
+type PricedLine struct {
+	PackPrice domain.Money
+	PackSize  int64
+	Quantity  int64
+}
+
+// UnitPrice is the per-unit price rounded to the paisa, for display only.
+func (l PricedLine) UnitPrice() (domain.Money, error) {
+	return l.PackPrice.MulRatio(1, l.PackSize)
+}
+
+// Subtotal prices the quantity exactly from the pack price, rounding once to
+// the paisa, so three units of a 10-taka pack of three cost exactly 10.
+func (l PricedLine) Subtotal() (domain.Money, error) {
+	return l.PackPrice.MulRatio(l.Quantity, l.PackSize)
+}
+
+// SaleTotals are the amounts recorded for a sale. Total, discount, round-off
+// and payment are whole taka; only line subtotals keep paisa.
+type SaleTotals struct {
+	Total          domain.Money
+	Discount       domain.Money
+	RoundOff       domain.Money
+	NetPayable     domain.Money
+	Paid           domain.Money
+	ChangeReturned domain.Money
+	Due            domain.Money
+}
+
+// errSaleRange rejects sales whose amounts do not fit in a Money or in the
+// columns they are stored in.
+var errSaleRange = invalid("sale amount is out of range")
+
+// maxRoundOff bounds the cashier's round-off, which only evens out the
+// amount payable.
+const maxRoundOff = 100 // paisa
+
+// PriceSale applies the discount percentage, the cashier's round-off and the
+// amount paid to the priced lines. It has no side effects.
+func PriceSale(lines []PricedLine, discountPercent float64, roundOff, paid domain.Money) (SaleTotals, error) {
+	if !(discountPercent >= 0 && discountPercent <= 100) {
+		return SaleTotals{}, invalid("discount_percent must be between 0 and 100")
+	}
+	if roundOff < -maxRoundOff || roundOff > maxRoundOff {
+		return SaleTotals{}, invalid("round_off must be between -1 and 1 taka")
+	}
+	if paid < 0 || paid > domain.MaxStored {
+		return SaleTotals{}, invalid("paid_amount is out of range")
+	}
+	var total domain.Money
+	for _, line := range lines {
+		if line.PackSize <= 0 {
+			return SaleTotals{}, fmt.Errorf("pack size %d of a sale line is not positive", line.PackSize)
+		}
+		subtotal, err := line.Subtotal()
+		if err != nil || subtotal > domain.MaxStored {
+			return SaleTotals{}, errSaleRange
+		}
+		if total, err = total.Add(subtotal); err != nil {
+			return SaleTotals{}, errSaleRange
+		}
+	}
+
+	t := SaleTotals{
+		Total:    total.RoundTaka(),
+		RoundOff: roundOff.RoundTaka(),
+		Paid:     paid.RoundTaka(),
+	}
+	if t.Total > domain.MaxStored || t.Paid > domain.MaxStored {
+		return SaleTotals{}, errSaleRange
+	}
+	// Percentages are honoured to two decimals (basis points).
+	basisPoints := int64(math.Round(discountPercent * 100))
+	discount, err := t.Total.MulRatio(basisPoints, 10000)
+	if err != nil {
+		return SaleTotals{}, errSaleRange
+	}
+	t.Discount = discount.RoundTaka()
+	// A positive round-off can lift the amount payable past the columns.
+	net, err := t.Total.Sub(t.Discount)
+	if err == nil {
+		t.NetPayable, err = net.Add(t.RoundOff)
+	}
+	if err != nil || t.NetPayable > domain.MaxStored {
+		return SaleTotals{}, errSaleRange
+	}
+	if t.NetPayable < 0 {
+		return SaleTotals{}, invalid("round_off must not make the amount payable negative")
+	}
+	if t.Paid >= t.NetPayable {
+		t.ChangeReturned, err = t.Paid.Sub(t.NetPayable)
+	} else {
+		t.Due, err = t.NetPayable.Sub(t.Paid)
+	}
+	if err != nil {
+		return SaleTotals{}, errSaleRange
+	}
+	return t, nil
+}
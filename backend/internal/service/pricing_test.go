@@ -0,0 +1,138 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"medeasy/m/domain"
+)
+
+func TestPriceSale(t *testing.T) {
+	tenForThree := PricedLine{PackPrice: domain.Taka(10), PackSize: 3, Quantity: 1}
+
+	tests := []struct {
+		name     string
+		lines    []PricedLine
+		discount float64
+		roundOff domain.Money
+		paid     domain.Money
+		want     SaleTotals
+	}{
+		{
+			name:  "whole pack prices exactly",
+			lines: []PricedLine{{PackPrice: domain.Taka(10), PackSize: 3, Quantity: 3}},
+			paid:  domain.Taka(10),
+			want:  SaleTotals{Total: domain.Taka(10), NetPayable: domain.Taka(10), Paid: domain.Taka(10)},
+		},
+		{
+			name:  "lines keep paisa until the total",
+			lines: []PricedLine{tenForThree, tenForThree},
+			paid:  domain.Taka(10),
+			// 3.33 + 3.33 = 6.66, rounded to 7.
+			want: SaleTotals{Total: domain.Taka(7), NetPayable: domain.Taka(7), Paid: domain.Taka(10), ChangeReturned: domain.Taka(3)},
+		},
+		{
+			name:     "half a taka of discount rounds up",
+			lines:    []PricedLine{{PackPrice: domain.Taka(100), PackSize: 1, Quantity: 1}},
+			discount: 12.5,
+			paid:     domain.Taka(100),
+			want: SaleTotals{Total: domain.Taka(100), Discount: domain.Taka(13), NetPayable: domain.Taka(87),
+				Paid: domain.Taka(100), ChangeReturned: domain.Taka(13)},
+		},
+		{
+			name:     "discount to two decimals",
+			lines:    []PricedLine{{PackPrice: domain.Taka(300), PackSize: 1, Quantity: 1}},
+			discount: 33.33,
+			paid:     domain.Taka(150),
+			// 99.99 of discount, rounded to 100.
+			want: SaleTotals{Total: domain.Taka(300), Discount: domain.Taka(100), NetPayable: domain.Taka(200),
+				Paid: domain.Taka(150), Due: domain.Taka(50)},
+		},
+		{
+			name:     "full discount",
+			lines:    []PricedLine{{PackPrice: domain.Taka(45), PackSize: 10, Quantity: 4}},
+			discount: 100,
+			want:     SaleTotals{Total: domain.Taka(18), Discount: domain.Taka(18)},
+		},
+		{
+			name:     "round-off and payment round to the taka",
+			lines:    []PricedLine{{PackPrice: domain.Taka(101), PackSize: 1, Quantity: 1}},
+			roundOff: domain.Paisa(-60),
+			paid:     domain.Paisa(9950),
+			want: SaleTotals{Total: domain.Taka(101), RoundOff: domain.Taka(-1), NetPayable: domain.Taka(100),
+				Paid: domain.Taka(100)},
+		},
+		{
+			name: "no lines",
+			paid: domain.Taka(5),
+			want: SaleTotals{Paid: domain.Taka(5), ChangeReturned: domain.Taka(5)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PriceSale(tt.lines, tt.discount, tt.roundOff, tt.paid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPriceSaleRejects(t *testing.T) {
+	one := []PricedLine{{PackPrice: domain.Taka(10), PackSize: 1, Quantity: 1}}
+	huge := PricedLine{PackPrice: domain.Money(math.MaxInt64 / 2), PackSize: 1, Quantity: 1}
+
+	tests := []struct {
+		name     string
+		lines    []PricedLine
+		discount float64
+		roundOff domain.Money
+		paid     domain.Money
+		invalid  bool
+	}{
+		{name: "negative discount", lines: one, discount: -1, invalid: true},
+		{name: "discount over 100", lines: one, discount: 100.01, invalid: true},
+		{name: "NaN discount", lines: one, discount: math.NaN(), invalid: true},
+		{name: "line overflows", lines: []PricedLine{{PackPrice: domain.Money(math.MaxInt64), PackSize: 1, Quantity: 2}}, invalid: true},
+		{name: "total overflows", lines: []PricedLine{huge, huge, huge}, invalid: true},
+		{name: "total beyond the columns", lines: []PricedLine{{PackPrice: domain.MaxStored, PackSize: 1, Quantity: 2}}, invalid: true},
+		{name: "round-off over a taka", lines: one, roundOff: domain.Paisa(101), invalid: true},
+		{name: "round-off under minus a taka", lines: one, roundOff: domain.Paisa(-101), invalid: true},
+		{name: "round-off makes the sale negative", roundOff: domain.Taka(-1), invalid: true},
+		{name: "negative payment", lines: one, paid: domain.Taka(-1), invalid: true},
+		{name: "payment beyond the columns", lines: one, paid: domain.MaxStored + 1, invalid: true},
+		{name: "payment rounds beyond the columns", lines: one, paid: domain.MaxStored, invalid: true},
+		{name: "round-off lifts the total beyond the columns", lines: []PricedLine{{PackPrice: domain.MaxStored - 99, PackSize: 1, Quantity: 1}},
+			roundOff: domain.Taka(1), invalid: true},
+		{name: "pack size zero", lines: []PricedLine{{PackPrice: domain.Taka(10), PackSize: 0, Quantity: 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := PriceSale(tt.lines, tt.discount, tt.roundOff, tt.paid)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			var svcErr *Error
+			if isInvalid := errors.As(err, &svcErr) && svcErr.Kind == KindInvalid; isInvalid != tt.invalid {
+				t.Errorf("error %v: invalid = %v, want %v", err, isInvalid, tt.invalid)
+			}
+		})
+	}
+}
+
+func TestPricedLineUnitPrice(t *testing.T) {
+	price, err := PricedLine{PackPrice: domain.Taka(10), PackSize: 3}.UnitPrice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price != domain.Paisa(333) {
+		t.Errorf("unit price = %v, want 3.33", price)
+	}
+	if _, err := (PricedLine{PackPrice: domain.Taka(10)}).UnitPrice(); err == nil {
+		t.Error("unit price of an empty pack did not fail")
+	}
+}
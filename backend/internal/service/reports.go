@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"medeasy/m/domain"
+)
+
+// Reports summarises a pharmacy's sales.
+type Reports interface {
+	Daily(ctx context.Context, pharmacyID int64) (domain.SalesSummary, error)
+	Monthly(ctx context.Context, pharmacyID int64) (domain.SalesSummary, error)
+	// Sales lists sales with their items between optional YYYY-MM-DD dates.
+	Sales(ctx context.Context, pharmacyID int64, startDate, endDate string) ([]domain.SaleReport, error)
+	// ControlledRegister lists the controlled medicines dispensed between
+	// optional YYYY-MM-DD dates, oldest first.
+	ControlledRegister(ctx context.Context, pharmacyID int64, startDate, endDate string) ([]domain.ControlledDispense, error)
+}
+
+type reportService struct {
+	reports ReportRepository
+}
+
+func (s *reportService) Daily(ctx context.Context, pharmacyID int64) (domain.SalesSummary, error) {
+	return s.reports.DailySummary(ctx, pharmacyID)
+}
+
+func (s *reportService) Monthly(ctx context.Context, pharmacyID int64) (domain.SalesSummary, error) {
+	return s.reports.MonthlySummary(ctx, pharmacyID)
+}
+
+func (s *reportService) Sales(ctx context.Context, pharmacyID int64, startDate, endDate string) ([]domain.SaleReport, error) {
+	startDate, endDate, err := dateRange(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	sales, err := s.reports.Sales(ctx, pharmacyID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	return withItems(ctx, s.reports, sales)
+}
+
+func (s *reportService) ControlledRegister(ctx context.Context, pharmacyID int64, startDate, endDate string) ([]domain.ControlledDispense, error) {
+	startDate, endDate, err := dateRange(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	return s.reports.ControlledRegister(ctx, pharmacyID, startDate, endDate)
+}
+
+// dateRange trims and checks optional YYYY-MM-DD report bounds.
+func dateRange(startDate, endDate string) (string, string, error) {
+	startDate = strings.TrimSpace(startDate)
+	if startDate != "" {
+		if _, err := time.Parse("2006-01-02", startDate); err != nil {
+			return "", "", invalid("start_date must be in YYYY-MM-DD format")
+		}
+	}
+	endDate = strings.TrimSpace(endDate)
+	if endDate != "" {
+		if _, err := time.Parse("2006-01-02", endDate); err != nil {
+			return "", "", invalid("end_date must be in YYYY-MM-DD format")
+		}
+	}
+	return startDate, endDate, nil
+}
+
+// withItems attaches each sale's line items.
+func withItems(ctx context.Context, reports ReportRepository, sales []domain.Sale) ([]domain.SaleReport, error) {
+	report := make([]domain.SaleReport, len(sales))
+	if len(sales) == 0 {
+		return report, nil
+	}
+
+	ids := make([]int64, len(sales))
+	for i, sale := range sales {
+		ids[i] = sale.ID
+	}
+	rows, err := reports.SaleItems(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	itemsBySale := make(map[int64][]domain.SaleItemDetail)
+	for _, row := range rows {
+		itemsBySale[row.SaleID] = append(itemsBySale[row.SaleID], row)
+	}
+	for i, sale := range sales {
+		items := itemsBySale[sale.ID]
+		if items == nil {
+			items = []domain.SaleItemDetail{}
+		}
+		report[i] = domain.SaleReport{Sale: sale, Items: items}
+	}
+	return report, nil
+}
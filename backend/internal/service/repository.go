@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"medeasy/m/domain"
+)
+
+// Repositories return these for missing rows and unique violations so
+// services can tell them apart from infrastructure failures.
+var (
+	ErrNotFound  = errors.New("record not found")
+	ErrDuplicate = errors.New("duplicate record")
+)
+
+// Repositories groups the persistence interfaces the services need.
+type Repositories struct {
+	Users         UserRepository
+	Pharmacies    PharmacyRepository
+	Medicines     MedicineRepository
+	Inventory     InventoryRepository
+	Sales         SaleRepository
+	Reports       ReportRepository
+	APIKeys       APIKeyRepository
+	Sync          SyncRepository
+	Submissions   SubmissionRepository
+	Interactions  InteractionRepository
+	Prescriptions PrescriptionRepository
+	Attachments   AttachmentStore
+}
+
+// UserRepository persists user accounts.
+type UserRepository interface {
+	ByEmail(ctx context.Context, email string) (domain.User, error)
+	ByID(ctx context.Context, id int64) (domain.User, error)
+	// CreateOwner inserts the owner and their pharmacy together and links them.
+	CreateOwner(ctx context.Context, user *domain.User, pharmacy *domain.Pharmacy) error
+	// CreateEmployee returns ErrNotFound when the user's pharmacy does not exist.
+	CreateEmployee(ctx context.Context, user *domain.User) error
+	UpdatePassword(ctx context.Context, userID int64, hash string) error
+}
+
+// PharmacyRepository persists pharmacies.
+type PharmacyRepository interface {
+	Create(ctx context.Context, pharmacy *domain.Pharmacy) error
+	Update(ctx context.Context, pharmacy domain.Pharmacy) error
+	List(ctx context.Context) ([]domain.Pharmacy, error)
+}
+
+// MedicineRepository reads the shared medicine catalog.
+type MedicineRepository interface {
+	ByID(ctx context.Context, id int64) (domain.Medicine, error)
+	// Search ranks medicines that are still in the catalog against query and
+	// returns limit of them, starting after the result with afterScore and
+	// afterID, or from the top when afterID is zero.
+	Search(ctx context.Context, query string, afterScore float64, afterID int64, limit int) ([]domain.RankedMedicine, error)
+	// Packs lists a medicine's priced packs, smallest first.
+	Packs(ctx context.Context, medicineID int64) ([]domain.MedicinePack, error)
+	ByBarcode(ctx context.Context, gtin string) (domain.MedicineBarcode, error)
+	// AddBarcode returns ErrDuplicate when the GTIN is already registered.
+	AddBarcode(ctx context.Context, barcode *domain.MedicineBarcode) error
+}
+
+// SubmissionRepository persists catalog submissions and applies their merges.
+type SubmissionRepository interface {
+	// Create returns ErrDuplicate when the item already has a pending
+	// submission.
+	Create(ctx context.Context, submission *domain.CatalogSubmission) error
+	ByID(ctx context.Context, id int64) (domain.CatalogSubmission, error)
+	// List returns submissions with status, or all when status is empty, of
+	// one pharmacy or of every pharmacy when pharmacyID is zero. Newest first.
+	List(ctx context.Context, pharmacyID int64, status string) ([]domain.CatalogSubmission, error)
+	// Candidates finds catalog medicines resembling a brand and generic name.
+	Candidates(ctx context.Context, brandName, genericName string, limit int) ([]domain.CatalogCandidate, error)
+	// Merge links the submitting pharmacy's custom items of the submitted
+	// medicine, and the sale items that sold them, to medicineID, and closes
+	// the submission and any other pending ones for those items. AddToCatalog
+	// does the same with a new catalog medicine made from the submission.
+	// Both return ErrNotFound when the submission is no longer pending.
+	Merge(ctx context.Context, id, medicineID int64) (domain.CatalogSubmission, domain.Relinked, error)
+	AddToCatalog(ctx context.Context, id int64) (domain.CatalogSubmission, domain.Relinked, error)
+	// Reject returns ErrNotFound when the submission is no longer pending.
+	Reject(ctx context.Context, id int64, note string) (domain.CatalogSubmission, error)
+}
+
+// InteractionRepository reads the drug interaction knowledge base.
+type InteractionRepository interface {
+	// Among returns the interactions whose two ingredients are both in
+	// ingredients.
+	Among(ctx context.Context, ingredients []string) ([]domain.DrugInteraction, error)
+}
+
+// InventoryRepository persists a pharmacy's stock.
+type InventoryRepository interface {
+	Search(ctx context.Context, pharmacyID int64, query string, limit int) ([]domain.InventorySearchResult, error)
+	ByID(ctx context.Context, id int64) (domain.InventoryItem, error)
+	Create(ctx context.Context, item *domain.InventoryItem) error
+	// UpdatePricing, SetQuantity and AdjustQuantity return the updated item,
+	// or ErrNotFound when version is not AnyVersion and no longer matches.
+	UpdatePricing(ctx context.Context, item domain.InventoryItem, version int64) (domain.InventoryItem, error)
+	SetQuantity(ctx context.Context, id, quantity, version int64) (domain.InventoryItem, error)
+	// AdjustQuantity also returns ErrNotFound when stock would go below zero.
+	AdjustQuantity(ctx context.Context, id, delta, version int64) (domain.InventoryItem, error)
+	ExpiringWithin(ctx context.Context, pharmacyID int64, days int) ([]domain.ExpiryAlert, error)
+	// Equivalents returns the catalog brands sharing medicineID's generic
+	// name, strength and dosage form, medicineID itself included, with the
+	// pharmacy's stock of each. Discontinued brands other than medicineID
+	// are left out.
+	Equivalents(ctx context.Context, pharmacyID, medicineID int64) ([]domain.Substitute, error)
+	// Lots lists the pharmacy's in-stock lots of a medicine, earliest expiry
+	// first.
+	Lots(ctx context.Context, pharmacyID, medicineID int64) ([]domain.InventoryItem, error)
+}
+
+// SaleRepository writes sales atomically with their stock movements.
+type SaleRepository interface {
+	// InTx runs fn in one transaction, committing only when fn returns nil.
+	InTx(ctx context.Context, fn func(tx SaleTx) error) error
+	// Conflicts lists conflicts with the given status, oldest first.
+	Conflicts(ctx context.Context, pharmacyID int64, status string) ([]domain.SaleConflict, error)
+	Conflict(ctx context.Context, pharmacyID, id int64) (domain.SaleConflict, error)
+	// ResolveConflict returns ErrNotFound unless the conflict is still open.
+	ResolveConflict(ctx context.Context, pharmacyID, id, userID int64, resolution string) (domain.SaleConflict, error)
+}
+
+// SaleTx is the set of operations available while recording a sale.
+type SaleTx interface {
+	// ClaimIdempotencyKey serialises requests sharing key until the
+	// transaction ends, then returns the sale already recorded under it or
+	// ErrNotFound.
+	ClaimIdempotencyKey(ctx context.Context, pharmacyID int64, key string) (domain.Sale, error)
+	// LockInventory locks the pharmacy's items among ids, in id order so
+	// concurrent sales cannot deadlock, and returns them in that order.
+	LockInventory(ctx context.Context, pharmacyID int64, ids []int64) ([]domain.InventoryItem, error)
+	// CreateSale records the sale as made at soldAt.
+	CreateSale(ctx context.Context, sale *domain.Sale, soldAt time.Time) error
+	AddItem(ctx context.Context, item *domain.SaleItem) error
+	// DecrementStock returns ErrNotFound when less than quantity is in stock.
+	DecrementStock(ctx context.Context, inventoryID, quantity int64) error
+	RecordConflict(ctx context.Context, conflict *domain.SaleConflict) error
+	RecordInteraction(ctx context.Context, interaction *domain.SaleInteraction) error
+	RecordPrescription(ctx context.Context, prescription *domain.SalePrescription) error
+	// LockPrescription returns the pharmacy's prescription with its items,
+	// locked until the sale commits so concurrent refills cannot dispense
+	// more than it allows.
+	LockPrescription(ctx context.Context, pharmacyID, id int64) (domain.Prescription, error)
+	// RecordFill adds fill.Quantity to the item's dispensed units.
+	RecordFill(ctx context.Context, fill *domain.PrescriptionFill) error
+	// Schedules returns the schedule of each medicine among ids.
+	Schedules(ctx context.Context, ids []int64) (map[int64]string, error)
+	// IngredientSchedules returns the schedule listed for each of
+	// ingredients that has one.
+	IngredientSchedules(ctx context.Context, ingredients []string) (map[string]string, error)
+	// Conflicts lists the conflicts recorded for a sale.
+	Conflicts(ctx context.Context, saleID int64) ([]domain.SaleConflict, error)
+	// SoldGenerics returns the generic name of each line of a sale, empty
+	// when unknown.
+	SoldGenerics(ctx context.Context, saleID int64) ([]string, error)
+	// Fills lists what a sale dispensed against a stored prescription.
+	Fills(ctx context.Context, saleID int64) ([]domain.PrescriptionFill, error)
+}
+
+// ReportRepository reads aggregated sales.
+type ReportRepository interface {
+	DailySummary(ctx context.Context, pharmacyID int64) (domain.SalesSummary, error)
+	MonthlySummary(ctx context.Context, pharmacyID int64) (domain.SalesSummary, error)
+	// Sales lists sales newest first; empty dates leave that bound open.
+	Sales(ctx context.Context, pharmacyID int64, startDate, endDate string) ([]domain.Sale, error)
+	SaleItems(ctx context.Context, saleIDs []int64) ([]domain.SaleItemDetail, error)
+	// ControlledRegister lists sold lines of controlled medicines, oldest
+	// first; empty dates leave that bound open.
+	ControlledRegister(ctx context.Context, pharmacyID int64, startDate, endDate string) ([]domain.ControlledDispense, error)
+}
+
+// PrescriptionRepository persists stored prescriptions. Prescriptions are
+// returned with their items.
+type PrescriptionRepository interface {
+	// Create inserts the prescription and its items together.
+	Create(ctx context.Context, prescription *domain.Prescription) error
+	ByID(ctx context.Context, pharmacyID, id int64) (domain.Prescription, error)
+	// ByPatient lists a pharmacy's prescriptions newest first, matching
+	// phone exactly when set and otherwise name by similarity.
+	ByPatient(ctx context.Context, pharmacyID int64, phone, name string, limit int) ([]domain.Prescription, error)
+	// SetScan records key as the prescription's scan.
+	SetScan(ctx context.Context, pharmacyID, id int64, key string) error
+}
+
+// AttachmentStore keeps uploaded files, such as prescription scans, under
+// keys chosen by the services.
+type AttachmentStore interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open returns ErrNotFound when nothing is stored under key.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete succeeds when nothing is stored under key.
+	Delete(ctx context.Context, key string) error
+}
+
+// APIKeyRepository persists integration credentials.
+type APIKeyRepository interface {
+	ByPrefix(ctx context.Context, prefix string) (domain.APIKey, error)
+	// TouchLastUsed records use, throttled to avoid a write per request.
+	TouchLastUsed(ctx context.Context, id int64) error
+	Create(ctx context.Context, key *domain.APIKey) error
+	List(ctx context.Context, pharmacyID int64) ([]domain.APIKey, error)
+	Revoke(ctx context.Context, id, pharmacyID int64) (time.Time, error)
+}
+
+// SyncRepository reads rows in change order for offline clients, returning
+// up to q.Limit changes after q.After made by transactions below q.Horizon.
+type SyncRepository interface {
+	// Horizon returns the oldest transaction that may still be running.
+	Horizon(ctx context.Context) (int64, error)
+	Inventory(ctx context.Context, q SyncQuery) ([]Synced[domain.InventoryItem], error)
+	Medicines(ctx context.Context, q SyncQuery) ([]Synced[domain.Medicine], error)
+	Sales(ctx context.Context, q SyncQuery) ([]Synced[domain.Sale], error)
+	// Tombstones lists rows deleted from table.
+	Tombstones(ctx context.Context, table string, q SyncQuery) ([]Tombstone, error)
+}
@@ -0,0 +1,703 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"medeasy/m/domain"
+)
+
+// Sales records sales and the stock they consume.
+type Sales interface {
+	// Create records a sale. With an IdempotencyKey, repeating the request
+	// returns the original receipt marked Replayed instead of selling twice.
+	Create(ctx context.Context, in NewSale) (SaleReceipt, error)
+	// Conflicts lists a pharmacy's stock and prescription conflicts by
+	// status: open (the default), resolved or all.
+	Conflicts(ctx context.Context, pharmacyID int64, status string) ([]domain.SaleConflict, error)
+	// ResolveConflict closes an open conflict with the owner's note.
+	ResolveConflict(ctx context.Context, pharmacyID, conflictID, userID int64, resolution string) (domain.SaleConflict, error)
+	// CheckInteractions returns the known interactions among the generic
+	// names of a cart, severe first.
+	CheckInteractions(ctx context.Context, genericNames []string) ([]domain.InteractionWarning, error)
+}
+
+// NewSale is a sale as submitted at the counter. Exactly one of UserID and
+// APIKeyID identifies who made it.
+type NewSale struct {
+	PharmacyID int64
+	UserID     int64
+	// Role is the user's role, which decides whether they may override.
+	Role            string
+	APIKeyID        int64
+	Items           []SaleLine
+	DiscountPercent float64
+	PaidAmount      domain.Money
+	RoundOff        domain.Money
+	// IdempotencyKey is chosen by the client, unique per pharmacy.
+	IdempotencyKey string
+	// CreatedAt is when the client made the sale, for sales queued offline.
+	// RFC 3339, or a local time without offset in the pharmacy's time zone.
+	CreatedAt string
+	// Offline accepts lines that sell more than is in stock, or that sell
+	// prescription-only and controlled medicines without a prescription
+	// covering them, recording a conflict for each instead of rejecting the
+	// sale. It requires an IdempotencyKey, since offline sales are retried.
+	Offline bool
+	// Override allows severe interactions between the sold medicines. Only
+	// owners and pharmacists may send one, offline or not.
+	Override *InteractionOverride
+	// Prescription is required to sell prescription-only and controlled
+	// medicines.
+	Prescription *Prescription
+	// PrescriptionID fills a stored prescription instead; it is an
+	// alternative to Prescription.
+	PrescriptionID int64
+}
+
+// Prescription is the prescription a sale is dispensed against.
+type Prescription struct {
+	Prescriber         string
+	RegistrationNumber string
+	PatientName        string
+	PatientAddress     string
+	// PrescribedOn is the prescription's date, YYYY-MM-DD.
+	PrescribedOn string
+	// ImageRef optionally points to a scan of the prescription.
+	ImageRef string
+}
+
+// SaleLine is one requested inventory item.
+type SaleLine struct {
+	InventoryID int64
+	Quantity    int64
+	// PrescriptionItemID names the item of the sale's stored prescription
+	// the line fills. Without it the line fills the first item prescribing
+	// its medicine that has units left.
+	PrescriptionItemID int64
+}
+
+// SaleReceipt is the recorded sale with its computed totals.
+type SaleReceipt struct {
+	SaleID int64
+	Lines  int
+	// Replayed is set when an earlier sale with the same key was returned.
+	Replayed bool
+	// Conflicts lists the lines of an offline sale that oversold stock or
+	// sold restricted medicines without a prescription.
+	Conflicts []domain.SaleConflict
+	// Interactions lists the known interactions between the sold medicines.
+	Interactions []domain.InteractionWarning
+	// Fills lists the units dispensed against the stored prescription.
+	Fills []domain.PrescriptionFill
+	SaleTotals
+}
+
+// Conflict statuses accepted by Sales.Conflicts.
+const (
+	ConflictsOpen     = "open"
+	ConflictsResolved = "resolved"
+	ConflictsAll      = "all"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+	// maxBackdate bounds how old a client-reported sale time may be.
+	maxBackdate = 30 * 24 * time.Hour
+	// maxClockSkew is how far ahead of the server a client clock may run
+	// before the sale time is clamped to now.
+	maxClockSkew = 5 * time.Minute
+)
+
+type salesService struct {
+	sales        SaleRepository
+	interactions InteractionRepository
+	location     *time.Location
+	now          func() time.Time
+}
+
+func (s *salesService) Create(ctx context.Context, in NewSale) (SaleReceipt, error) {
+	if len(in.Items) == 0 {
+		return SaleReceipt{}, invalid("no items in sale")
+	}
+	if in.PharmacyID <= 0 || (in.UserID <= 0 && in.APIKeyID <= 0) {
+		return SaleReceipt{}, forbidden("invalid context")
+	}
+	if len(in.IdempotencyKey) > maxIdempotencyKeyLength {
+		return SaleReceipt{}, invalid(fmt.Sprintf("idempotency key must be at most %d characters", maxIdempotencyKeyLength))
+	}
+	if in.Offline && in.IdempotencyKey == "" {
+		return SaleReceipt{}, invalid("offline sales need an Idempotency-Key or client_sale_id")
+	}
+	if in.Override != nil {
+		if in.UserID <= 0 || (in.Role != RoleOwner && in.Role != RolePharmacist) {
+			return SaleReceipt{}, forbidden("only a pharmacist or owner can override an interaction")
+		}
+		in.Override.Reason = strings.TrimSpace(in.Override.Reason)
+		if in.Override.Reason == "" {
+			return SaleReceipt{}, invalid("interaction override needs a reason")
+		}
+	}
+	if in.Prescription != nil && in.PrescriptionID > 0 {
+		return SaleReceipt{}, invalid("send prescription or prescription_id, not both")
+	}
+	for _, line := range in.Items {
+		if line.PrescriptionItemID != 0 && in.PrescriptionID <= 0 {
+			return SaleReceipt{}, invalid("prescription_item_id needs prescription_id")
+		}
+	}
+	soldAt, err := s.soldAt(in.CreatedAt)
+	if err != nil {
+		return SaleReceipt{}, err
+	}
+	if in.Prescription != nil {
+		if err := s.checkPrescription(in.Prescription, soldAt); err != nil {
+			return SaleReceipt{}, err
+		}
+	}
+
+	sale := domain.Sale{PharmacyID: in.PharmacyID}
+	// Sales made by an integration are attributed to the key instead of a user.
+	if in.UserID > 0 {
+		sale.UserID = &in.UserID
+	}
+	if in.APIKeyID > 0 {
+		sale.APIKeyID = &in.APIKeyID
+	}
+	if in.IdempotencyKey != "" {
+		hash := requestHash(in)
+		sale.IdempotencyKey = &in.IdempotencyKey
+		sale.RequestHash = &hash
+	}
+
+	var receipt SaleReceipt
+	err = s.sales.InTx(ctx, func(tx SaleTx) error {
+		if sale.IdempotencyKey != nil {
+			// Waits for an in-flight request with the same key to finish.
+			existing, err := tx.ClaimIdempotencyKey(ctx, in.PharmacyID, *sale.IdempotencyKey)
+			switch {
+			case err == nil:
+				receipt, err = s.replay(ctx, tx, existing, *sale.RequestHash)
+				return err
+			case !errors.Is(err, ErrNotFound):
+				return err
+			}
+		}
+
+		ids := make([]int64, len(in.Items))
+		for i, line := range in.Items {
+			if line.Quantity <= 0 {
+				return invalid(fmt.Sprintf("quantity for item %d must be positive", line.InventoryID))
+			}
+			ids[i] = line.InventoryID
+		}
+		// Stock stays locked until the sale commits, so the checks below
+		// cannot be invalidated by a concurrent sale.
+		locked, err := tx.LockInventory(ctx, in.PharmacyID, ids)
+		if err != nil {
+			return err
+		}
+		byID := make(map[int64]domain.InventoryItem, len(locked))
+		remaining := make(map[int64]int64, len(locked))
+		for _, inv := range locked {
+			byID[inv.ID] = inv
+			remaining[inv.ID] = inv.Quantity
+		}
+
+		items := make([]domain.InventoryItem, len(in.Items))
+		lines := make([]PricedLine, len(in.Items))
+		// available is what each line can take from stock, after earlier
+		// lines of the same sale.
+		available := make([]int64, len(in.Items))
+		for i, line := range in.Items {
+			inv, ok := byID[line.InventoryID]
+			if !ok {
+				return invalid(fmt.Sprintf("inventory item %d not found", line.InventoryID))
+			}
+			available[i] = min(line.Quantity, remaining[inv.ID])
+			remaining[inv.ID] -= available[i]
+			if available[i] < line.Quantity && !in.Offline {
+				return errInsufficientStock(line.InventoryID)
+			}
+			items[i] = inv
+			lines[i] = PricedLine{PackPrice: inv.PackSalePrice, PackSize: inv.PackSize, Quantity: line.Quantity}
+		}
+
+		schedules, err := classify(ctx, tx, items)
+		if err != nil {
+			return err
+		}
+		var stored domain.Prescription
+		// fills holds what each line dispenses against the stored
+		// prescription, and uncovered the units of restricted medicines an
+		// offline sale made without a prescription covering them.
+		var fills []domain.PrescriptionFill
+		var uncovered []int64
+		switch {
+		case in.PrescriptionID > 0:
+			stored, err = tx.LockPrescription(ctx, in.PharmacyID, in.PrescriptionID)
+			if errors.Is(err, ErrNotFound) {
+				return invalid("prescription not found")
+			}
+			if err != nil {
+				return err
+			}
+			if fills, uncovered, err = fillPrescription(stored, in.Items, items, schedules, in.Offline); err != nil {
+				return err
+			}
+		case in.Prescription == nil:
+			if uncovered, err = requirePrescription(in.Items, items, schedules, in.Offline); err != nil {
+				return err
+			}
+		}
+
+		generics := make([]string, len(items))
+		for i, inv := range items {
+			if inv.GenericName != nil {
+				generics[i] = *inv.GenericName
+			}
+		}
+		warnings, err := findInteractions(ctx, s.interactions, generics)
+		if err != nil {
+			return err
+		}
+		severe := severeInteractions(warnings)
+		if len(severe) > 0 && in.Override == nil {
+			return errInteractionOverride(severe)
+		}
+
+		totals, err := PriceSale(lines, in.DiscountPercent, in.RoundOff, in.PaidAmount)
+		if err != nil {
+			return err
+		}
+
+		sale.TotalAmount = totals.Total
+		sale.Discount = totals.Discount
+		sale.PaidAmount = totals.Paid
+		sale.DueAmount = totals.Due
+		sale.RoundOff = totals.RoundOff
+		sale.ChangeReturned = totals.ChangeReturned
+		if err := tx.CreateSale(ctx, &sale, soldAt); err != nil {
+			return err
+		}
+
+		var conflicts []domain.SaleConflict
+		var filled []domain.PrescriptionFill
+		for i, inv := range items {
+			// PriceSale has checked every line can be priced.
+			unitPrice, _ := lines[i].UnitPrice()
+			subtotal, _ := lines[i].Subtotal()
+			item := domain.SaleItem{
+				SaleID:      sale.ID,
+				MedicineID:  inv.MedicineID,
+				InventoryID: inv.ID,
+				Quantity:    lines[i].Quantity,
+				UnitPrice:   unitPrice,
+				Subtotal:    subtotal,
+				Schedule:    schedules[i],
+			}
+			if err := tx.AddItem(ctx, &item); err != nil {
+				return err
+			}
+			if fills != nil && fills[i].PrescriptionItemID != 0 {
+				fills[i].SaleID = sale.ID
+				fills[i].SaleItemID = item.ID
+				if err := tx.RecordFill(ctx, &fills[i]); err != nil {
+					return err
+				}
+				filled = append(filled, fills[i])
+			}
+			err := tx.DecrementStock(ctx, inv.ID, available[i])
+			if errors.Is(err, ErrNotFound) {
+				return errInsufficientStock(inv.ID)
+			}
+			if err != nil {
+				return err
+			}
+			if available[i] < lines[i].Quantity {
+				oversold := domain.SaleConflict{
+					Kind:        domain.ConflictStock,
+					PharmacyID:  in.PharmacyID,
+					SaleID:      sale.ID,
+					SaleItemID:  item.ID,
+					InventoryID: inv.ID,
+					Requested:   lines[i].Quantity,
+					Available:   available[i],
+					Shortfall:   lines[i].Quantity - available[i],
+				}
+				if inv.BrandName != nil {
+					oversold.BrandName = *inv.BrandName
+				}
+				if err := tx.RecordConflict(ctx, &oversold); err != nil {
+					return err
+				}
+				conflicts = append(conflicts, oversold)
+			}
+			if uncovered != nil && uncovered[i] > 0 {
+				unverified := domain.SaleConflict{
+					Kind:        domain.ConflictPrescription,
+					PharmacyID:  in.PharmacyID,
+					SaleID:      sale.ID,
+					SaleItemID:  item.ID,
+					InventoryID: inv.ID,
+					Requested:   lines[i].Quantity,
+					Available:   lines[i].Quantity - uncovered[i],
+					Shortfall:   uncovered[i],
+				}
+				if inv.BrandName != nil {
+					unverified.BrandName = *inv.BrandName
+				}
+				if err := tx.RecordConflict(ctx, &unverified); err != nil {
+					return err
+				}
+				conflicts = append(conflicts, unverified)
+			}
+		}
+		if in.Prescription != nil {
+			p := in.Prescription
+			prescription := domain.SalePrescription{
+				SaleID:             sale.ID,
+				Prescriber:         p.Prescriber,
+				RegistrationNumber: p.RegistrationNumber,
+				PatientName:        p.PatientName,
+				PrescribedOn:       p.PrescribedOn,
+			}
+			if p.PatientAddress != "" {
+				prescription.PatientAddress = &p.PatientAddress
+			}
+			if p.ImageRef != "" {
+				prescription.ImageRef = &p.ImageRef
+			}
+			if err := tx.RecordPrescription(ctx, &prescription); err != nil {
+				return err
+			}
+		}
+		if in.PrescriptionID > 0 {
+			prescription := domain.SalePrescription{
+				SaleID:             sale.ID,
+				Prescriber:         stored.Prescriber,
+				RegistrationNumber: stored.RegistrationNumber,
+				PatientName:        stored.PatientName,
+				PrescribedOn:       stored.PrescribedOn,
+				ImageRef:           stored.ScanKey,
+				PrescriptionID:     &stored.ID,
+			}
+			if stored.PatientAddress != "" {
+				prescription.PatientAddress = &stored.PatientAddress
+			}
+			if err := tx.RecordPrescription(ctx, &prescription); err != nil {
+				return err
+			}
+		}
+		for _, warning := range severe {
+			dispensed := domain.SaleInteraction{
+				SaleID:      sale.ID,
+				IngredientA: warning.IngredientA,
+				IngredientB: warning.IngredientB,
+				Severity:    warning.Severity,
+			}
+			dispensed.OverriddenBy = &in.UserID
+			dispensed.Reason = &in.Override.Reason
+			if err := tx.RecordInteraction(ctx, &dispensed); err != nil {
+				return err
+			}
+		}
+		receipt = SaleReceipt{SaleID: sale.ID, Lines: len(items), Conflicts: conflicts, Interactions: warnings, Fills: filled, SaleTotals: totals}
+		return nil
+	})
+	return receipt, err
+}
+
+func (s *salesService) Conflicts(ctx context.Context, pharmacyID int64, status string) ([]domain.SaleConflict, error) {
+	switch status {
+	case "":
+		status = ConflictsOpen
+	case ConflictsOpen, ConflictsResolved, ConflictsAll:
+	default:
+		return nil, invalid("status must be open, resolved or all")
+	}
+	return s.sales.Conflicts(ctx, pharmacyID, status)
+}
+
+func (s *salesService) ResolveConflict(ctx context.Context, pharmacyID, conflictID, userID int64, resolution string) (domain.SaleConflict, error) {
+	resolution = strings.TrimSpace(resolution)
+	if resolution == "" {
+		return domain.SaleConflict{}, invalid("resolution is required")
+	}
+	existing, err := s.sales.Conflict(ctx, pharmacyID, conflictID)
+	if errors.Is(err, ErrNotFound) {
+		return domain.SaleConflict{}, notFound("conflict not found")
+	}
+	if err != nil {
+		return domain.SaleConflict{}, err
+	}
+	if existing.ResolvedAt != nil {
+		return domain.SaleConflict{}, conflict("conflict is already resolved")
+	}
+	resolved, err := s.sales.ResolveConflict(ctx, pharmacyID, conflictID, userID, resolution)
+	if errors.Is(err, ErrNotFound) {
+		// Resolved by someone else since it was read.
+		return domain.SaleConflict{}, conflict("conflict is already resolved")
+	}
+	return resolved, err
+}
+
+func (s *salesService) CheckInteractions(ctx context.Context, genericNames []string) ([]domain.InteractionWarning, error) {
+	if len(genericNames) > maxCheckedGenerics {
+		return nil, invalid(fmt.Sprintf("at most %d generic names can be checked", maxCheckedGenerics))
+	}
+	warnings, err := findInteractions(ctx, s.interactions, genericNames)
+	if warnings == nil {
+		warnings = []domain.InteractionWarning{}
+	}
+	return warnings, err
+}
+
+// classify returns the schedule of each of items: the catalog medicine's,
+// or for custom medicines the most restricted listed for the ingredients of
+// their generic name, matched like the catalog's.
+func classify(ctx context.Context, tx SaleTx, items []domain.InventoryItem) ([]string, error) {
+	var ids []int64
+	var keys []string
+	for _, inv := range items {
+		if inv.MedicineID != nil {
+			ids = append(ids, *inv.MedicineID)
+		} else if inv.GenericName != nil {
+			keys = append(keys, domain.GenericKeys(*inv.GenericName)...)
+		}
+	}
+	medicines, err := tx.Schedules(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	ingredients, err := tx.IngredientSchedules(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	schedules := make([]string, len(items))
+	for i, inv := range items {
+		schedules[i] = domain.ScheduleOTC
+		switch {
+		case inv.MedicineID != nil:
+			if schedule, ok := medicines[*inv.MedicineID]; ok {
+				schedules[i] = schedule
+			}
+		case inv.GenericName != nil:
+			schedules[i] = domain.ScheduleOf(*inv.GenericName, ingredients)
+		}
+	}
+	return schedules, nil
+}
+
+// requirePrescription returns the units of each line that sell a
+// prescription-only or controlled medicine, for a sale without a
+// prescription. Unless offline, any such line is an error.
+func requirePrescription(lines []SaleLine, items []domain.InventoryItem, schedules []string, offline bool) ([]int64, error) {
+	uncovered := make([]int64, len(lines))
+	var restricted []string
+	for i, line := range lines {
+		if schedules[i] != domain.ScheduleOTC {
+			uncovered[i] = line.Quantity
+			restricted = append(restricted, itemName(items[i]))
+		}
+	}
+	if len(restricted) > 0 && !offline {
+		return nil, &Error{
+			Kind:    KindInvalid,
+			Message: fmt.Sprintf("a prescription is required for %s", strings.Join(restricted, ", ")),
+			Reason:  "prescription_required",
+		}
+	}
+	return uncovered, nil
+}
+
+// fillPrescription matches the sale's lines to the items of p they fill,
+// indexed like lines; lines filling nothing have a zero fill. A line may not
+// take more than its item has left, and prescription-only and controlled
+// medicines must fill an item. Offline, lines fill what is left instead, and
+// the units of restricted medicines left uncovered are returned indexed like
+// lines.
+func fillPrescription(p domain.Prescription, lines []SaleLine, items []domain.InventoryItem, schedules []string, offline bool) ([]domain.PrescriptionFill, []int64, error) {
+	byID := make(map[int64]domain.PrescriptionItem, len(p.Items))
+	remaining := make(map[int64]int64, len(p.Items))
+	for _, item := range p.Items {
+		byID[item.ID] = item
+		remaining[item.ID] = item.Remaining
+	}
+	fills := make([]domain.PrescriptionFill, len(lines))
+	uncovered := make([]int64, len(lines))
+	var restricted []string
+	for i, line := range lines {
+		inv := items[i]
+		target, ok := byID[line.PrescriptionItemID]
+		if line.PrescriptionItemID != 0 {
+			if !ok {
+				return nil, nil, invalid(fmt.Sprintf("prescription item %d is not on prescription %d", line.PrescriptionItemID, p.ID))
+			}
+			if target.MedicineID != nil && (inv.MedicineID == nil || *inv.MedicineID != *target.MedicineID) {
+				return nil, nil, invalid(fmt.Sprintf("inventory item %d is not the medicine of prescription item %d", inv.ID, target.ID))
+			}
+		} else if inv.MedicineID != nil {
+			for _, item := range p.Items {
+				if item.MedicineID != nil && *item.MedicineID == *inv.MedicineID && remaining[item.ID] > 0 {
+					target, ok = item, true
+					break
+				}
+			}
+		}
+		quantity := int64(0)
+		if ok {
+			quantity = min(line.Quantity, remaining[target.ID])
+			if quantity < line.Quantity && !offline {
+				return nil, nil, &Error{
+					Kind:    KindInvalid,
+					Message: fmt.Sprintf("prescription item %d has %d units left", target.ID, remaining[target.ID]),
+					Reason:  "prescription_exhausted",
+				}
+			}
+			remaining[target.ID] -= quantity
+			if quantity > 0 {
+				fills[i] = domain.PrescriptionFill{PrescriptionItemID: target.ID, Quantity: quantity}
+			}
+		}
+		if quantity < line.Quantity && schedules[i] != domain.ScheduleOTC {
+			uncovered[i] = line.Quantity - quantity
+			restricted = append(restricted, itemName(inv))
+		}
+	}
+	if len(restricted) > 0 && !offline {
+		return nil, nil, &Error{
+			Kind:    KindInvalid,
+			Message: fmt.Sprintf("prescription %d does not cover %s", p.ID, strings.Join(restricted, ", ")),
+			Reason:  "prescription_required",
+		}
+	}
+	return fills, uncovered, nil
+}
+
+// itemName names an inventory item in errors.
+func itemName(inv domain.InventoryItem) string {
+	if inv.BrandName != nil {
+		return *inv.BrandName
+	}
+	return fmt.Sprintf("item %d", inv.ID)
+}
+
+// checkPrescription trims p and checks it is complete and dated no later
+// than the sale.
+func (s *salesService) checkPrescription(p *Prescription, soldAt time.Time) error {
+	p.Prescriber = strings.TrimSpace(p.Prescriber)
+	p.RegistrationNumber = strings.TrimSpace(p.RegistrationNumber)
+	p.PatientName = strings.TrimSpace(p.PatientName)
+	p.PatientAddress = strings.TrimSpace(p.PatientAddress)
+	p.PrescribedOn = strings.TrimSpace(p.PrescribedOn)
+	p.ImageRef = strings.TrimSpace(p.ImageRef)
+	if p.Prescriber == "" || p.RegistrationNumber == "" || p.PatientName == "" {
+		return invalid("prescription needs the prescriber, their registration number and the patient")
+	}
+	date, err := time.ParseInLocation("2006-01-02", p.PrescribedOn, s.location)
+	if err != nil {
+		return invalid("prescribed_on must be in YYYY-MM-DD format")
+	}
+	if date.After(soldAt.In(s.location)) {
+		return invalid("prescribed_on is after the sale")
+	}
+	return nil
+}
+
+func errInsufficientStock(inventoryID int64) error {
+	return &Error{Kind: KindInvalid, Message: fmt.Sprintf("insufficient stock for item %d", inventoryID), Reason: "insufficient_stock"}
+}
+
+// replay rebuilds the receipt of a sale recorded earlier under the same key.
+// Interactions are looked up again among the sold generics.
+func (s *salesService) replay(ctx context.Context, tx SaleTx, sale domain.Sale, hash string) (SaleReceipt, error) {
+	if sale.RequestHash == nil || *sale.RequestHash != hash {
+		return SaleReceipt{}, conflict("idempotency key was already used for a different sale")
+	}
+	generics, err := tx.SoldGenerics(ctx, sale.ID)
+	if err != nil {
+		return SaleReceipt{}, err
+	}
+	warnings, err := findInteractions(ctx, s.interactions, generics)
+	if err != nil {
+		return SaleReceipt{}, err
+	}
+	conflicts, err := tx.Conflicts(ctx, sale.ID)
+	if err != nil {
+		return SaleReceipt{}, err
+	}
+	fills, err := tx.Fills(ctx, sale.ID)
+	if err != nil {
+		return SaleReceipt{}, err
+	}
+	return SaleReceipt{
+		SaleID:       sale.ID,
+		Lines:        len(generics),
+		Replayed:     true,
+		Conflicts:    conflicts,
+		Interactions: warnings,
+		Fills:        fills,
+		SaleTotals: SaleTotals{
+			Total:          sale.TotalAmount,
+			Discount:       sale.Discount,
+			RoundOff:       sale.RoundOff,
+			NetPayable:     sale.TotalAmount - sale.Discount + sale.RoundOff,
+			Paid:           sale.PaidAmount,
+			ChangeReturned: sale.ChangeReturned,
+			Due:            sale.DueAmount,
+		},
+	}, nil
+}
+
+// soldAt resolves the client-reported sale time, defaulting to now.
+func (s *salesService) soldAt(value string) (time.Time, error) {
+	now := s.now()
+	if value == "" {
+		return now, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		t, err = time.ParseInLocation("2006-01-02T15:04:05.999999999", value, s.location)
+	}
+	if err != nil {
+		return time.Time{}, invalid("created_at must be an ISO 8601 timestamp")
+	}
+	if t.Before(now.Add(-maxBackdate)) {
+		return time.Time{}, invalid("created_at is too far in the past")
+	}
+	if t.After(now.Add(maxClockSkew)) {
+		return now, nil
+	}
+	return t, nil
+}
+
+// requestHash fingerprints what a replay must repeat for its key to match.
+func requestHash(in NewSale) string {
+	h := sha256.New()
+	for _, line := range in.Items {
+		fmt.Fprintf(h, "item %d %d\n", line.InventoryID, line.Quantity)
+	}
+	fmt.Fprintf(h, "discount %g\npaid %d\nround_off %d\ncreated_at %s\noffline %t\n",
+		in.DiscountPercent, in.PaidAmount.Paisa(), in.RoundOff.Paisa(), in.CreatedAt, in.Offline)
+	if in.Override != nil {
+		fmt.Fprintf(h, "override %q\n", in.Override.Reason)
+	}
+	if p := in.Prescription; p != nil {
+		fmt.Fprintf(h, "prescription %q %q %q %q %q %q\n", p.Prescriber, p.RegistrationNumber, p.PatientName, p.PatientAddress, p.PrescribedOn, p.ImageRef)
+	}
+	// Written only when set, so keys recorded before stored prescriptions
+	// existed still match.
+	if in.PrescriptionID > 0 {
+		fmt.Fprintf(h, "prescription_id %d\n", in.PrescriptionID)
+		for _, line := range in.Items {
+			fmt.Fprintf(h, "prescription_item %d\n", line.PrescriptionItemID)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"medeasy/m/domain"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestRequirePrescription(t *testing.T) {
+	lines := []SaleLine{{InventoryID: 1, Quantity: 2}, {InventoryID: 2, Quantity: 5}}
+	items := []domain.InventoryItem{{ID: 1, BrandName: ptr("Napa")}, {ID: 2, BrandName: ptr("Rivotril")}}
+
+	tests := []struct {
+		name      string
+		schedules []string
+		offline   bool
+		uncovered []int64
+		reason    string
+	}{
+		{"otc only", []string{domain.ScheduleOTC, domain.ScheduleOTC}, false, []int64{0, 0}, ""},
+		{"restricted online", []string{domain.ScheduleOTC, domain.ScheduleControlled}, false, nil, "prescription_required"},
+		{"restricted offline", []string{domain.ScheduleOTC, domain.ScheduleControlled}, true, []int64{0, 5}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uncovered, err := requirePrescription(lines, items, tt.schedules, tt.offline)
+			if ReasonOf(err) != tt.reason || (tt.reason == "" && err != nil) {
+				t.Fatalf("err = %v, want reason %q", err, tt.reason)
+			}
+			if !slices.Equal(uncovered, tt.uncovered) {
+				t.Errorf("uncovered = %v, want %v", uncovered, tt.uncovered)
+			}
+		})
+	}
+}
+
+func TestFillPrescription(t *testing.T) {
+	p := domain.Prescription{ID: 7, Items: []domain.PrescriptionItem{
+		{ID: 70, MedicineID: ptr(int64(100)), Quantity: 10, Remaining: 4},
+	}}
+	items := []domain.InventoryItem{
+		{ID: 1, MedicineID: ptr(int64(100)), BrandName: ptr("Rivotril")},
+		{ID: 2, GenericName: ptr("Clonazepam"), BrandName: ptr("Loose clonazepam")},
+	}
+	restricted := []string{domain.ScheduleControlled, domain.ScheduleControlled}
+
+	tests := []struct {
+		name      string
+		lines     []SaleLine
+		schedules []string
+		offline   bool
+		filled    []int64
+		uncovered []int64
+		reason    string
+	}{
+		{
+			name:      "within what is left",
+			lines:     []SaleLine{{InventoryID: 1, Quantity: 3}},
+			schedules: restricted[:1],
+			filled:    []int64{3},
+			uncovered: []int64{0},
+		},
+		{
+			name:      "more than is left",
+			lines:     []SaleLine{{InventoryID: 1, Quantity: 6}},
+			schedules: restricted[:1],
+			reason:    "prescription_exhausted",
+		},
+		{
+			name:      "more than is left offline",
+			lines:     []SaleLine{{InventoryID: 1, Quantity: 6}},
+			schedules: restricted[:1],
+			offline:   true,
+			filled:    []int64{4},
+			uncovered: []int64{2},
+		},
+		{
+			name:      "restricted custom line not prescribed",
+			lines:     []SaleLine{{InventoryID: 1, Quantity: 1}, {InventoryID: 2, Quantity: 2}},
+			schedules: restricted,
+			reason:    "prescription_required",
+		},
+		{
+			name:      "restricted custom line not prescribed offline",
+			lines:     []SaleLine{{InventoryID: 1, Quantity: 1}, {InventoryID: 2, Quantity: 2}},
+			schedules: restricted,
+			offline:   true,
+			filled:    []int64{1, 0},
+			uncovered: []int64{0, 2},
+		},
+		{
+			name:      "otc line not prescribed",
+			lines:     []SaleLine{{InventoryID: 1, Quantity: 1}, {InventoryID: 2, Quantity: 2}},
+			schedules: []string{domain.ScheduleControlled, domain.ScheduleOTC},
+			filled:    []int64{1, 0},
+			uncovered: []int64{0, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fills, uncovered, err := fillPrescription(p, tt.lines, items[:len(tt.lines)], tt.schedules, tt.offline)
+			if ReasonOf(err) != tt.reason || (tt.reason == "" && err != nil) {
+				t.Fatalf("err = %v, want reason %q", err, tt.reason)
+			}
+			if err != nil {
+				return
+			}
+			filled := make([]int64, len(fills))
+			for i, fill := range fills {
+				filled[i] = fill.Quantity
+			}
+			if !slices.Equal(filled, tt.filled) {
+				t.Errorf("filled = %v, want %v", filled, tt.filled)
+			}
+			if !slices.Equal(uncovered, tt.uncovered) {
+				t.Errorf("uncovered = %v, want %v", uncovered, tt.uncovered)
+			}
+		})
+	}
+}
+
+func TestCreateOverrideNeedsPharmacistOrOwner(t *testing.T) {
+	s := &salesService{}
+	sale := NewSale{
+		PharmacyID: 1,
+		Items:      []SaleLine{{InventoryID: 1, Quantity: 1}},
+		Override:   &InteractionOverride{Reason: "reviewed with the prescriber"},
+	}
+	tests := []struct {
+		name     string
+		userID   int64
+		role     string
+		apiKeyID int64
+	}{
+		{"employee", 2, RoleEmployee, 0},
+		{"api key", 0, "", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := sale
+			in.UserID, in.Role, in.APIKeyID = tt.userID, tt.role, tt.apiKeyID
+			_, err := s.Create(context.Background(), in)
+			var svcErr *Error
+			if !errors.As(err, &svcErr) || svcErr.Kind != KindForbidden {
+				t.Fatalf("err = %v, want forbidden", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// Package service holds the business rules of the API. Handlers translate
+// HTTP into calls on these interfaces, and the services reach the database
+// only through the repository interfaces in repository.go.
+package service
+
+import (
+	"errors"
+	"time"
+)
+
+// Kind classifies an Error so callers can map it to a response.
+type Kind int
+
+const (
+	KindInvalid Kind = iota + 1
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	// KindPreconditionRequired and KindPreconditionFailed reject writes that
+	// do not name, or do not match, the version being replaced.
+	KindPreconditionRequired
+	KindPreconditionFailed
+)
+
+// Error is a failure caused by the caller's input or permissions. Any other
+// error returned by a service is internal.
+type Error struct {
+	Kind    Kind
+	Message string
+	// Reason is a short machine-readable cause, used for metric labels.
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func invalid(message string) error {
+	return &Error{Kind: KindInvalid, Message: message}
+}
+
+func unauthorized(message, reason string) error {
+	return &Error{Kind: KindUnauthorized, Message: message, Reason: reason}
+}
+
+func forbidden(message string) error {
+	return &Error{Kind: KindForbidden, Message: message}
+}
+
+func notFound(message string) error {
+	return &Error{Kind: KindNotFound, Message: message}
+}
+
+func conflict(message string) error {
+	return &Error{Kind: KindConflict, Message: message}
+}
+
+func preconditionRequired(message string) error {
+	return &Error{Kind: KindPreconditionRequired, Message: message}
+}
+
+func preconditionFailed(message string) error {
+	return &Error{Kind: KindPreconditionFailed, Message: message}
+}
+
+// ReasonOf returns the Reason of err when it is an *Error.
+func ReasonOf(err error) string {
+	var svcErr *Error
+	if errors.As(err, &svcErr) {
+		return svcErr.Reason
+	}
+	return ""
+}
+
+// Config carries the settings services need from the application config.
+type Config struct {
+	Secret   string
+	TokenTTL time.Duration
+	// Location interprets client times that carry no offset.
+	Location *time.Location
+}
+
+// Services bundles every service the HTTP layer depends on.
+type Services struct {
+	Auth          Auth
+	Pharmacies    Pharmacies
+	Catalog       Catalog
+	Inventory     Inventory
+	Sales         Sales
+	Reports       Reports
+	APIKeys       APIKeys
+	Sync          Sync
+	Submissions   Submissions
+	Prescriptions Prescriptions
+}
+
+// New wires the default service implementations on top of repos.
+func New(repos Repositories, cfg Config) Services {
+	location := cfg.Location
+	if location == nil {
+		location = time.UTC
+	}
+	return Services{
+		Auth:          &authService{users: repos.Users, apiKeys: repos.APIKeys, secret: []byte(cfg.Secret), tokenTTL: cfg.TokenTTL, now: time.Now},
+		Pharmacies:    &pharmacyService{pharmacies: repos.Pharmacies},
+		Catalog:       &catalogService{medicines: repos.Medicines},
+		Inventory:     &inventoryService{inventory: repos.Inventory, medicines: repos.Medicines},
+		Sales:         &salesService{sales: repos.Sales, interactions: repos.Interactions, location: location, now: time.Now},
+		Reports:       &reportService{reports: repos.Reports},
+		APIKeys:       &apiKeyService{apiKeys: repos.APIKeys, now: time.Now},
+		Sync:          &syncService{sync: repos.Sync, reports: repos.Reports},
+		Submissions:   &submissionService{submissions: repos.Submissions, inventory: repos.Inventory, medicines: repos.Medicines},
+		Prescriptions: &prescriptionService{prescriptions: repos.Prescriptions, medicines: repos.Medicines, attachments: repos.Attachments, location: location, now: time.Now},
+	}
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"medeasy/m/domain"
+)
+
+// Submissions moves custom inventory medicines into the shared catalog.
+// Owners submit their custom items and merge them into catalog medicines
+// they duplicate; adding a new medicine to the catalog is left to the server
+// operator, since every pharmacy shares it.
+type Submissions interface {
+	// Submit proposes a custom inventory item for the catalog, listing the
+	// catalog medicines it may duplicate.
+	Submit(ctx context.Context, pharmacyID, userID, inventoryID int64, in SubmissionInput) (domain.CatalogSubmission, error)
+	// List returns submissions by status (all when empty), of one pharmacy
+	// or, with pharmacyID zero, of all. Pending ones carry candidates.
+	List(ctx context.Context, pharmacyID int64, status string) ([]domain.CatalogSubmission, error)
+	// Merge links the submission's custom items and their past sales to an
+	// existing catalog medicine. pharmacyID zero skips the ownership check.
+	Merge(ctx context.Context, pharmacyID, id, medicineID int64) (domain.CatalogSubmission, domain.Relinked, error)
+	// Approve adds the submission to the catalog as a new medicine and
+	// links its items to it.
+	Approve(ctx context.Context, id int64) (domain.CatalogSubmission, domain.Relinked, error)
+	Reject(ctx context.Context, id int64, note string) (domain.CatalogSubmission, error)
+}
+
+// SubmissionInput completes what a custom inventory item records about its
+// medicine.
+type SubmissionInput struct {
+	Strength   string
+	DosageForm string
+}
+
+// maxCandidates caps the catalog matches listed for a submission.
+const maxCandidates = 5
+
+type submissionService struct {
+	submissions SubmissionRepository
+	inventory   InventoryRepository
+	medicines   MedicineRepository
+}
+
+func (s *submissionService) Submit(ctx context.Context, pharmacyID, userID, inventoryID int64, in SubmissionInput) (domain.CatalogSubmission, error) {
+	item, err := s.inventory.ByID(ctx, inventoryID)
+	if errors.Is(err, ErrNotFound) || (err == nil && item.PharmacyID != pharmacyID) {
+		return domain.CatalogSubmission{}, notFound("inventory not found")
+	}
+	if err != nil {
+		return domain.CatalogSubmission{}, err
+	}
+	if item.MedicineID != nil {
+		return domain.CatalogSubmission{}, invalid("inventory item is already a catalog medicine")
+	}
+	submission := domain.CatalogSubmission{
+		PharmacyID:   pharmacyID,
+		InventoryID:  inventoryID,
+		BrandName:    strings.TrimSpace(deref(item.BrandName)),
+		GenericName:  strings.TrimSpace(deref(item.GenericName)),
+		Manufacturer: strings.TrimSpace(deref(item.Manufacturer)),
+		Type:         strings.TrimSpace(deref(item.Type)),
+		Strength:     strings.TrimSpace(in.Strength),
+		DosageForm:   strings.TrimSpace(in.DosageForm),
+	}
+	if submission.BrandName == "" {
+		return domain.CatalogSubmission{}, invalid("inventory item has no brand name")
+	}
+	if userID > 0 {
+		submission.SubmittedBy = &userID
+	}
+	err = s.submissions.Create(ctx, &submission)
+	if errors.Is(err, ErrDuplicate) {
+		return domain.CatalogSubmission{}, conflict("inventory item is already submitted")
+	}
+	if err != nil {
+		return domain.CatalogSubmission{}, err
+	}
+	submission.Candidates, err = s.submissions.Candidates(ctx, submission.BrandName, submission.GenericName, maxCandidates)
+	return submission, err
+}
+
+func (s *submissionService) List(ctx context.Context, pharmacyID int64, status string) ([]domain.CatalogSubmission, error) {
+	switch status {
+	case "", domain.SubmissionPending, domain.SubmissionMerged, domain.SubmissionAdded, domain.SubmissionRejected:
+	default:
+		return nil, invalid("status must be pending, merged, added or rejected")
+	}
+	submissions, err := s.submissions.List(ctx, pharmacyID, status)
+	if err != nil {
+		return nil, err
+	}
+	for i := range submissions {
+		if submissions[i].Status != domain.SubmissionPending {
+			continue
+		}
+		submissions[i].Candidates, err = s.submissions.Candidates(ctx, submissions[i].BrandName, submissions[i].GenericName, maxCandidates)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return submissions, nil
+}
+
+func (s *submissionService) Merge(ctx context.Context, pharmacyID, id, medicineID int64) (domain.CatalogSubmission, domain.Relinked, error) {
+	if _, err := s.pending(ctx, pharmacyID, id); err != nil {
+		return domain.CatalogSubmission{}, domain.Relinked{}, err
+	}
+	medicine, err := s.medicines.ByID(ctx, medicineID)
+	if errors.Is(err, ErrNotFound) {
+		return domain.CatalogSubmission{}, domain.Relinked{}, invalid("invalid medicine_id")
+	}
+	if err != nil {
+		return domain.CatalogSubmission{}, domain.Relinked{}, err
+	}
+	if medicine.DiscontinuedAt != nil {
+		return domain.CatalogSubmission{}, domain.Relinked{}, invalid("medicine is discontinued")
+	}
+	merged, relinked, err := s.submissions.Merge(ctx, id, medicineID)
+	if errors.Is(err, ErrNotFound) {
+		return domain.CatalogSubmission{}, domain.Relinked{}, errSubmissionReviewed
+	}
+	return merged, relinked, err
+}
+
+func (s *submissionService) Approve(ctx context.Context, id int64) (domain.CatalogSubmission, domain.Relinked, error) {
+	if _, err := s.pending(ctx, 0, id); err != nil {
+		return domain.CatalogSubmission{}, domain.Relinked{}, err
+	}
+	added, relinked, err := s.submissions.AddToCatalog(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return domain.CatalogSubmission{}, domain.Relinked{}, errSubmissionReviewed
+	}
+	return added, relinked, err
+}
+
+func (s *submissionService) Reject(ctx context.Context, id int64, note string) (domain.CatalogSubmission, error) {
+	if _, err := s.pending(ctx, 0, id); err != nil {
+		return domain.CatalogSubmission{}, err
+	}
+	rejected, err := s.submissions.Reject(ctx, id, strings.TrimSpace(note))
+	if errors.Is(err, ErrNotFound) {
+		return domain.CatalogSubmission{}, errSubmissionReviewed
+	}
+	return rejected, err
+}
+
+var errSubmissionReviewed = conflict("submission was already reviewed")
+
+// pending loads a submission that is still open, checking it belongs to
+// pharmacyID unless that is zero.
+func (s *submissionService) pending(ctx context.Context, pharmacyID, id int64) (domain.CatalogSubmission, error) {
+	submission, err := s.submissions.ByID(ctx, id)
+	if errors.Is(err, ErrNotFound) || (err == nil && pharmacyID != 0 && submission.PharmacyID != pharmacyID) {
+		return domain.CatalogSubmission{}, notFound("submission not found")
+	}
+	if err != nil {
+		return domain.CatalogSubmission{}, err
+	}
+	if submission.Status != domain.SubmissionPending {
+		return domain.CatalogSubmission{}, errSubmissionReviewed
+	}
+	return submission, nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math"
+
+	"medeasy/m/domain"
+)
+
+// Sync pages through changes so offline clients can mirror their pharmacy's
+// data. Cursors are opaque; an empty cursor starts from the beginning.
+type Sync interface {
+	Inventory(ctx context.Context, pharmacyID int64, cursor string, limit int) (domain.InventoryChanges, error)
+	Catalog(ctx context.Context, cursor string, limit int) (domain.CatalogChanges, error)
+	Sales(ctx context.Context, pharmacyID int64, cursor string, limit int) (domain.SaleChanges, error)
+}
+
+// Tables whose deletions are recorded as tombstones.
+const (
+	syncInventory = "inventory"
+	syncMedicines = "medicines"
+	syncSales     = "sales"
+)
+
+const (
+	defaultSyncLimit = 500
+	maxSyncLimit     = 1000
+)
+
+// SyncPosition orders changes within one table: by writing transaction, then
+// by row or tombstone id.
+type SyncPosition struct {
+	TxID int64
+	ID   int64
+}
+
+// Synced is a row with the position of its latest change.
+type Synced[T any] struct {
+	SyncPosition
+	Row T
+}
+
+// Tombstone records the deletion of RowID.
+type Tombstone struct {
+	SyncPosition
+	RowID int64
+}
+
+// SyncQuery selects the next changes of one table.
+type SyncQuery struct {
+	// PharmacyID is zero for the shared catalog.
+	PharmacyID int64
+	After      SyncPosition
+	// Horizon excludes transactions that may still be running.
+	Horizon int64
+	Limit   int
+}
+
+// syncCursor is the last change a client has seen. Within a transaction,
+// upserts come before tombstones.
+type syncCursor struct {
+	SyncPosition
+	tombstone bool
+}
+
+func (c syncCursor) less(o syncCursor) bool {
+	if c.TxID != o.TxID {
+		return c.TxID < o.TxID
+	}
+	if c.tombstone != o.tombstone {
+		return !c.tombstone
+	}
+	return c.ID < o.ID
+}
+
+// rowsAfter is where the row stream resumes.
+func (c syncCursor) rowsAfter() SyncPosition {
+	if c.tombstone {
+		return SyncPosition{TxID: c.TxID, ID: math.MaxInt64}
+	}
+	return c.SyncPosition
+}
+
+// tombstonesAfter is where the tombstone stream resumes.
+func (c syncCursor) tombstonesAfter() SyncPosition {
+	if c.tombstone {
+		return c.SyncPosition
+	}
+	return SyncPosition{TxID: c.TxID}
+}
+
+func (c syncCursor) String() string {
+	if c == (syncCursor{}) {
+		return ""
+	}
+	kind := 0
+	if c.tombstone {
+		kind = 1
+	}
+	return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d.%d.%d", c.TxID, kind, c.ID))
+}
+
+func parseSyncCursor(value string) (syncCursor, error) {
+	if value == "" {
+		return syncCursor{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return syncCursor{}, invalid("invalid sync cursor")
+	}
+	var c syncCursor
+	var kind int
+	if n, err := fmt.Sscanf(string(raw), "%d.%d.%d", &c.TxID, &kind, &c.ID); err != nil || n != 3 || kind < 0 || kind > 1 {
+		return syncCursor{}, invalid("invalid sync cursor")
+	}
+	c.tombstone = kind == 1
+	return c, nil
+}
+
+type syncService struct {
+	sync    SyncRepository
+	reports ReportRepository
+}
+
+func (s *syncService) Inventory(ctx context.Context, pharmacyID int64, cursor string, limit int) (domain.InventoryChanges, error) {
+	rows, tombstones, after, err := fetch(ctx, s, syncInventory, pharmacyID, cursor, limit, s.sync.Inventory)
+	if err != nil {
+		return domain.InventoryChanges{}, err
+	}
+	p := merge(rows, tombstones, after, syncLimit(limit))
+	return domain.InventoryChanges{Upserts: p.upserts, Deleted: p.deleted, Cursor: p.cursor.String(), HasMore: p.hasMore}, nil
+}
+
+func (s *syncService) Catalog(ctx context.Context, cursor string, limit int) (domain.CatalogChanges, error) {
+	rows, tombstones, after, err := fetch(ctx, s, syncMedicines, 0, cursor, limit, s.sync.Medicines)
+	if err != nil {
+		return domain.CatalogChanges{}, err
+	}
+	p := merge(rows, tombstones, after, syncLimit(limit))
+	return domain.CatalogChanges{Upserts: p.upserts, Deleted: p.deleted, Cursor: p.cursor.String(), HasMore: p.hasMore}, nil
+}
+
+func (s *syncService) Sales(ctx context.Context, pharmacyID int64, cursor string, limit int) (domain.SaleChanges, error) {
+	rows, tombstones, after, err := fetch(ctx, s, syncSales, pharmacyID, cursor, limit, s.sync.Sales)
+	if err != nil {
+		return domain.SaleChanges{}, err
+	}
+	p := merge(rows, tombstones, after, syncLimit(limit))
+	sales, err := withItems(ctx, s.reports, p.upserts)
+	if err != nil {
+		return domain.SaleChanges{}, err
+	}
+	return domain.SaleChanges{Upserts: sales, Deleted: p.deleted, Cursor: p.cursor.String(), HasMore: p.hasMore}, nil
+}
+
+// fetch reads one more row and tombstone than fits in a page, so merge can
+// tell whether more changes follow.
+func fetch[T any](ctx context.Context, s *syncService, table string, pharmacyID int64, cursor string, limit int,
+	rows func(context.Context, SyncQuery) ([]Synced[T], error)) ([]Synced[T], []Tombstone, syncCursor, error) {
+	after, err := parseSyncCursor(cursor)
+	if err != nil {
+		return nil, nil, after, err
+	}
+	horizon, err := s.sync.Horizon(ctx)
+	if err != nil {
+		return nil, nil, after, err
+	}
+	q := SyncQuery{PharmacyID: pharmacyID, After: after.rowsAfter(), Horizon: horizon, Limit: syncLimit(limit) + 1}
+	changed, err := rows(ctx, q)
+	if err != nil {
+		return nil, nil, after, err
+	}
+	q.After = after.tombstonesAfter()
+	tombstones, err := s.sync.Tombstones(ctx, table, q)
+	if err != nil {
+		return nil, nil, after, err
+	}
+	return changed, tombstones, after, nil
+}
+
+func syncLimit(limit int) int {
+	switch {
+	case limit <= 0:
+		return defaultSyncLimit
+	case limit > maxSyncLimit:
+		return maxSyncLimit
+	}
+	return limit
+}
+
+type syncPage[T any] struct {
+	upserts []T
+	deleted []int64
+	cursor  syncCursor
+	hasMore bool
+}
+
+// merge interleaves rows and tombstones, each already in change order, and
+// keeps the first limit changes after cursor.
+func merge[T any](rows []Synced[T], tombstones []Tombstone, cursor syncCursor, limit int) syncPage[T] {
+	p := syncPage[T]{upserts: []T{}, deleted: []int64{}, cursor: cursor}
+	i, j := 0, 0
+	for n := 0; n < limit && (i < len(rows) || j < len(tombstones)); n++ {
+		var row, tomb syncCursor
+		if i < len(rows) {
+			row = syncCursor{SyncPosition: rows[i].SyncPosition}
+		}
+		if j < len(tombstones) {
+			tomb = syncCursor{SyncPosition: tombstones[j].SyncPosition, tombstone: true}
+		}
+		if i < len(rows) && (j == len(tombstones) || row.less(tomb)) {
+			p.upserts = append(p.upserts, rows[i].Row)
+			p.cursor = row
+			i++
+		} else {
+			p.deleted = append(p.deleted, tombstones[j].RowID)
+			p.cursor = tomb
+			j++
+		}
+	}
+	p.hasMore = i < len(rows) || j < len(tombstones)
+	return p
+}
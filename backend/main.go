@@ -1,32 +1,19 @@
 package main
 
 import (
-	"log"
-	"net/http"
+	"fmt"
+	"os"
 
 	"github.com/joho/godotenv"
 
-	"medeasy/m/internal/api"
-	"medeasy/m/internal/config"
-	"medeasy/m/internal/database"
-	"medeasy/m/internal/migrations"
-	"medeasy/m/internal/seed"
+	"medeasy/m/internal/cli"
 )
 
 func main() {
 	_ = godotenv.Load()
 
-	cfg := config.Load()
-	db := database.Connect(cfg.DatabaseDSN)
-	defer db.Close()
-
-	migrations.Run(db)
-	seed.LoadMedicines(db, "assets/medicine.csv")
-
-	handler := api.New(db, cfg.Secret)
-
-	log.Printf("MedEasy POS server starting on :%s", cfg.HTTPPort)
-	if err := http.ListenAndServe(":"+cfg.HTTPPort, handler.Router()); err != nil {
-		log.Fatalf("server error: %v", err)
+	if err := cli.Run(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "medeasy: %v\n", err)
+		os.Exit(1)
 	}
 }
@@ -123,8 +123,8 @@ function escapeHtml(str) {
     .replaceAll("'", "&#039;");
 }
 
-async function apiCall(endpoint, method = "GET", body = null) {
-  const headers = { "Content-Type": "application/json" };
+async function apiCall(endpoint, method = "GET", body = null, extraHeaders = {}) {
+  const headers = { "Content-Type": "application/json", ...extraHeaders };
   if (token) headers["Authorization"] = `Bearer ${token}`;
 
   const options = { method, headers };
@@ -161,8 +161,9 @@ function setupForms() {
     { id: "update-pharmacy-form", url: "/pharmacies/:id", method: "PUT", hasId: true },
     { id: "search-medicine-form", url: "/medicines", method: "GET", query: true },
     { id: "add-inventory-form", url: "/inventory", method: "POST", numeric: ["medicine_id", "quantity"], float: ["cost_price", "sale_price"] },
-    { id: "update-inventory-form", url: "/inventory/:id", method: "PUT", hasId: true, numeric: ["medicine_id", "quantity"], float: ["cost_price", "sale_price"] },
-    { id: "update-stock-form", url: "/inventory/:id/stock", method: "POST", hasId: true, numeric: ["quantity"] },
+    { id: "get-inventory-form", url: "/inventory/:id", method: "GET", hasId: true },
+    { id: "update-inventory-form", url: "/inventory/:id", method: "PUT", hasId: true, ifMatch: true, numeric: ["medicine_id", "quantity"], float: ["cost_price", "sale_price"] },
+    { id: "update-stock-form", url: "/inventory/:id/stock", method: "POST", hasId: true, ifMatch: true, numeric: ["quantity", "delta"] },
     { id: "expiry-alert-form", url: "/inventory/expiry-alert", method: "GET", query: true },
     { id: "daily-sales-form", url: "/reports/sales/daily", method: "GET", query: true },
     { id: "monthly-sales-form", url: "/reports/sales/monthly", method: "GET", query: true },
@@ -188,6 +189,13 @@ function setupForms() {
         url = url.replace(":id", id);
       }
 
+      // Versioned writes send the version read earlier as If-Match
+      const headers = {};
+      if (config.ifMatch) {
+        if (data.version) headers["If-Match"] = `"${data.version}"`;
+        delete data.version;
+      }
+
       // Handle Query Params
       if (config.query) {
         const params = new URLSearchParams(data).toString();
@@ -199,7 +207,8 @@ function setupForms() {
       // Numeric/Float conversions
       if (config.numeric) {
         config.numeric.forEach((field) => {
-          if (data[field] !== undefined && data[field] !== "") data[field] = parseInt(data[field], 10);
+          if (data[field] === "") delete data[field];
+          else if (data[field] !== undefined) data[field] = parseInt(data[field], 10);
         });
       }
       if (config.float) {
@@ -217,7 +226,7 @@ function setupForms() {
         delete data.pharmacy_id;
       }
 
-      const res = await apiCall(url, config.method, data);
+      const res = await apiCall(url, config.method, config.method === "GET" ? null : data, headers);
 
       if (res && config.authUpdate) {
         token = res.token;
@@ -271,6 +280,22 @@ function setupForms() {
         paid_amount: parseFloat(formData.get("paid_amount")),
         items,
       };
+      const prescriptionId = formData.get("prescription_id");
+      const prescriber = formData.get("prescriber");
+      if (prescriptionId) {
+        data.prescription_id = parseInt(prescriptionId, 10);
+      } else if (prescriber) {
+        data.prescription = {
+          prescriber,
+          registration_number: formData.get("registration_number"),
+          patient_name: formData.get("patient_name"),
+          prescribed_on: formData.get("prescribed_on"),
+        };
+      }
+      const overrideReason = formData.get("override_reason");
+      if (overrideReason) {
+        data.interaction_override = { reason: overrideReason };
+      }
 
       await apiCall("/sales", "POST", data);
     });